@@ -30,6 +30,9 @@ type ReleaseSpec struct {
 	// ValuesContent is a string representation of the values.yaml file
 	// +optional
 	ValuesContent string `json:"valuesContent,omitempty"`
+	// Suspend stops installs and upgrades of the release until it's unset
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
 }
 
 // ReleaseStatus defines the observed state of Release.
@@ -19,11 +19,38 @@ package v1alpha1
 import (
 	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
 	"github.com/edgeflare/edge/internal/common"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// DeletionPolicy decides what happens to a component's resources when its Project is deleted
+// +kubebuilder:validation:Enum=Delete;Retain;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete uninstalls the component's release and deletes its generated secrets
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain uninstalls the release but keeps its PersistentVolumeClaims and the
+	// component's credential secrets, for a later Project of the same name to adopt
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyOrphan leaves the release installed but unmanaged and keeps the credential
+	// secrets it depends on
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
 // ProjectSpec defines the desired state of Project
 type ProjectSpec struct {
+	// TemplateRef references a ProjectTemplate the spec is merged onto. Fields set here override
+	// the template's, valuesContent of a release set in both is deep-merged
+	// +optional
+	TemplateRef *TemplateRef `json:"templateRef,omitempty"`
+	// Suspend stops the reconciliation of the project, including its secrets, Postgres roles and
+	// releases, until it's unset. Resuming reconciles every component
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+	// DeletionPolicy applies to every component that doesn't set its own. Defaults to Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
 	// +optional
 	Database *Database `json:"database,omitempty"`
 	// +optional
@@ -34,6 +61,12 @@ type ProjectSpec struct {
 	Storage *Storage `json:"storage,omitempty"`
 	// +optional
 	PubSub *PubSub `json:"pubsub,omitempty"`
+	// Ingress exposes the project's built-in components through a Gateway API Gateway
+	// +optional
+	Ingress *Ingress `json:"ingress,omitempty"`
+	// TLS issues the built-in components' server certificates through cert-manager
+	// +optional
+	TLS *TLS `json:"tls,omitempty"`
 }
 
 // ComponentRef defines a reference to an existing component or an external resource
@@ -44,6 +77,9 @@ type ComponentRef struct {
 	// Release is Helm chart release. If release already exists, it's upgraded if old and new values differ
 	// +optional
 	Release *helmv1alpha1.ReleaseSpec `json:"release,omitempty"`
+	// DeletionPolicy overrides the Project's deletion policy for this component
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
 }
 
 // GetSecretName returns the name of the secret for this component, if any
@@ -66,15 +102,15 @@ func (c *ComponentRef) IsRelease() bool {
 
 // GetReleaseSpec returns the ReleaseSpec for this component
 // If the release field is nil but the component should be a release,
-// it returns a default ReleaseSpec.
-func (c *ComponentRef) GetReleaseSpec(componentType, projectName string) helmv1alpha1.ReleaseSpec {
+// it returns a default ReleaseSpec. Defaults are keyed by component name, e.g. postgres, not its type.
+func (c *ComponentRef) GetReleaseSpec(componentName, projectName string) helmv1alpha1.ReleaseSpec {
 	if c.Release != nil {
 		return *c.Release
 	}
 
-	// Default values based on component type
-	chartURL := common.DefaultChartURL(componentType)
-	valuesContent := common.DefaultValuesContent(componentType, projectName)
+	// Default values based on component name
+	chartURL := common.DefaultChartURL(componentName)
+	valuesContent := common.DefaultValuesContent(componentName, projectName)
 
 	return helmv1alpha1.ReleaseSpec{
 		ChartURL:      chartURL,
@@ -82,9 +118,22 @@ func (c *ComponentRef) GetReleaseSpec(componentType, projectName string) helmv1a
 	}
 }
 
+// GetDeletionPolicy returns the deletion policy of this component, falling back to the project's
+func (c *ComponentRef) GetDeletionPolicy(projectPolicy DeletionPolicy) DeletionPolicy {
+	if c.DeletionPolicy != "" {
+		return c.DeletionPolicy
+	}
+	if projectPolicy != "" {
+		return projectPolicy
+	}
+	return DeletionPolicyDelete
+}
+
 // ExternalRef references external resources via Secret
 type ExternalRef struct {
-	// SecretName is the name of the secret containing credentials
+	// SecretName is the name of the secret containing credentials. The keys depend on the component:
+	// PG* libpq variables and optionally ca.crt for databases, AWS_* variables for storage, and issuer, client-id,
+	// client-secret and optionally audience for identity providers
 	SecretName string `json:"secretName"`
 }
 
@@ -92,6 +141,281 @@ type ExternalRef struct {
 type Database struct {
 	// +optional
 	Postgres *ComponentRef `json:"postgres,omitempty"`
+	// Rotation rotates the passwords the controller generated for the built-in PostgreSQL and the
+	// components' roles. They're stable unless rotated on this schedule or on request through the
+	// edgeflare.io/rotate-credentials annotation
+	// +optional
+	Rotation *CredentialRotation `json:"rotation,omitempty"`
+	// Roles are reconciled in the built-in PostgreSQL. A <project>-pguser-<name> connection secret
+	// is issued for every login role
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Roles []PostgresRole `json:"roles,omitempty"`
+	// Databases are created in the built-in PostgreSQL
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Databases []PostgresDatabase `json:"databases,omitempty"`
+	// RemovalPolicy decides what happens to the roles and databases removed from roles and
+	// databases. Defaults to Revoke
+	// +optional
+	RemovalPolicy RemovalPolicy `json:"removalPolicy,omitempty"`
+	// Migrations are SQL files applied in order to a database of the built-in PostgreSQL
+	// +optional
+	Migrations *Migrations `json:"migrations,omitempty"`
+	// Backup runs scheduled logical backups of the databases with pg_dump. It replaces the
+	// backup CronJob of the built-in PostgreSQL's chart
+	// +optional
+	Backup *DatabaseBackup `json:"backup,omitempty"`
+	// RestoreFrom seeds a new project's databases from a backup once, before the components
+	// depending on PostgreSQL start
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="restoreFrom is immutable"
+	// +optional
+	RestoreFrom *RestoreSource `json:"restoreFrom,omitempty"`
+}
+
+// DatabaseBackup schedules pg_dump Jobs. Every run writes a custom-format dump per database,
+// <database>.dump, under a directory named after its Job, e.g. acme-backup-29334420, which names
+// the backup in the project's status and in restoreFrom
+type DatabaseBackup struct {
+	// Schedule of the backups in cron format, e.g. "0 3 * * *"
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+	// Retention is the number of backups kept, older ones are deleted
+	// +kubebuilder:default=7
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Retention int32 `json:"retention,omitempty"`
+	// Databases are dumped. Defaults to main
+	// +kubebuilder:validation:items:Pattern=`^[a-z_][a-z0-9_]*$`
+	// +optional
+	Databases []string `json:"databases,omitempty"`
+	// Target the backups are written to
+	Target BackupTarget `json:"target"`
+	// Image providing pg_dump, pg_restore and psql, at least the server's major version.
+	// Defaults to the image of the built-in PostgreSQL
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// GetRetention returns the number of backups kept
+func (b *DatabaseBackup) GetRetention() int32 {
+	if b.Retention < 1 {
+		return 7
+	}
+	return b.Retention
+}
+
+// GetDatabases returns the databases dumped
+func (b *DatabaseBackup) GetDatabases() []string {
+	if len(b.Databases) == 0 {
+		return []string{"main"}
+	}
+	return b.Databases
+}
+
+// BackupTarget is where backups are written to, a PersistentVolumeClaim or a bucket of the
+// project's storage component
+// +kubebuilder:validation:XValidation:rule="has(self.persistentVolumeClaim) != has(self.storage)",message="exactly one of persistentVolumeClaim or storage is required"
+type BackupTarget struct {
+	// +optional
+	PersistentVolumeClaim *BackupVolume `json:"persistentVolumeClaim,omitempty"`
+	// +optional
+	Storage *BackupBucket `json:"storage,omitempty"`
+}
+
+// BackupVolume is a PersistentVolumeClaim holding the backups. A missing claim is created, not
+// owned by the project so the backups outlive it
+type BackupVolume struct {
+	// +kubebuilder:validation:MinLength=1
+	ClaimName string `json:"claimName"`
+	// Size of the claim if it's created. Defaults to 8Gi
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+	// StorageClassName of the claim if it's created
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// BackupBucket is a bucket of the project's minio or seaweedfs storage, built-in or external. It
+// must exist, e.g. declared in spec.storage.buckets
+type BackupBucket struct {
+	// +kubebuilder:validation:Pattern=`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`
+	Bucket string `json:"bucket"`
+	// Prefix is the directory of the backups in the bucket, e.g. postgres
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9/_.-]*$`
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+	// Image providing the MinIO client mc. Defaults to minio/mc
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// RestoreSource names the backup a project's databases are restored from. The databases are
+// created if missing, the dumps restore their objects, ownership and privileges, so the owning
+// roles need to be declared in spec.database.roles
+type RestoreSource struct {
+	// Backup to restore, e.g. acme-backup-29334420 as listed in the status of the project that
+	// took it
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Backup string `json:"backup"`
+	// Target holding the backup. Defaults to spec.database.backup.target
+	// +optional
+	Target *BackupTarget `json:"target,omitempty"`
+}
+
+// Migrations sources ordered SQL files from ConfigMaps or an OCI artifact. Files are applied in
+// the lexical order of their names, e.g. 0001_init.sql, each in a transaction unless its first
+// line is "-- edge:no-transaction". The name without the .sql extension is the file's version
+// recorded in the edge_schema_migrations table along with its checksum. A changed checksum of an
+// applied file blocks further migrations
+// +kubebuilder:validation:XValidation:rule="has(self.configMaps) || has(self.oci)",message="configMaps or oci is required"
+type Migrations struct {
+	// Database the migrations are applied to. Defaults to main
+	// +optional
+	Database string `json:"database,omitempty"`
+	// Role the migrations run as, e.g. the owner of the objects they create. Defaults to postgres
+	// +optional
+	Role string `json:"role,omitempty"`
+	// ConfigMaps hold the SQL files as keys ending in .sql
+	// +optional
+	ConfigMaps []string `json:"configMaps,omitempty"`
+	// OCI is an artifact holding the SQL files as layers titled with their names, e.g. pushed
+	// with oras push
+	// +optional
+	OCI *OCIArtifact `json:"oci,omitempty"`
+}
+
+// GetDatabase returns the database the migrations are applied to
+func (m *Migrations) GetDatabase() string {
+	if m.Database == "" {
+		return "main"
+	}
+	return m.Database
+}
+
+// OCIArtifact references an artifact in an OCI registry
+type OCIArtifact struct {
+	// Ref of the artifact, e.g. ghcr.io/acme/migrations:v3
+	// +kubebuilder:validation:MinLength=1
+	Ref string `json:"ref"`
+	// PullSecret is a kubernetes.io/dockerconfigjson Secret with the registry's credentials
+	// +optional
+	PullSecret string `json:"pullSecret,omitempty"`
+}
+
+// RemovalPolicy decides what happens to a declared PostgreSQL role or database once it's removed
+// from the spec
+// +kubebuilder:validation:Enum=Retain;Revoke;Drop
+type RemovalPolicy string
+
+const (
+	// RemovalPolicyRetain leaves the role, its connection secret and the database untouched
+	RemovalPolicyRetain RemovalPolicy = "Retain"
+	// RemovalPolicyRevoke disables the role's login and memberships and deletes its connection
+	// secret, and revokes connecting to the database. Data is kept
+	RemovalPolicyRevoke RemovalPolicy = "Revoke"
+	// RemovalPolicyDrop drops the role, the objects it owns and the database
+	RemovalPolicyDrop RemovalPolicy = "Drop"
+)
+
+// GetRemovalPolicy returns the removal policy of declared roles and databases, Revoke by default
+func (d *Database) GetRemovalPolicy() RemovalPolicy {
+	if d.RemovalPolicy == "" {
+		return RemovalPolicyRevoke
+	}
+	return d.RemovalPolicy
+}
+
+// PostgresRole is a role reconciled in the project's PostgreSQL
+type PostgresRole struct {
+	// Name of the role
+	// +kubebuilder:validation:Pattern=`^[a-z_][a-z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// Login allows the role to log in and issues its connection secret
+	// +optional
+	Login bool `json:"login,omitempty"`
+	// +optional
+	Inherit bool `json:"inherit,omitempty"`
+	// +optional
+	CreateDB bool `json:"createDB,omitempty"`
+	// +optional
+	CreateRole bool `json:"createRole,omitempty"`
+	// +optional
+	Replication bool `json:"replication,omitempty"`
+	// ConnectionLimit caps the role's concurrent connections, 0 or -1 for no limit
+	// +kubebuilder:validation:Minimum=-1
+	// +optional
+	ConnectionLimit int32 `json:"connectionLimit,omitempty"`
+	// MemberOf are the roles granted to the role
+	// +optional
+	MemberOf []string `json:"memberOf,omitempty"`
+	// Database the connection secret of a login role connects to. Defaults to main
+	// +optional
+	Database string `json:"database,omitempty"`
+	// Grants are the privileges granted to the role on schemas and tables
+	// +optional
+	Grants []PostgresGrant `json:"grants,omitempty"`
+}
+
+// PostgresGrant grants privileges on a schema, or on tables in it
+type PostgresGrant struct {
+	// Database holding the schema. Defaults to main
+	// +optional
+	Database string `json:"database,omitempty"`
+	// Schema the privileges are granted on, or holding the tables
+	// +kubebuilder:default=public
+	// +optional
+	Schema string `json:"schema,omitempty"`
+	// Tables the privileges are granted on, * for all tables in the schema. The privileges are
+	// granted on the schema itself if unset
+	// +optional
+	Tables []string `json:"tables,omitempty"`
+	// Privileges, e.g. USAGE or CREATE on a schema and SELECT or INSERT on tables. USAGE on the
+	// schema is granted along with table privileges
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:items:Enum=ALL;CREATE;DELETE;INSERT;REFERENCES;SELECT;TRIGGER;TRUNCATE;UPDATE;USAGE
+	Privileges []string `json:"privileges"`
+}
+
+// PostgresDatabase is a database created in the project's PostgreSQL. Only the extensions of
+// the databases of an external PostgreSQL are reconciled, the databases must exist
+type PostgresDatabase struct {
+	// Name of the database
+	// +kubebuilder:validation:Pattern=`^[a-z_][a-z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// Owner role of the database, postgres if unset
+	// +optional
+	Owner string `json:"owner,omitempty"`
+	// Extensions are created in the database, or updated to their version. Extensions removed
+	// from the list are left installed
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Extensions []PostgresExtension `json:"extensions,omitempty"`
+}
+
+// PostgresExtension is an extension installed in a database, e.g. vector, postgis or pg_trgm
+type PostgresExtension struct {
+	// Name of the extension as listed in pg_available_extensions
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_-]+$`
+	Name string `json:"name"`
+	// Version to install or update to. Defaults to the extension's default version
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Schema the extension's objects are created in
+	// +optional
+	Schema string `json:"schema,omitempty"`
+}
+
+// CredentialRotation schedules the rotation of generated database credentials
+type CredentialRotation struct {
+	// Interval between rotations. Credentials are only rotated on request if unset
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
 }
 
 // GetComponentRef returns the ComponentRef for the requested database type
@@ -156,8 +480,44 @@ func (a *API) GetComponentRef(apiType string) *ComponentRef {
 type Storage struct {
 	// +optional
 	SeaweedFS *ComponentRef `json:"seaweedfs,omitempty"`
+	// Minio gets OpenID login against the project's auth component once the <project>-minio-oidc
+	// secret holds the client-id and client-secret of its OIDC app
 	// +optional
 	Minio *ComponentRef `json:"minio,omitempty"`
+	// Buckets are created on the storage component once it's ready
+	// +optional
+	Buckets []Bucket `json:"buckets,omitempty"`
+}
+
+// Bucket defines an S3 bucket managed on the project's storage component
+type Bucket struct {
+	// Name of the bucket
+	// +kubebuilder:validation:MinLength=3
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// Versioning enables object versioning. Turning it off suspends versioning on an existing bucket
+	// +optional
+	Versioning bool `json:"versioning,omitempty"`
+	// Lifecycle rules replace any lifecycle configuration of the bucket
+	// +optional
+	Lifecycle []LifecycleRule `json:"lifecycle,omitempty"`
+}
+
+// LifecycleRule expires objects of a bucket
+type LifecycleRule struct {
+	// ID uniquely identifies the rule within the bucket
+	ID string `json:"id"`
+	// Prefix limits the rule to object keys with this prefix
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+	// ExpirationDays after which current object versions expire
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ExpirationDays int32 `json:"expirationDays,omitempty"`
+	// NoncurrentExpirationDays after which noncurrent object versions are removed
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	NoncurrentExpirationDays int32 `json:"noncurrentExpirationDays,omitempty"`
 }
 
 // GetComponentRef returns the ComponentRef for the requested storage type
@@ -182,6 +542,26 @@ func (s *Storage) GetComponentRef(storageType string) *ComponentRef {
 type PubSub struct {
 	// +optional
 	PGO *ComponentRef `json:"pgo,omitempty"`
+	// Replication configures the publication and replication slot PGO streams changes from
+	// +optional
+	Replication *Replication `json:"replication,omitempty"`
+}
+
+// Replication defines the logical replication source of the pub/sub component
+type Replication struct {
+	// Tables to publish. Entries are table, schema.table, schema.* or * for all tables
+	// +kubebuilder:validation:MinItems=1
+	Tables []string `json:"tables"`
+	// Publication name
+	// +kubebuilder:default=pgo_pub
+	// +kubebuilder:validation:Pattern=`^[a-z_][a-z0-9_]*$`
+	// +optional
+	Publication string `json:"publication,omitempty"`
+	// Slot is the name of the logical replication slot
+	// +kubebuilder:default=pgo_slot
+	// +kubebuilder:validation:Pattern=`^[a-z_][a-z0-9_]*$`
+	// +optional
+	Slot string `json:"slot,omitempty"`
 }
 
 // GetComponentRef returns the ComponentRef for the requested pubsub type
@@ -197,6 +577,74 @@ func (p *PubSub) GetComponentRef(pubsubType string) *ComponentRef {
 	}
 }
 
+// DefaultHostnamePattern generates hostnames like iam.<project>.<domain>
+const DefaultHostnamePattern = "{name}.{project}.{domain}"
+
+// Ingress exposes a project's components through HTTPRoutes and TCPRoutes attached to a Gateway
+type Ingress struct {
+	// Domain is the base domain hostnames are generated under
+	// +kubebuilder:validation:MinLength=1
+	Domain string `json:"domain"`
+	// GatewayRef is the Gateway the routes attach to
+	GatewayRef GatewayRef `json:"gatewayRef"`
+	// HostnamePattern generates the hostname of each endpoint from the placeholders {name}, e.g. iam,
+	// api or s3, {project}, {namespace} and {domain}
+	// +kubebuilder:default="{name}.{project}.{domain}"
+	// +kubebuilder:validation:Pattern=`\{name\}`
+	// +optional
+	HostnamePattern string `json:"hostnamePattern,omitempty"`
+}
+
+// GetHostnamePattern returns the hostname pattern, defaulting to {name}.{project}.{domain}
+func (i *Ingress) GetHostnamePattern() string {
+	if i.HostnamePattern == "" {
+		return DefaultHostnamePattern
+	}
+	return i.HostnamePattern
+}
+
+// GatewayRef references a Gateway and the listeners a project's routes attach to
+type GatewayRef struct {
+	// Name of the Gateway
+	Name string `json:"name"`
+	// Namespace of the Gateway. Defaults to the project's namespace
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// SectionName is the listener HTTPRoutes attach to. They attach to every listener if empty
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+	// TCPListeners map a TCP endpoint, e.g. postgres, to the listener its TCPRoute attaches to.
+	// TCP endpoints without a listener aren't exposed
+	// +optional
+	TCPListeners map[string]string `json:"tcpListeners,omitempty"`
+}
+
+// TLS requests a cert-manager Certificate for each built-in component serving TLS. The issued
+// secrets replace the charts' self-signed certificates, and the connection secrets verify the
+// server with sslmode=verify-full
+type TLS struct {
+	// IssuerRef is the cert-manager Issuer or ClusterIssuer signing the certificates
+	IssuerRef IssuerRef `json:"issuerRef"`
+	// Duration of the certificates. Defaults to cert-manager's 90 days
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// RenewBefore is how long before expiry cert-manager renews the certificates. Renewed
+	// certificates are rolled out by restarting the component's pods
+	// +optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+}
+
+// IssuerRef references a cert-manager issuer
+type IssuerRef struct {
+	// Name of the issuer
+	Name string `json:"name"`
+	// Kind is Issuer, in the project's namespace, or ClusterIssuer
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default=Issuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
 // ProjectStatus defines the observed state of Project
 type ProjectStatus struct {
 	// ObservedGeneration is the last generation that was reconciled
@@ -207,6 +655,208 @@ type ProjectStatus struct {
 	// ComponentStatuses tracks the status of individual components
 	// +optional
 	ComponentStatuses map[string]ComponentStatus `json:"componentStatuses,omitempty"`
+	// ReadyComponents is the number of declared components that are ready
+	// +optional
+	ReadyComponents int32 `json:"readyComponents,omitempty"`
+	// TotalComponents is the number of declared components
+	// +optional
+	TotalComponents int32 `json:"totalComponents,omitempty"`
+	// ManagedResources are the Secrets and Releases the controller created for the project,
+	// as last reconciled. They are re-verified on every resync
+	// +optional
+	ManagedResources []ManagedResource `json:"managedResources,omitempty"`
+	// LastRepair records the drifted resources restored by the last self-healing reconciliation
+	// +optional
+	LastRepair *Repair `json:"lastRepair,omitempty"`
+	// Template records the ProjectTemplate the spec was last resolved against
+	// +optional
+	Template *TemplateStatus `json:"template,omitempty"`
+	// Connection summarizes the connection details of the ready components, published in full
+	// in the <project>-connection Secret and ConfigMap
+	// +optional
+	Connection *ConnectionStatus `json:"connection,omitempty"`
+	// Routes are the HTTPRoutes and TCPRoutes exposing the project's components
+	// +optional
+	Routes []RouteStatus `json:"routes,omitempty"`
+	// Certificates are the cert-manager Certificates issued for the project's components, and the
+	// revision of their secrets rolled out
+	// +optional
+	Certificates []CertificateStatus `json:"certificates,omitempty"`
+	// CredentialRotation records the last rotation of the generated database credentials
+	// +optional
+	CredentialRotation *CredentialRotationStatus `json:"credentialRotation,omitempty"`
+	// DatabaseObjects are the roles and databases declared in spec.database as last reconciled
+	// +optional
+	DatabaseObjects *DatabaseObjectsStatus `json:"databaseObjects,omitempty"`
+	// Migrations records the SQL migrations applied to the project's database
+	// +optional
+	Migrations *MigrationsStatus `json:"migrations,omitempty"`
+	// Extensions reports the extensions declared in spec.database.databases
+	// +optional
+	Extensions []ExtensionStatus `json:"extensions,omitempty"`
+	// Backups lists the completed backups still kept, oldest first
+	// +optional
+	Backups []BackupStatus `json:"backups,omitempty"`
+	// LastFailedBackup is the Job of the last backup that failed
+	// +optional
+	LastFailedBackup string `json:"lastFailedBackup,omitempty"`
+	// Restore records the restore of spec.database.restoreFrom
+	// +optional
+	Restore *RestoreStatus `json:"restore,omitempty"`
+}
+
+// BackupStatus is a completed backup
+type BackupStatus struct {
+	// Name of the backup, its Job
+	Name           string      `json:"name"`
+	CompletionTime metav1.Time `json:"completionTime"`
+}
+
+// RestoreStatus records the restore of a backup
+type RestoreStatus struct {
+	Backup string `json:"backup"`
+	// Completed reports whether the backup was restored. Components depending on PostgreSQL
+	// wait for it
+	Completed bool `json:"completed"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Message explains a pending or failed restore
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ExtensionStatus reports a declared extension of a database
+type ExtensionStatus struct {
+	Database string `json:"database"`
+	Name     string `json:"name"`
+	// Version is the installed version
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Available reports whether the server provides the extension at the requested version
+	Available bool `json:"available"`
+	// Message explains why the extension isn't installed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// MigrationsStatus records the applied SQL migrations
+type MigrationsStatus struct {
+	// Version is the latest applied migration
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Applied is the number of applied migrations
+	Applied int32 `json:"applied"`
+	// SourceDigest identifies the set of SQL files last applied, a different digest of the
+	// sources triggers a reconciliation
+	// +optional
+	SourceDigest string `json:"sourceDigest,omitempty"`
+}
+
+// DatabaseObjectsStatus records the declared PostgreSQL roles and databases the controller
+// reconciled, to revoke or drop those removed from the spec
+type DatabaseObjectsStatus struct {
+	// +optional
+	Roles []string `json:"roles,omitempty"`
+	// +optional
+	Databases []string `json:"databases,omitempty"`
+	// +optional
+	Grants []RoleGrantStatus `json:"grants,omitempty"`
+}
+
+// RoleGrantStatus is a grant of a declared role
+type RoleGrantStatus struct {
+	Role          string `json:"role"`
+	PostgresGrant `json:",inline"`
+}
+
+// CredentialRotationStatus records a rotation of generated database credentials
+type CredentialRotationStatus struct {
+	// LastRotated is the time the credentials were last rotated
+	LastRotated metav1.Time `json:"lastRotated"`
+	// LastRequest is the value of the edgeflare.io/rotate-credentials annotation last acted upon
+	// +optional
+	LastRequest string `json:"lastRequest,omitempty"`
+	// PendingRestarts are the components whose workloads are restarted once the secrets derived
+	// from the rotated credentials are updated
+	// +optional
+	PendingRestarts []string `json:"pendingRestarts,omitempty"`
+}
+
+// CertificateStatus describes a component's certificate as rolled out
+type CertificateStatus struct {
+	// Component is the <type>-<name> of the component
+	Component string `json:"component"`
+	// Name of the Certificate and its secret in the project's namespace
+	Name string `json:"name"`
+	// Revision identifies the certificate in the secret. A new revision restarts the component's pods
+	Revision string `json:"revision"`
+}
+
+// RouteStatus describes a Gateway API route created for a component
+type RouteStatus struct {
+	// Kind is HTTPRoute or TCPRoute
+	Kind string `json:"kind"`
+	// Name of the route in the project's namespace
+	Name string `json:"name"`
+	// Component is the <type>-<name> of the exposed component
+	Component string `json:"component"`
+	// Hostname the endpoint is exposed at. TCP endpoints are reached through the Gateway's address
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// ConnectionStatus summarizes the connection details of a project's ready components. Credentials
+// are only published in the connection Secret
+type ConnectionStatus struct {
+	// SecretName is the Secret holding the DSNs and credentials
+	SecretName string `json:"secretName"`
+	// ConfigMapName is the ConfigMap holding the endpoints
+	ConfigMapName string `json:"configMapName"`
+	// DatabaseHost is the host of the database's primary
+	// +optional
+	DatabaseHost string `json:"databaseHost,omitempty"`
+	// DatabaseReadHost is the host of the database's read replicas
+	// +optional
+	DatabaseReadHost string `json:"databaseReadHost,omitempty"`
+	// DatabasePort is the port of the database
+	// +optional
+	DatabasePort string `json:"databasePort,omitempty"`
+	// DatabaseName is the name of the project's database
+	// +optional
+	DatabaseName string `json:"databaseName,omitempty"`
+	// Issuer is the OIDC issuer URL
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// DiscoveryURL is the URL of the OIDC issuer's discovery document
+	// +optional
+	DiscoveryURL string `json:"discoveryURL,omitempty"`
+	// APIURL is the URL of the REST API
+	// +optional
+	APIURL string `json:"apiURL,omitempty"`
+	// S3Endpoint is the URL of the S3 endpoint
+	// +optional
+	S3Endpoint string `json:"s3Endpoint,omitempty"`
+	// PubSubURL is the URL of the pub/sub broker, e.g. MQTT
+	// +optional
+	PubSubURL string `json:"pubSubURL,omitempty"`
+}
+
+// ManagedResource is a resource owned by the project
+type ManagedResource struct {
+	// Kind is Secret or Release
+	Kind string `json:"kind"`
+	// Name of the resource in the project's namespace
+	Name string `json:"name"`
+	// Hash of the Secret's data or the Release's spec as last reconciled
+	Hash string `json:"hash"`
+}
+
+// Repair describes a self-healing reconciliation
+type Repair struct {
+	// Time the drift was repaired
+	Time metav1.Time `json:"time"`
+	// Resources lists the drifted resources, e.g. "Secret/demo-pguser-postgres deleted"
+	Resources []string `json:"resources"`
 }
 
 // ComponentStatus represents the status of an individual component
@@ -219,11 +869,63 @@ type ComponentStatus struct {
 	// Endpoint where the component can be accessed
 	// +optional
 	Endpoint string `json:"endpoint,omitempty"`
+	// Issuer is the OIDC issuer URL of an auth component
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// JWKSURI is the URI of the auth component's JSON Web Key Set
+	// +optional
+	JWKSURI string `json:"jwksURI,omitempty"`
+	// Database describes the server of an external database component, as probed
+	// +optional
+	Database *DatabaseInfo `json:"database,omitempty"`
+	// Replicas are the standbys and logical replication clients streaming from the primary of a
+	// built-in PostgreSQL, from pg_stat_replication
+	// +optional
+	Replicas []ReplicaStatus `json:"replicas,omitempty"`
+	// ReplicasObservedAt is when the replicas were last queried
+	// +optional
+	ReplicasObservedAt *metav1.Time `json:"replicasObservedAt,omitempty"`
+}
+
+// ReplicaStatus reports a client streaming WAL from a PostgreSQL primary
+type ReplicaStatus struct {
+	// Name is the client's application_name
+	Name string `json:"name"`
+	// State of its WAL sender, e.g. streaming or catchup
+	State string `json:"state"`
+	// SyncState is async, potential, sync or quorum
+	// +optional
+	SyncState string `json:"syncState,omitempty"`
+	// ReplayLag is the time between flushing recent WAL on the primary and the replica applying
+	// it. It's unset while an idle replica is caught up
+	// +optional
+	ReplayLag *metav1.Duration `json:"replayLag,omitempty"`
+	// LagBytes is the WAL the replica has yet to replay
+	LagBytes int64 `json:"lagBytes"`
+}
+
+// DatabaseInfo describes a PostgreSQL server and the privileges of the user the controller connects as
+type DatabaseInfo struct {
+	// Version is the server version
+	Version string `json:"version"`
+	// WALLevel is the server's wal_level. Logical replication requires logical
+	WALLevel string `json:"walLevel"`
+	// Extensions lists the extensions available for installation
+	// +optional
+	Extensions []string `json:"extensions,omitempty"`
+	// CanCreateRole reports whether the user can create the roles of the project's components
+	CanCreateRole bool `json:"canCreateRole"`
+	// CanReplicate reports whether the user can create replication slots
+	CanReplicate bool `json:"canReplicate"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="Components Ready",type="integer",JSONPath=".status.readyComponents"
+// +kubebuilder:printcolumn:name="Components",type="integer",JSONPath=".status.totalComponents"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].reason",priority=1
+// +kubebuilder:printcolumn:name="Suspended",type="boolean",JSONPath=".spec.suspend",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // Project is the Schema for the projects API
 type Project struct {
@@ -0,0 +1,87 @@
+/*
+Copyright 2025 edgeflare.io.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TemplateRolloutPolicy decides when changes of a ProjectTemplate reach the Projects referencing it
+// +kubebuilder:validation:Enum=Auto;Manual
+type TemplateRolloutPolicy string
+
+const (
+	// TemplateRolloutAuto rolls every template change out to the project
+	TemplateRolloutAuto TemplateRolloutPolicy = "Auto"
+	// TemplateRolloutManual keeps the project on the template generation it was last resolved
+	// against until the project itself is updated
+	TemplateRolloutManual TemplateRolloutPolicy = "Manual"
+)
+
+// TemplateRef references the ProjectTemplate a Project's spec is merged onto
+type TemplateRef struct {
+	// Name of the ProjectTemplate
+	Name string `json:"name"`
+	// RolloutPolicy decides when template changes reach the project
+	// +kubebuilder:default=Auto
+	// +optional
+	RolloutPolicy TemplateRolloutPolicy `json:"rolloutPolicy,omitempty"`
+}
+
+// GetRolloutPolicy returns the rollout policy, defaulting to Auto
+func (t *TemplateRef) GetRolloutPolicy() TemplateRolloutPolicy {
+	if t.RolloutPolicy == "" {
+		return TemplateRolloutAuto
+	}
+	return t.RolloutPolicy
+}
+
+// TemplateStatus records the ProjectTemplate a Project's spec was resolved against
+type TemplateStatus struct {
+	// Name of the ProjectTemplate
+	Name string `json:"name"`
+	// Generation of the ProjectTemplate the spec was resolved against
+	Generation int64 `json:"generation"`
+	// Spec is the template's spec at Generation. Projects with the Manual rollout policy resolve
+	// against it until they're updated
+	// +optional
+	Spec *ProjectSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// ProjectTemplate is the Schema for the projecttemplates API. Its spec is a reusable ProjectSpec,
+// Projects referencing it via spec.templateRef override its fields. templateRef is ignored in
+// a template.
+type ProjectTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ProjectSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// ProjectTemplateList contains a list of ProjectTemplate
+type ProjectTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProjectTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProjectTemplate{}, &ProjectTemplateList{})
+}
@@ -41,6 +41,9 @@ import (
 	edgeflareiov1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
 	"github.com/edgeflare/edge/internal/controller"
 	helmcontroller "github.com/edgeflare/edge/internal/controller/helm"
+	"github.com/edgeflare/edge/internal/util/helm"
+	webhookhelmv1alpha1 "github.com/edgeflare/edge/internal/webhook/helm/v1alpha1"
+	webhookv1alpha1 "github.com/edgeflare/edge/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -205,20 +208,42 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Shared by both controllers. The release controller creates its own if this fails
+	helmClient, err := helm.NewClient()
+	if err != nil {
+		setupLog.Error(err, "unable to create helm client, chart values schemas fall back to embedded ones")
+	}
+
 	if err = (&helmcontroller.ReleaseReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		HelmClient: helmClient,
+		Recorder:   mgr.GetEventRecorderFor("release-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Release")
 		os.Exit(1)
 	}
 	if err = (&controller.ProjectReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		HelmClient: helmClient,
+		APIReader:  mgr.GetAPIReader(),
+		Recorder:   mgr.GetEventRecorderFor("project-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Project")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookhelmv1alpha1.SetupReleaseWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Release")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupProjectWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Project")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -1,18 +1,79 @@
 package common
 
 const (
-	AnnotationChartVersion = "helm.edgeflare.io/chart-version"
-	AnnotationRevision     = "helm.edgeflare.io/revision"
-	AnnotationValuesHash   = "helm.edgeflare.io/values-hash"
-	ConditionTypeInstalled = "Installed"
-	ConditionTypeError     = "Error"
-	ConditionTypeReady     = "Ready"
-	LabelVersion           = "app.kubernetes.io/version"
-	LabelManagedBy         = "app.kubernetes.io/managed-by"
-	LabelComponent         = "app.kubernetes.io/component"
-	LabelProject           = "app.kubernetes.io/project"
-	ReasonReconciling      = "Reconciling"
-	ReasonReady            = "Ready"
-	ReasonError            = "Error"
-	ReasonComponentError   = "ComponentError"
+	AnnotationChartVersion       = "helm.edgeflare.io/chart-version"
+	AnnotationRevision           = "helm.edgeflare.io/revision"
+	AnnotationValuesHash         = "helm.edgeflare.io/values-hash"
+	AnnotationDeletionPolicy     = "helm.edgeflare.io/deletion-policy"
+	AnnotationRetained           = "edgeflare.io/retained"
+	AnnotationSuspendedBy        = "edgeflare.io/suspended-by"
+	AnnotationCertRevision       = "edgeflare.io/certificate-revision"
+	AnnotationRotateCredentials  = "edgeflare.io/rotate-credentials"
+	AnnotationRestartedAt        = "edgeflare.io/restarted-at"
+	ConditionTypeInstalled       = "Installed"
+	ConditionTypeError           = "Error"
+	ConditionTypeReady           = "Ready"
+	ConditionTypeProgressing     = "Progressing"
+	ConditionTypeDegraded        = "Degraded"
+	ConditionTypeValuesInvalid   = "ValuesInvalid"
+	ConditionTypeSuspended       = "Suspended"
+	ConditionTypeRoutesReady     = "RoutesReady"
+	ConditionTypeMigrations      = "MigrationsApplied"
+	ConditionTypeDrifted         = "Drifted"
+	ConditionTypeStorageSSO      = "StorageSSO"
+	LabelVersion                 = "app.kubernetes.io/version"
+	LabelManagedBy               = "app.kubernetes.io/managed-by"
+	LabelComponent               = "app.kubernetes.io/component"
+	LabelProject                 = "app.kubernetes.io/project"
+	LabelName                    = "app.kubernetes.io/name"
+	ReasonReconciling            = "Reconciling"
+	ReasonReady                  = "Ready"
+	ReasonError                  = "Error"
+	ReasonComponentError         = "ComponentError"
+	ReasonProgressing            = "Progressing"
+	ReasonWaitingForDependency   = "WaitingForDependency"
+	ReasonMissingCapability      = "MissingCapability"
+	ReasonComponentsReady        = "ComponentsReady"
+	ReasonComponentsProgressing  = "ComponentsProgressing"
+	ReasonComponentsDegraded     = "ComponentsDegraded"
+	ReasonSchemaValidationFailed = "SchemaValidationFailed"
+	ReasonValuesValid            = "ValuesValid"
+	ReasonDriftDetected          = "DriftDetected"
+	ReasonDriftRepaired          = "DriftRepaired"
+	ReasonCredentialsDrifted     = "CredentialsDrifted"
+	ReasonDriftResolved          = "DriftResolved"
+	ReasonRetained               = "Retained"
+	ReasonAdopted                = "Adopted"
+	ReasonTemplateNotFound       = "TemplateNotFound"
+	ReasonTemplateRolledOut      = "TemplateRolledOut"
+	ReasonSecretGenerated        = "SecretGenerated"
+	ReasonRoleCreated            = "RoleCreated"
+	ReasonInstallationSucceeded  = "InstallationSucceeded"
+	ReasonInstallationFailed     = "InstallationFailed"
+	ReasonInstalled              = "Installed"
+	ReasonUpgraded               = "Upgraded"
+	ReasonRevisionFailed         = "RevisionFailed"
+	ReasonUninstalled            = "Uninstalled"
+	ReasonUninstallError         = "UninstallError"
+	ReasonOrphaned               = "Orphaned"
+	ReasonSuspended              = "Suspended"
+	ReasonResumed                = "Resumed"
+	ReasonRoutesReconciled       = "RoutesReconciled"
+	ReasonRouteError             = "RouteError"
+	ReasonGatewayAPIMissing      = "GatewayAPIMissing"
+	ReasonCertificateRenewed     = "CertificateRenewed"
+	ReasonCredentialsRotated     = "CredentialsRotated"
+	ReasonRoleRevoked            = "RoleRevoked"
+	ReasonRoleDropped            = "RoleDropped"
+	ReasonRoleError              = "RoleError"
+	ReasonMigrationsApplied      = "MigrationsApplied"
+	ReasonMigrationFailed        = "MigrationFailed"
+	ReasonChecksumMismatch       = "ChecksumMismatch"
+	ReasonExtensionUnavailable   = "ExtensionUnavailable"
+	ReasonBackupCompleted        = "BackupCompleted"
+	ReasonBackupFailed           = "BackupFailed"
+	ReasonRestoreCompleted       = "RestoreCompleted"
+	ReasonRestoreFailed          = "RestoreFailed"
+	ReasonOIDCClientConfigured   = "OIDCClientConfigured"
+	ReasonOIDCClientMissing      = "OIDCClientMissing"
 )
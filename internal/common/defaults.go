@@ -1,5 +1,12 @@
 package common
 
+const (
+	// DefaultBackupImage runs pg_dump and pg_restore, the image of the built-in PostgreSQL
+	DefaultBackupImage = "docker.io/bitnami/postgresql:17.4.0"
+	// DefaultS3ClientImage copies backups to and from S3 compatible storage
+	DefaultS3ClientImage = "docker.io/minio/mc:RELEASE.2025-04-16T18-13-26Z"
+)
+
 // DefaultChartURL returns the default chart URL for a given component type
 func DefaultChartURL(componentType string) string {
 	switch componentType {
@@ -56,6 +63,47 @@ readReplicas:
 tls:
   autoGenerated: true
   enabled: true
+`
+	case "keycloak":
+		return `
+auth:
+  adminUser: admin
+postgresql:
+  enabled: false
+production: false
+proxy: edge
+replicaCount: 1
+`
+	case "postgrest":
+		return `
+image:
+  repository: postgrest/postgrest
+  tag: v12.2.3
+service:
+  port: 80
+`
+	case "minio":
+		return `
+defaultBuckets: ""
+mode: standalone
+persistence:
+  enabled: true
+  size: 8Gi
+`
+	case "seaweedfs":
+		return `
+filer:
+  s3:
+    enabled: false
+s3:
+  enabled: true
+`
+	case "pgo":
+		return `
+image:
+  repository: ghcr.io/edgeflare/pgo
+service:
+  port: 8001
 `
 	// Add default values for other component types
 	default:
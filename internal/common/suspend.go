@@ -0,0 +1,64 @@
+package common
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RecordSuspendedBy records user as the one who suspended obj in the edgeflare.io/suspended-by
+// annotation, keeping the user who suspended it first. The annotation is removed once obj resumes.
+func RecordSuspendedBy(obj metav1.Object, suspend bool, user string) {
+	annotations := obj.GetAnnotations()
+	if !suspend {
+		if _, ok := annotations[AnnotationSuspendedBy]; ok {
+			delete(annotations, AnnotationSuspendedBy)
+			obj.SetAnnotations(annotations)
+		}
+		return
+	}
+	if annotations[AnnotationSuspendedBy] != "" || user == "" {
+		return
+	}
+
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationSuspendedBy] = user
+	obj.SetAnnotations(annotations)
+}
+
+// SuspendedBy describes who or what suspended obj: the user recorded in the edgeflare.io/suspended-by
+// annotation, or else the field manager that last set spec.suspend, e.g. kubectl-patch.
+func SuspendedBy(obj metav1.Object) string {
+	if user := obj.GetAnnotations()[AnnotationSuspendedBy]; user != "" {
+		return user
+	}
+
+	var manager string
+	var managedAt *metav1.Time
+	for _, entry := range obj.GetManagedFields() {
+		if entry.FieldsV1 == nil || !managesSuspend(entry.FieldsV1.Raw) {
+			continue
+		}
+		if managedAt == nil || (entry.Time != nil && managedAt.Before(entry.Time)) {
+			manager, managedAt = entry.Manager, entry.Time
+		}
+	}
+	if manager == "" {
+		return "unknown"
+	}
+	return manager
+}
+
+// managesSuspend reports whether managed fields include spec.suspend
+func managesSuspend(fields []byte) bool {
+	var managed struct {
+		Spec map[string]json.RawMessage `json:"f:spec"`
+	}
+	if err := json.Unmarshal(fields, &managed); err != nil {
+		return false
+	}
+	_, ok := managed.Spec["f:suspend"]
+	return ok
+}
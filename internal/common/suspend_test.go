@@ -0,0 +1,39 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSuspendedBy(t *testing.T) {
+	obj := &metav1.ObjectMeta{}
+	if got := SuspendedBy(obj); got != "unknown" {
+		t.Errorf("got %q for an object without managers", got)
+	}
+
+	earlier, later := metav1.NewTime(time.Unix(100, 0)), metav1.NewTime(time.Unix(200, 0))
+	obj.ManagedFields = []metav1.ManagedFieldsEntry{
+		{Manager: "kubectl-client-side-apply", Time: &earlier,
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:suspend":{}}}`)}},
+		{Manager: "kubectl-patch", Time: &later,
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:suspend":{}}}`)}},
+		{Manager: "manager", Time: &later,
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:conditions":{}}}`)}},
+	}
+	if got := SuspendedBy(obj); got != "kubectl-patch" {
+		t.Errorf("got %q, want the latest manager of spec.suspend", got)
+	}
+
+	RecordSuspendedBy(obj, true, "alice")
+	RecordSuspendedBy(obj, true, "bob")
+	if got := SuspendedBy(obj); got != "alice" {
+		t.Errorf("got %q, want the user who suspended first", got)
+	}
+
+	RecordSuspendedBy(obj, false, "bob")
+	if _, ok := obj.Annotations[AnnotationSuspendedBy]; ok {
+		t.Error("annotation kept after resuming")
+	}
+}
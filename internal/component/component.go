@@ -0,0 +1,117 @@
+// Package component registers the components a Project can declare: where each is declared in the
+// ProjectSpec and which chart and values its release defaults to. The Project webhooks default and
+// validate Projects through the registry, the Project controller reconciles the registered
+// components through providers extending them.
+package component
+
+import (
+	"fmt"
+
+	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+// Component describes a project component
+type Component interface {
+	// Type is the component group, e.g. database or auth
+	Type() string
+	// Name is the component name. The component's release is named <project>-<name>
+	Name() string
+	// ComponentRef returns the component's reference in spec, or nil if it isn't declared
+	ComponentRef(spec *edgev1alpha1.ProjectSpec) *edgev1alpha1.ComponentRef
+	// DefaultRelease returns the chart and values used when the reference has no release
+	DefaultRelease(projectName string) helmv1alpha1.ReleaseSpec
+	// ValuesSchema returns the JSON schema the release values are validated against when the
+	// chart ships no values.schema.json and the binary embeds none for it, or nil
+	ValuesSchema() ([]byte, error)
+}
+
+// Built-in components, in dependency order
+func init() {
+	Register(Builtin("database", "postgres"))
+	Register(Builtin("auth", "zitadel"))
+	Register(Builtin("auth", "keycloak"))
+	Register(Builtin("api", "postgrest"))
+	Register(Builtin("storage", "minio"))
+	Register(Builtin("storage", "seaweedfs"))
+	Register(Builtin("pubsub", "pgo"))
+}
+
+// components holds the registered components in registration order
+var components []Component
+
+// Register adds c to the components a Project can declare
+func Register(c Component) {
+	if Lookup(c.Type(), c.Name()) != nil {
+		panic(fmt.Sprintf("component %s/%s already registered", c.Type(), c.Name()))
+	}
+	components = append(components, c)
+}
+
+// Components returns the registered components in registration order
+func Components() []Component {
+	return append([]Component(nil), components...)
+}
+
+// Lookup returns the registered component compType/name, or nil
+func Lookup(compType, name string) Component {
+	for _, c := range components {
+		if c.Type() == compType && c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// builtin is a component with a field of its own in the ProjectSpec and defaults in common
+type builtin struct {
+	compType string
+	name     string
+}
+
+// Builtin returns the built-in component compType/name
+func Builtin(compType, name string) Component {
+	return builtin{compType: compType, name: name}
+}
+
+func (b builtin) Type() string { return b.compType }
+
+func (b builtin) Name() string { return b.name }
+
+func (b builtin) ComponentRef(spec *edgev1alpha1.ProjectSpec) *edgev1alpha1.ComponentRef {
+	switch {
+	case b.compType == "database" && spec.Database != nil:
+		// Declaring spec.database alone is enough
+		return spec.Database.GetComponentRef(b.name)
+	case b.compType == "auth" && spec.Auth != nil:
+		switch b.name {
+		case "zitadel":
+			return spec.Auth.Zitadel
+		case "keycloak":
+			return spec.Auth.Keycloak
+		}
+	case b.compType == "api" && spec.API != nil && b.name == "postgrest":
+		return spec.API.PostgREST
+	case b.compType == "storage" && spec.Storage != nil:
+		switch b.name {
+		case "minio":
+			return spec.Storage.Minio
+		case "seaweedfs":
+			return spec.Storage.SeaweedFS
+		}
+	case b.compType == "pubsub" && spec.PubSub != nil && b.name == "pgo":
+		return spec.PubSub.PGO
+	}
+	return nil
+}
+
+// DefaultRelease returns the built-in chart defaults of the component
+func (b builtin) DefaultRelease(projectName string) helmv1alpha1.ReleaseSpec {
+	return helmv1alpha1.ReleaseSpec{
+		ChartURL:      common.DefaultChartURL(b.name),
+		ValuesContent: common.DefaultValuesContent(b.name, projectName),
+	}
+}
+
+func (b builtin) ValuesSchema() ([]byte, error) { return nil, nil }
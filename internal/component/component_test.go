@@ -0,0 +1,49 @@
+package component
+
+import (
+	"slices"
+	"testing"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestComponents(t *testing.T) {
+	names := []string{}
+	for _, c := range Components() {
+		names = append(names, c.Type()+"/"+c.Name())
+	}
+	want := []string{"database/postgres", "auth/zitadel", "auth/keycloak", "api/postgrest",
+		"storage/minio", "storage/seaweedfs", "pubsub/pgo"}
+	if !slices.Equal(names, want) {
+		t.Errorf("got components %v, want %v", names, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a component twice didn't panic")
+		}
+	}()
+	Register(Builtin("auth", "zitadel"))
+}
+
+func TestBuiltinComponentRef(t *testing.T) {
+	minio := &edgev1alpha1.ComponentRef{}
+	spec := &edgev1alpha1.ProjectSpec{
+		Database: &edgev1alpha1.Database{},
+		Storage:  &edgev1alpha1.Storage{Minio: minio},
+	}
+
+	if ref := Lookup("storage", "minio").ComponentRef(spec); ref != minio {
+		t.Errorf("got minio reference %v", ref)
+	}
+	if ref := Lookup("storage", "seaweedfs").ComponentRef(spec); ref != nil {
+		t.Errorf("got undeclared seaweedfs reference %v", ref)
+	}
+	if ref := Lookup("auth", "zitadel").ComponentRef(spec); ref != nil {
+		t.Errorf("got reference %v of an undeclared group", ref)
+	}
+	// Declaring spec.database alone is enough
+	if ref := Lookup("database", "postgres").ComponentRef(spec); ref == nil || spec.Database.Postgres != ref {
+		t.Errorf("got postgres reference %v", ref)
+	}
+}
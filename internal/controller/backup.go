@@ -0,0 +1,604 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+const (
+	backupMountPath = "/backups"
+	pgCAMountPath   = "/etc/edge/postgres"
+	// backupS3Alias is the mc alias of the backup bucket's endpoint, set through MC_HOST_<alias>
+	backupS3Alias = "backup"
+	// labelJobName is set on the pods of a Job to its name
+	labelJobName = "batch.kubernetes.io/job-name"
+	// bitnamiUID is the user the Bitnami images run as
+	bitnamiUID = 1001
+)
+
+// backupLocation is a resolved backup target
+type backupLocation struct {
+	// claimName is the PersistentVolumeClaim holding the backups
+	claimName string
+	// s3SecretName holds the S3 credentials of the bucket holding the backups
+	s3SecretName string
+	bucket       string
+	prefix       string
+	clientImage  string
+}
+
+func backupCronJobName(project *edgev1alpha1.Project) string {
+	return project.Name + "-backup"
+}
+
+func restoreJobName(project *edgev1alpha1.Project) string {
+	return project.Name + "-restore"
+}
+
+// reconcileBackups records the completed backups of the project's databases and schedules the
+// pg_dump Jobs taking them as a CronJob, or removes it if backups are no longer declared
+func (r *ProjectReconciler) reconcileBackups(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	spec := project.Spec.Database.Backup
+	if spec == nil {
+		cronJob := &batchv1.CronJob{}
+		err := r.Get(ctx, types.NamespacedName{Name: backupCronJobName(project), Namespace: project.Namespace}, cronJob)
+		if errors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !metav1.IsControlledBy(cronJob, project) {
+			return nil
+		}
+		log.FromContext(ctx).Info("Deleting backup CronJob", "name", cronJob.Name)
+		return client.IgnoreNotFound(r.Delete(ctx, cronJob,
+			client.PropagationPolicy(metav1.DeletePropagationBackground)))
+	}
+
+	location, err := r.resolveBackupTarget(ctx, project, &spec.Target)
+	if err != nil {
+		return err
+	}
+	if location == nil {
+		log.FromContext(ctx).Info("Waiting for the storage component to schedule backups")
+		return nil
+	}
+	if err := r.recordBackups(ctx, project, location); err != nil {
+		return err
+	}
+
+	connSecret, err := r.postgresAdminSecret(ctx, project, ref)
+	if err != nil {
+		return err
+	}
+	desired := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backupCronJobName(project),
+			Namespace: project.Namespace,
+			Labels:    backupLabels(project, "backup"),
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   spec.Schedule,
+			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: ptr.To[int32](3),
+			FailedJobsHistoryLimit:     ptr.To[int32](1),
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: backupLabels(project, "backup")},
+				Spec:       backupJobSpec(project, spec, location, connSecret),
+			},
+		},
+	}
+
+	current := &batchv1.CronJob{}
+	err = r.Get(ctx, types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, current)
+	if errors.IsNotFound(err) {
+		if err := controllerutil.SetControllerReference(project, desired, r.Scheme); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("Creating backup CronJob", "name", desired.Name, "schedule", spec.Schedule)
+		return r.Create(ctx, desired)
+	} else if err != nil {
+		return err
+	}
+
+	// Fields defaulted by the API server are ignored
+	if equality.Semantic.DeepDerivative(desired.Spec, current.Spec) {
+		return nil
+	}
+	current.Spec = desired.Spec
+	current.Labels = desired.Labels
+	return r.Update(ctx, current)
+}
+
+// recordBackups adds the backups completed since the last reconciliation to the project's
+// status, keeping the latest spec.database.backup.retention. Backups on a PersistentVolumeClaim
+// are pruned by the Jobs themselves, those in a bucket are deleted here.
+func (r *ProjectReconciler) recordBackups(ctx context.Context, project *edgev1alpha1.Project,
+	location *backupLocation) error {
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(project.Namespace),
+		client.MatchingLabels(backupLabels(project, "backup"))); err != nil {
+		return fmt.Errorf("failed to list backup jobs: %w", err)
+	}
+	completed, failed := unrecordedBackups(project, jobs.Items)
+	if len(completed) == 0 && failed == "" {
+		return nil
+	}
+
+	backups := append(slices.Clone(project.Status.Backups), completed...)
+	var pruned []edgev1alpha1.BackupStatus
+	if retention := int(project.Spec.Database.Backup.GetRetention()); len(backups) > retention {
+		pruned, backups = backups[:len(backups)-retention], backups[len(backups)-retention:]
+	}
+	if location.s3SecretName != "" && len(pruned) > 0 {
+		if err := r.pruneBucketBackups(ctx, project, location, pruned); err != nil {
+			return err
+		}
+	}
+
+	patch := client.MergeFrom(project.DeepCopy())
+	project.Status.Backups = backups
+	if failed != "" {
+		project.Status.LastFailedBackup = failed
+	}
+	if err := r.patchStatus(ctx, project, patch); err != nil {
+		return err
+	}
+	for _, backup := range completed {
+		r.event(project, corev1.EventTypeNormal, common.ReasonBackupCompleted, "Backup %s completed", backup.Name)
+	}
+	if failed != "" {
+		r.event(project, corev1.EventTypeWarning, common.ReasonBackupFailed, "Backup %s failed", failed)
+	}
+	return nil
+}
+
+// backupsChanged reports whether backups completed or failed since the last reconciliation
+func (r *ProjectReconciler) backupsChanged(ctx context.Context, project *edgev1alpha1.Project) bool {
+	if project.Spec.Database == nil || project.Spec.Database.Backup == nil {
+		return false
+	}
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(project.Namespace),
+		client.MatchingLabels(backupLabels(project, "backup"))); err != nil {
+		return true
+	}
+	completed, failed := unrecordedBackups(project, jobs.Items)
+	return len(completed) > 0 || failed != ""
+}
+
+// unrecordedBackups returns the backup Jobs completed since the last recorded backup, oldest
+// first, and the latest failed Job if it wasn't recorded yet
+func unrecordedBackups(project *edgev1alpha1.Project, jobs []batchv1.Job) ([]edgev1alpha1.BackupStatus, string) {
+	var last metav1.Time
+	if n := len(project.Status.Backups); n > 0 {
+		last = project.Status.Backups[n-1].CompletionTime
+	}
+
+	completed := []edgev1alpha1.BackupStatus{}
+	var failed *batchv1.Job
+	for i := range jobs {
+		job := &jobs[i]
+		switch {
+		case jobFinished(job, batchv1.JobComplete) && job.Status.CompletionTime != nil:
+			if !job.Status.CompletionTime.After(last.Time) || slices.ContainsFunc(project.Status.Backups,
+				func(b edgev1alpha1.BackupStatus) bool { return b.Name == job.Name }) {
+				continue
+			}
+			completed = append(completed, edgev1alpha1.BackupStatus{
+				Name:           job.Name,
+				CompletionTime: *job.Status.CompletionTime,
+			})
+		case jobFinished(job, batchv1.JobFailed):
+			if failed == nil || failed.CreationTimestamp.Before(&job.CreationTimestamp) {
+				failed = job
+			}
+		}
+	}
+	slices.SortFunc(completed, func(a, b edgev1alpha1.BackupStatus) int {
+		return a.CompletionTime.Compare(b.CompletionTime.Time)
+	})
+
+	if failed == nil || failed.Name == project.Status.LastFailedBackup {
+		return completed, ""
+	}
+	return completed, failed.Name
+}
+
+// pruneBucketBackups deletes the objects of backups beyond the retention from their bucket
+func (r *ProjectReconciler) pruneBucketBackups(ctx context.Context, project *edgev1alpha1.Project,
+	location *backupLocation, pruned []edgev1alpha1.BackupStatus) error {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: location.s3SecretName, Namespace: project.Namespace}, secret); err != nil {
+		return fmt.Errorf("failed to get S3 secret %s: %w", location.s3SecretName, err)
+	}
+	s3, err := newS3Client(string(secret.Data["AWS_ENDPOINT_URL_S3"]),
+		string(secret.Data["AWS_ACCESS_KEY_ID"]), string(secret.Data["AWS_SECRET_ACCESS_KEY"]),
+		string(secret.Data["AWS_REGION"]))
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range pruned {
+		prefix := location.prefix + backup.Name + "/"
+		for object := range s3.ListObjects(ctx, location.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if object.Err != nil {
+				return fmt.Errorf("failed to list backup %s: %w", backup.Name, object.Err)
+			}
+			if err := s3.RemoveObject(ctx, location.bucket, object.Key, minio.RemoveObjectOptions{}); err != nil {
+				return fmt.Errorf("failed to delete %s of backup %s: %w", object.Key, backup.Name, err)
+			}
+		}
+		log.FromContext(ctx).Info("Deleted backup", "name", backup.Name, "bucket", location.bucket)
+	}
+	return nil
+}
+
+// reconcileRestore restores spec.database.restoreFrom once with a pg_restore Job. It reports
+// whether the databases are restored, the components depending on PostgreSQL wait until then.
+func (r *ProjectReconciler) reconcileRestore(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) (bool, error) {
+	spec := project.Spec.Database.RestoreFrom
+	if !restorePending(project) {
+		return true, nil
+	}
+
+	target := spec.Target
+	if target == nil && project.Spec.Database.Backup != nil {
+		target = &project.Spec.Database.Backup.Target
+	}
+	if target == nil {
+		return false, r.setRestoreStatus(ctx, project, false, "restoreFrom.target is required without backup.target")
+	}
+	location, err := r.resolveBackupTarget(ctx, project, target)
+	if err != nil {
+		return false, err
+	}
+	if location == nil {
+		return false, r.setRestoreStatus(ctx, project, false, "Waiting for the storage component")
+	}
+
+	job := &batchv1.Job{}
+	err = r.Get(ctx, types.NamespacedName{Name: restoreJobName(project), Namespace: project.Namespace}, job)
+	if errors.IsNotFound(err) {
+		connSecret, err := r.postgresAdminSecret(ctx, project, ref)
+		if err != nil {
+			return false, err
+		}
+		job = &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      restoreJobName(project),
+				Namespace: project.Namespace,
+				Labels:    backupLabels(project, "restore"),
+			},
+			Spec: restoreJobSpec(spec.Backup, backupImage(project.Spec.Database.Backup), location, connSecret),
+		}
+		if err := controllerutil.SetControllerReference(project, job, r.Scheme); err != nil {
+			return false, err
+		}
+		log.FromContext(ctx).Info("Restoring backup", "backup", spec.Backup)
+		if err := r.Create(ctx, job); err != nil {
+			return false, err
+		}
+		return false, r.setRestoreStatus(ctx, project, false, fmt.Sprintf("Restoring backup %s", spec.Backup))
+	} else if err != nil {
+		return false, err
+	}
+
+	switch {
+	case jobFinished(job, batchv1.JobComplete):
+		// The declared grants were applied before the restored tables existed
+		if grants := declaredGrants(project.Spec.Database.Roles); len(grants) > 0 && !ref.IsExternal() {
+			if err := r.applyGrants(ctx, project, grants, nil); err != nil {
+				return false, err
+			}
+		}
+		if err := r.setRestoreStatus(ctx, project, true, ""); err != nil {
+			return false, err
+		}
+		r.event(project, corev1.EventTypeNormal, common.ReasonRestoreCompleted, "Restored backup %s", spec.Backup)
+		return true, nil
+	case jobFinished(job, batchv1.JobFailed):
+		message := fmt.Sprintf("Restore Job %s failed, delete it to retry", job.Name)
+		if project.Status.Restore == nil || project.Status.Restore.Message != message {
+			r.event(project, corev1.EventTypeWarning, common.ReasonRestoreFailed,
+				"Failed to restore backup %s", spec.Backup)
+		}
+		return false, r.setRestoreStatus(ctx, project, false, message)
+	}
+	return false, nil
+}
+
+// restorePending reports whether spec.database.restoreFrom wasn't restored yet
+func restorePending(project *edgev1alpha1.Project) bool {
+	if project.Spec.Database == nil || project.Spec.Database.RestoreFrom == nil {
+		return false
+	}
+	status := project.Status.Restore
+	return status == nil || !status.Completed || status.Backup != project.Spec.Database.RestoreFrom.Backup
+}
+
+func (r *ProjectReconciler) setRestoreStatus(ctx context.Context, project *edgev1alpha1.Project,
+	completed bool, message string) error {
+	status := &edgev1alpha1.RestoreStatus{
+		Backup:    project.Spec.Database.RestoreFrom.Backup,
+		Completed: completed,
+		Message:   message,
+	}
+	if completed {
+		status.CompletionTime = ptr.To(metav1.Now())
+	}
+	if project.Status.Restore != nil && project.Status.Restore.Backup == status.Backup &&
+		project.Status.Restore.Completed == completed && project.Status.Restore.Message == message {
+		return nil
+	}
+	patch := client.MergeFrom(project.DeepCopy())
+	project.Status.Restore = status
+	return r.patchStatus(ctx, project, patch)
+}
+
+// resolveBackupTarget ensures the PersistentVolumeClaim of a backup target, or finds the S3
+// credentials of the project's storage component. It returns nil while the built-in storage
+// isn't ready.
+func (r *ProjectReconciler) resolveBackupTarget(ctx context.Context, project *edgev1alpha1.Project,
+	target *edgev1alpha1.BackupTarget) (*backupLocation, error) {
+	if volume := target.PersistentVolumeClaim; volume != nil {
+		if err := r.ensureBackupVolume(ctx, project, volume); err != nil {
+			return nil, err
+		}
+		return &backupLocation{claimName: volume.ClaimName}, nil
+	}
+
+	bucket := target.Storage
+	if bucket == nil {
+		return nil, fmt.Errorf("backup target requires persistentVolumeClaim or storage")
+	}
+	location := &backupLocation{bucket: bucket.Bucket, prefix: strings.Trim(bucket.Prefix, "/"), clientImage: bucket.Image}
+	if location.prefix != "" {
+		location.prefix += "/"
+	}
+	if location.clientImage == "" {
+		location.clientImage = common.DefaultS3ClientImage
+	}
+	for _, p := range Providers() {
+		ref := p.ComponentRef(&project.Spec)
+		if p.Type() != "storage" || ref == nil {
+			continue
+		}
+		if ref.IsExternal() {
+			location.s3SecretName = ref.GetSecretName()
+			return location, nil
+		}
+		if !project.Status.ComponentStatuses[fmt.Sprintf("%s-%s", p.Type(), p.Name())].Ready {
+			return nil, nil
+		}
+		location.s3SecretName = project.Name + "-s3"
+		return location, nil
+	}
+	return nil, fmt.Errorf("backup target storage requires spec.storage")
+}
+
+// ensureBackupVolume creates the PersistentVolumeClaim of a backup target if it's missing. The
+// project doesn't own it, so deleting the project keeps the backups.
+func (r *ProjectReconciler) ensureBackupVolume(ctx context.Context, project *edgev1alpha1.Project,
+	volume *edgev1alpha1.BackupVolume) error {
+	claim := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: volume.ClaimName, Namespace: project.Namespace}, claim)
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	size := resource.MustParse("8Gi")
+	if volume.Size != nil {
+		size = *volume.Size
+	}
+	claim = &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      volume.ClaimName,
+			Namespace: project.Namespace,
+			Labels:    backupLabels(project, "backup"),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: volume.StorageClassName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+		},
+	}
+	log.FromContext(ctx).Info("Creating backup volume", "name", claim.Name)
+	return r.Create(ctx, claim)
+}
+
+// backupJobSpec renders the Job dumping the databases. pg_dump writes to the backup volume
+// directly, or to a scratch volume mc copies to the bucket from. Backups on a volume beyond the
+// retention are deleted by the Job.
+func backupJobSpec(project *edgev1alpha1.Project, spec *edgev1alpha1.DatabaseBackup, location *backupLocation,
+	connSecret *corev1.Secret) batchv1.JobSpec {
+	script := []string{"set -eu", `dir="` + backupMountPath + `/$BACKUP_NAME"`, `mkdir -p "$dir"`}
+	for _, database := range spec.GetDatabases() {
+		script = append(script, fmt.Sprintf(`pg_dump --format=custom --dbname=%s --file="$dir/%s.dump"`,
+			database, database))
+	}
+	if location.claimName != "" {
+		script = append(script, fmt.Sprintf("ls -1d %s/%s-* | sort | head -n -%d | xargs -r rm -rf",
+			backupMountPath, backupCronJobName(project), spec.GetRetention()))
+	}
+
+	dump := postgresContainer("pg-dump", backupImage(spec), strings.Join(script, "\n"), connSecret)
+	dump.Env = append(dump.Env, backupNameEnv())
+
+	podSpec := backupPodSpec(location, connSecret)
+	if location.claimName != "" {
+		podSpec.Containers = []corev1.Container{dump}
+	} else {
+		upload := s3Container("upload", location, fmt.Sprintf(`mc cp --recursive "%s/$BACKUP_NAME/" "%s/$BACKUP_NAME/"`,
+			backupMountPath, location.s3Path()))
+		upload.Env = append(upload.Env, backupNameEnv())
+		podSpec.InitContainers = []corev1.Container{dump}
+		podSpec.Containers = []corev1.Container{upload}
+	}
+	return batchv1.JobSpec{BackoffLimit: ptr.To[int32](1), Template: corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: backupLabels(project, "backup")},
+		Spec:       podSpec,
+	}}
+}
+
+// restoreJobSpec renders the Job restoring every dump of a backup into the database it was taken
+// from, created if missing. mc first copies the backup from the bucket to a scratch volume.
+func restoreJobSpec(backup, image string, location *backupLocation, connSecret *corev1.Secret) batchv1.JobSpec {
+	dir := backupMountPath + "/" + backup
+	script := strings.Join([]string{
+		"set -eu",
+		fmt.Sprintf(`for dump in "%s"/*.dump; do`, dir),
+		`  [ -e "$dump" ] || { echo "backup ` + backup + ` not found" >&2; exit 1; }`,
+		`  db="$(basename "$dump" .dump)"`,
+		`  if [ -z "$(psql --dbname=postgres -tAc "SELECT 1 FROM pg_database WHERE datname = '$db'")" ]; then`,
+		`    createdb "$db"`,
+		`  fi`,
+		`  pg_restore --exit-on-error --dbname="$db" "$dump"`,
+		"done",
+	}, "\n")
+
+	podSpec := backupPodSpec(location, connSecret)
+	restore := postgresContainer("pg-restore", image, script, connSecret)
+	if location.claimName != "" {
+		podSpec.Containers = []corev1.Container{restore}
+	} else {
+		download := s3Container("download", location, fmt.Sprintf(`mc cp --recursive "%s/%s/" "%s/"`,
+			location.s3Path(), backup, dir))
+		podSpec.InitContainers = []corev1.Container{download}
+		podSpec.Containers = []corev1.Container{restore}
+	}
+	return batchv1.JobSpec{BackoffLimit: ptr.To[int32](2), Template: corev1.PodTemplateSpec{Spec: podSpec}}
+}
+
+// backupPodSpec mounts the backup volume, or a scratch volume for backups in a bucket, and the CA
+// of the PostgreSQL server
+func backupPodSpec(location *backupLocation, connSecret *corev1.Secret) corev1.PodSpec {
+	backups := corev1.Volume{Name: "backups", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+	if location.claimName != "" {
+		backups.VolumeSource = corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: location.claimName,
+		}}
+	}
+	podSpec := corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		// Lets the Bitnami image's user write to a fresh volume
+		SecurityContext: &corev1.PodSecurityContext{FSGroup: ptr.To[int64](bitnamiUID)},
+		Volumes:         []corev1.Volume{backups},
+	}
+	if len(connSecret.Data[pgCAKey]) > 0 {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{Name: "postgres-ca", VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: connSecret.Name,
+				Items:      []corev1.KeyToPath{{Key: pgCAKey, Path: pgCAKey}},
+			},
+		}})
+	}
+	return podSpec
+}
+
+// postgresContainer runs a script with the libpq environment of connSecret. The server's
+// certificate is verified against the secret's CA, or the system roots without one.
+func postgresContainer(name, image, script string, connSecret *corev1.Secret) corev1.Container {
+	container := corev1.Container{
+		Name:         name,
+		Image:        image,
+		Command:      []string{"/bin/bash", "-c", script},
+		EnvFrom:      []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: connSecret.Name}}}},
+		VolumeMounts: []corev1.VolumeMount{{Name: "backups", MountPath: backupMountPath}},
+	}
+	if len(connSecret.Data[pgCAKey]) > 0 {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "PGSSLROOTCERT", Value: pgCAMountPath + "/" + pgCAKey})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name: "postgres-ca", MountPath: pgCAMountPath, ReadOnly: true,
+		})
+	} else if strings.HasPrefix(string(connSecret.Data["PGSSLMODE"]), "verify-") {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "PGSSLROOTCERT", Value: "system"})
+	}
+	return container
+}
+
+// s3Container runs an mc command against the backup bucket, with the alias of its endpoint
+// rendered from the S3 credentials
+func s3Container(name string, location *backupLocation, command string) corev1.Container {
+	alias := fmt.Sprintf(`export MC_HOST_%s="${AWS_ENDPOINT_URL_S3%%%%://*}://${AWS_ACCESS_KEY_ID}:${AWS_SECRET_ACCESS_KEY}@${AWS_ENDPOINT_URL_S3#*://}"`,
+		backupS3Alias)
+	return corev1.Container{
+		Name:         name,
+		Image:        location.clientImage,
+		Command:      []string{"/bin/sh", "-c", strings.Join([]string{"set -eu", alias, command}, "\n")},
+		EnvFrom:      []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: location.s3SecretName}}}},
+		VolumeMounts: []corev1.VolumeMount{{Name: "backups", MountPath: backupMountPath}},
+	}
+}
+
+// s3Path is the mc path of the backups in the bucket
+func (l *backupLocation) s3Path() string {
+	return path.Join(backupS3Alias, l.bucket, l.prefix)
+}
+
+// backupImage returns the image running pg_dump and pg_restore
+func backupImage(spec *edgev1alpha1.DatabaseBackup) string {
+	if spec == nil || spec.Image == "" {
+		return common.DefaultBackupImage
+	}
+	return spec.Image
+}
+
+// backupNameEnv names the backup after the Job taking it
+func backupNameEnv() corev1.EnvVar {
+	return corev1.EnvVar{Name: "BACKUP_NAME", ValueFrom: &corev1.EnvVarSource{
+		FieldRef: &corev1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.labels['%s']", labelJobName)},
+	}}
+}
+
+func backupLabels(project *edgev1alpha1.Project, component string) map[string]string {
+	return map[string]string{
+		common.LabelManagedBy: "edge",
+		common.LabelProject:   project.Name,
+		common.LabelComponent: component,
+	}
+}
+
+func jobFinished(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// projectForBackupJob maps a backup Job to the project it was scheduled for
+func (r *ProjectReconciler) projectForBackupJob(ctx context.Context, obj client.Object) []ctrl.Request {
+	labels := obj.GetLabels()
+	if labels[common.LabelComponent] != "backup" || labels[common.LabelProject] == "" {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: types.NamespacedName{
+		Name: labels[common.LabelProject], Namespace: obj.GetNamespace(),
+	}}}
+}
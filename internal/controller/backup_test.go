@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+func TestUnrecordedBackups(t *testing.T) {
+	now := time.Now()
+	job := func(name string, age time.Duration, conditionType batchv1.JobConditionType) batchv1.Job {
+		j := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-age))}}
+		j.Status.Conditions = []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue}}
+		if conditionType == batchv1.JobComplete {
+			j.Status.CompletionTime = &metav1.Time{Time: now.Add(-age + time.Minute)}
+		}
+		return j
+	}
+	project := &edgev1alpha1.Project{Status: edgev1alpha1.ProjectStatus{
+		Backups:          []edgev1alpha1.BackupStatus{{Name: "acme-backup-2", CompletionTime: metav1.NewTime(now.Add(-47 * time.Hour))}},
+		LastFailedBackup: "acme-backup-1",
+	}}
+	jobs := []batchv1.Job{
+		job("acme-backup-4", time.Hour, batchv1.JobComplete),
+		job("acme-backup-3", 24*time.Hour, batchv1.JobComplete),
+		job("acme-backup-2", 48*time.Hour, batchv1.JobComplete),
+		job("acme-backup-1", 72*time.Hour, batchv1.JobFailed),
+		// Pruned from the status by the retention
+		job("acme-backup-0", 96*time.Hour, batchv1.JobComplete),
+	}
+
+	completed, failed := unrecordedBackups(project, jobs)
+	if len(completed) != 2 || completed[0].Name != "acme-backup-3" || completed[1].Name != "acme-backup-4" {
+		t.Errorf("unexpected completed backups %v", completed)
+	}
+	if failed != "" {
+		t.Errorf("recorded failure %s reported again", failed)
+	}
+
+	jobs = append(jobs, job("acme-backup-5", time.Minute, batchv1.JobFailed))
+	if _, failed := unrecordedBackups(project, jobs); failed != "acme-backup-5" {
+		t.Errorf("latest failure not reported, got %q", failed)
+	}
+}
+
+func TestBackupJobSpec(t *testing.T) {
+	project := &edgev1alpha1.Project{ObjectMeta: metav1.ObjectMeta{Name: "acme"}}
+	spec := &edgev1alpha1.DatabaseBackup{Retention: 3, Databases: []string{"main", "analytics"}}
+	connSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "acme-pguser-postgres"},
+		Data:       map[string][]byte{pgCAKey: []byte("ca"), "PGSSLMODE": []byte("verify-full")},
+	}
+
+	job := backupJobSpec(project, spec, &backupLocation{claimName: "acme-backups"}, connSecret)
+	containers := job.Template.Spec.Containers
+	if len(containers) != 1 || len(job.Template.Spec.InitContainers) != 0 {
+		t.Fatalf("expected pg_dump alone on a volume, got %d containers", len(containers))
+	}
+	script := containers[0].Command[2]
+	for _, want := range []string{`--dbname=main --file="$dir/main.dump"`, `--dbname=analytics --file="$dir/analytics.dump"`,
+		"ls -1d /backups/acme-backup-* | sort | head -n -3 | xargs -r rm -rf"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script misses %q:\n%s", want, script)
+		}
+	}
+	if env := containers[0].Env; len(env) != 2 || env[0].Value != pgCAMountPath+"/"+pgCAKey {
+		t.Errorf("unexpected env %v", env)
+	}
+	if claim := job.Template.Spec.Volumes[0].PersistentVolumeClaim; claim == nil || claim.ClaimName != "acme-backups" {
+		t.Errorf("backup volume not mounted")
+	}
+
+	location := &backupLocation{s3SecretName: "acme-s3", bucket: "backups", prefix: "postgres/", clientImage: "mc"}
+	job = backupJobSpec(project, spec, location, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "acme-pguser-postgres"}})
+	if len(job.Template.Spec.InitContainers) != 1 || len(job.Template.Spec.Containers) != 1 {
+		t.Fatalf("expected pg_dump before the upload")
+	}
+	if strings.Contains(job.Template.Spec.InitContainers[0].Command[2], "rm -rf") {
+		t.Error("backups in a bucket pruned by the Job")
+	}
+	if upload := job.Template.Spec.Containers[0].Command[2]; !strings.Contains(upload,
+		`mc cp --recursive "/backups/$BACKUP_NAME/" "backup/backups/postgres/$BACKUP_NAME/"`) ||
+		!strings.Contains(upload, `export MC_HOST_backup="${AWS_ENDPOINT_URL_S3%%://*}://`) {
+		t.Errorf("unexpected upload:\n%s", upload)
+	}
+}
+
+func TestBackupJobRequests(t *testing.T) {
+	r := &ProjectReconciler{}
+	mapFn := withOwnerProject(r.projectForBackupJob)
+
+	// Scheduled backups are owned by their CronJob and mapped through their labels, restores by the Project
+	backup := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "demo-backup-1", Namespace: "apps",
+		Labels: map[string]string{common.LabelComponent: "backup", common.LabelProject: "demo"}}}
+	restore := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "demo-restore", Namespace: "apps",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: edgev1alpha1.GroupVersion.String(), Kind: "Project",
+			Name: "demo", UID: "uid", Controller: ptr.To(true)}}}}
+	for _, job := range []*batchv1.Job{backup, restore} {
+		requests := mapFn(context.Background(), job)
+		if len(requests) != 1 || requests[0].Name != "demo" || requests[0].Namespace != "apps" {
+			t.Errorf("got requests %v for Job %s", requests, job.Name)
+		}
+	}
+}
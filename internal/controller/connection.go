@@ -0,0 +1,300 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+// Keys of the connection bundle. Apps load them as environment variables via envFrom.
+const (
+	connDatabaseHost     = "DATABASE_HOST"
+	connDatabaseReadHost = "DATABASE_READ_HOST"
+	connDatabasePort     = "DATABASE_PORT"
+	connDatabaseName     = "DATABASE_NAME"
+	connDatabaseUser     = "DATABASE_USER"
+	connDatabasePassword = "DATABASE_PASSWORD"
+	connDatabaseURL      = "DATABASE_URL"
+	connDatabaseReadURL  = "DATABASE_READ_URL"
+	connDatabaseCACert   = "DATABASE_CA_CERT"
+	connOIDCIssuer       = "OIDC_ISSUER"
+	connOIDCDiscoveryURL = "OIDC_DISCOVERY_URL"
+	connAPIURL           = "API_URL"
+	connPubSubURL        = "PUBSUB_URL"
+	connS3Endpoint       = "AWS_ENDPOINT_URL_S3"
+	connS3Region         = "AWS_REGION"
+	connS3AccessKey      = "AWS_ACCESS_KEY_ID"
+	connS3SecretKey      = "AWS_SECRET_ACCESS_KEY"
+)
+
+// connectionBundle is what the project's ready components publish: endpoints in a ConfigMap,
+// credentials in a Secret and a summary in the project's status
+type connectionBundle struct {
+	config map[string]string
+	secret map[string][]byte
+	status edgev1alpha1.ConnectionStatus
+}
+
+// connectionName returns the name of the project's connection Secret and ConfigMap
+func connectionName(project *edgev1alpha1.Project) string {
+	return project.Name + "-connection"
+}
+
+// publishConnection writes the connection bundle of the project's ready components to the
+// <project>-connection Secret and ConfigMap and the project's status.connection.
+func (r *ProjectReconciler) publishConnection(ctx context.Context, project *edgev1alpha1.Project) error {
+	bundle, err := r.connectionBundle(ctx, project)
+	if err != nil {
+		return err
+	}
+
+	if err := r.ensureConnectionConfigMap(ctx, project, bundle.config); err != nil {
+		return fmt.Errorf("failed to publish connection config map: %w", err)
+	}
+	if err := r.ensureConnectionSecret(ctx, project, bundle.secret); err != nil {
+		return fmt.Errorf("failed to publish connection secret: %w", err)
+	}
+
+	if project.Status.Connection != nil && *project.Status.Connection == bundle.status {
+		return nil
+	}
+	patch := client.MergeFrom(project.DeepCopy())
+	project.Status.Connection = &bundle.status
+	return r.patchStatus(ctx, project, patch)
+}
+
+// connectionBundle collects the connection details of the project's ready components
+func (r *ProjectReconciler) connectionBundle(ctx context.Context,
+	project *edgev1alpha1.Project) (*connectionBundle, error) {
+	bundle := &connectionBundle{
+		config: map[string]string{},
+		secret: map[string][]byte{},
+		status: edgev1alpha1.ConnectionStatus{
+			SecretName:    connectionName(project),
+			ConfigMapName: connectionName(project),
+		},
+	}
+
+	for _, p := range Providers() {
+		ref := p.ComponentRef(&project.Spec)
+		if ref == nil {
+			continue
+		}
+		status := project.Status.ComponentStatuses[fmt.Sprintf("%s-%s", p.Type(), p.Name())]
+		if !status.Ready {
+			continue
+		}
+
+		var err error
+		switch p.Type() {
+		case "database":
+			err = r.databaseConnection(ctx, project, ref, bundle)
+		case "auth":
+			if issuer := statusIssuer(status); issuer != "" {
+				bundle.config[connOIDCIssuer] = issuer
+				bundle.config[connOIDCDiscoveryURL] = oidcDiscoveryURL(issuer)
+				bundle.status.Issuer = issuer
+				bundle.status.DiscoveryURL = bundle.config[connOIDCDiscoveryURL]
+			}
+		case "api":
+			bundle.config[connAPIURL] = status.Endpoint
+			bundle.status.APIURL = status.Endpoint
+		case "storage":
+			err = r.storageConnection(ctx, project, p.Name(), ref, bundle)
+		case "pubsub":
+			bundle.config[connPubSubURL] = status.Endpoint
+			bundle.status.PubSubURL = status.Endpoint
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bundle, nil
+}
+
+// databaseConnection adds the DSNs of the project's PostgreSQL, read from the credentials secret
+// of the built-in server or the external one's secret
+func (r *ProjectReconciler) databaseConnection(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef, bundle *connectionBundle) error {
+	secret, err := r.postgresAdminSecret(ctx, project, ref)
+	if err != nil {
+		return err
+	}
+
+	host, port := string(secret.Data["PGHOST"]), string(secret.Data["PGPORT"])
+	user, password := string(secret.Data["PGUSER"]), string(secret.Data["PGPASSWORD"])
+	database, sslMode := string(secret.Data["PGDATABASE"]), string(secret.Data["PGSSLMODE"])
+	if port == "" {
+		port = "5432"
+	}
+	// The built-in server's superuser secret points at the maintenance database
+	if !ref.IsExternal() {
+		database = projectDatabase
+	}
+
+	bundle.config[connDatabaseHost] = host
+	bundle.config[connDatabasePort] = port
+	bundle.config[connDatabaseName] = database
+	bundle.secret[connDatabaseUser] = []byte(user)
+	bundle.secret[connDatabasePassword] = []byte(password)
+	bundle.secret[connDatabaseURL] = []byte(postgresDSN(host, port, user, password, database, sslMode))
+	if ca := secret.Data[pgCAKey]; len(ca) > 0 {
+		bundle.secret[connDatabaseCACert] = ca
+	}
+	bundle.status.DatabaseHost = host
+	bundle.status.DatabasePort = port
+	bundle.status.DatabaseName = database
+
+	readHost := r.postgresReadHost(project, ref)
+	if readHost == "" {
+		return nil
+	}
+	// The replicas are read with the readonly role's credentials, not the superuser's
+	readonly := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Name: readonlySecretName(project), Namespace: project.Namespace}, readonly)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get PostgreSQL secret %s: %w", readonlySecretName(project), err)
+	}
+	bundle.config[connDatabaseReadHost] = readHost
+	bundle.secret[connDatabaseReadURL] = []byte(postgresDSN(readHost, port, string(readonly.Data["PGUSER"]),
+		string(readonly.Data["PGPASSWORD"]), database, sslMode))
+	bundle.status.DatabaseReadHost = readHost
+	return nil
+}
+
+// postgresReadHost returns the service of the built-in PostgreSQL's read replicas, or "" if the
+// release runs without replicas
+func (r *ProjectReconciler) postgresReadHost(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string {
+	if ref.IsExternal() {
+		return ""
+	}
+	values := struct {
+		Architecture string `json:"architecture"`
+	}{}
+	releaseSpec := ref.GetReleaseSpec("postgres", project.Name)
+	if err := yaml.Unmarshal([]byte(releaseSpec.ValuesContent), &values); err != nil ||
+		values.Architecture != "replication" {
+		return ""
+	}
+	return fmt.Sprintf("%s-postgres-postgresql-read.%s.svc.cluster.local", project.Name, project.Namespace)
+}
+
+// storageConnection adds the S3 endpoint and credentials the storage component published
+func (r *ProjectReconciler) storageConnection(ctx context.Context, project *edgev1alpha1.Project,
+	name string, ref *edgev1alpha1.ComponentRef, bundle *connectionBundle) error {
+	secretName := fmt.Sprintf("%s-s3", project.Name)
+	if ref.IsExternal() {
+		secretName = ref.GetSecretName()
+	}
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, secret)
+	if errors.IsNotFound(err) {
+		// Published once the buckets are created
+		log.FromContext(ctx).Info("S3 secret not published yet", "component", name, "secret", secretName)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get S3 secret %s: %w", secretName, err)
+	}
+
+	endpoint := string(secret.Data[connS3Endpoint])
+	bundle.config[connS3Endpoint] = endpoint
+	if region := string(secret.Data[connS3Region]); region != "" {
+		bundle.config[connS3Region] = region
+	}
+	bundle.secret[connS3AccessKey] = secret.Data[connS3AccessKey]
+	bundle.secret[connS3SecretKey] = secret.Data[connS3SecretKey]
+	bundle.status.S3Endpoint = endpoint
+	return nil
+}
+
+// postgresDSN builds a postgres:// connection URL
+func postgresDSN(host, port, user, password, database, sslMode string) string {
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(user, password),
+		Host:   host + ":" + port,
+		Path:   "/" + database,
+	}
+	if sslMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": {sslMode}}.Encode()
+	}
+	return dsn.String()
+}
+
+// ensureConnectionConfigMap creates or updates the project's connection ConfigMap
+func (r *ProjectReconciler) ensureConnectionConfigMap(ctx context.Context, project *edgev1alpha1.Project,
+	data map[string]string) error {
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: connectionName(project), Namespace: project.Namespace}, configMap)
+	if errors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: connectionObjectMeta(project),
+			Data:       data,
+		}
+		if err := controllerutil.SetControllerReference(project, configMap, r.Scheme); err != nil {
+			return err
+		}
+		return r.Create(ctx, configMap)
+	} else if err != nil {
+		return err
+	}
+
+	if maps.Equal(configMap.Data, data) {
+		return nil
+	}
+	configMap.Data = data
+	return r.Update(ctx, configMap)
+}
+
+// ensureConnectionSecret creates or updates the project's connection Secret
+func (r *ProjectReconciler) ensureConnectionSecret(ctx context.Context, project *edgev1alpha1.Project,
+	data map[string][]byte) error {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: connectionName(project), Namespace: project.Namespace}, secret)
+	if errors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: connectionObjectMeta(project),
+			Type:       corev1.SecretTypeOpaque,
+			Data:       data,
+		}
+		if err := controllerutil.SetControllerReference(project, secret, r.Scheme); err != nil {
+			return err
+		}
+		return r.createSecret(ctx, project, secret)
+	} else if err != nil {
+		return err
+	}
+
+	if maps.EqualFunc(secret.Data, data, func(a, b []byte) bool { return string(a) == string(b) }) {
+		return nil
+	}
+	secret.Data = data
+	return r.Update(ctx, secret)
+}
+
+func connectionObjectMeta(project *edgev1alpha1.Project) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      connectionName(project),
+		Namespace: project.Namespace,
+		Labels: map[string]string{
+			common.LabelManagedBy: "edge",
+			common.LabelProject:   project.Name,
+		},
+	}
+}
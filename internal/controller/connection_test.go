@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"net/url"
+	"testing"
+
+	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	dsn := postgresDSN("db.example.com", "5432", "app", "p@ss/word", "main", "require")
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("invalid DSN %q: %v", dsn, err)
+	}
+	password, _ := parsed.User.Password()
+	if parsed.Scheme != "postgres" || parsed.Host != "db.example.com:5432" || parsed.User.Username() != "app" ||
+		password != "p@ss/word" || parsed.Path != "/main" || parsed.Query().Get("sslmode") != "require" {
+		t.Errorf("unexpected DSN %q", dsn)
+	}
+
+	if dsn := postgresDSN("db", "5432", "app", "secret", "main", ""); dsn != "postgres://app:secret@db:5432/main" {
+		t.Errorf("unexpected DSN without sslmode %q", dsn)
+	}
+}
+
+func TestPostgresReadHost(t *testing.T) {
+	r := &ProjectReconciler{}
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+
+	ref := &edgev1alpha1.ComponentRef{}
+	if host := r.postgresReadHost(project, ref); host != "demo-postgres-postgresql-read.apps.svc.cluster.local" {
+		t.Errorf("default replication architecture published read host %q", host)
+	}
+
+	ref = &edgev1alpha1.ComponentRef{Release: &helmv1alpha1.ReleaseSpec{ValuesContent: "auth:\n  database: main\n"}}
+	if host := r.postgresReadHost(project, ref); host != "" {
+		t.Errorf("standalone server published read host %q", host)
+	}
+
+	ref.Release.ValuesContent = "architecture: replication\n"
+	if host := r.postgresReadHost(project, ref); host != "demo-postgres-postgresql-read.apps.svc.cluster.local" {
+		t.Errorf("unexpected read host %q", host)
+	}
+
+	ref.External = &edgev1alpha1.ExternalRef{}
+	if host := r.postgresReadHost(project, ref); host != "" {
+		t.Errorf("external server published read host %q", host)
+	}
+}
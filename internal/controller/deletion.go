@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+// retainComponents applies the deletion policy of every built-in component before the project's
+// releases are deleted. The credential secrets of Retain and Orphan components are released from
+// the project so that garbage collection keeps them, and each release is annotated with its policy
+// for the release controller to keep its PersistentVolumeClaims or skip the uninstall.
+func (r *ProjectReconciler) retainComponents(ctx context.Context, project *edgev1alpha1.Project) error {
+	for _, p := range Providers() {
+		ref := p.ComponentRef(&project.Spec)
+		if ref == nil || ref.IsExternal() {
+			continue
+		}
+		policy := ref.GetDeletionPolicy(project.Spec.DeletionPolicy)
+
+		if err := r.annotateDeletionPolicy(ctx, project, p.Name(), policy); err != nil {
+			return err
+		}
+		if policy == edgev1alpha1.DeletionPolicyDelete {
+			continue
+		}
+
+		for _, secretName := range p.Secrets(project) {
+			if err := r.retainSecret(ctx, project, secretName, policy); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// annotateDeletionPolicy records policy on the component's release
+func (r *ProjectReconciler) annotateDeletionPolicy(ctx context.Context, project *edgev1alpha1.Project,
+	name string, policy edgev1alpha1.DeletionPolicy) error {
+	release := &helmv1alpha1.Release{}
+	err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-%s", project.Name, name),
+		Namespace: project.Namespace}, release)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if release.Annotations[common.AnnotationDeletionPolicy] == string(policy) {
+		return nil
+	}
+	patch := client.MergeFrom(release.DeepCopy())
+	if release.Annotations == nil {
+		release.Annotations = map[string]string{}
+	}
+	release.Annotations[common.AnnotationDeletionPolicy] = string(policy)
+	return r.Patch(ctx, release, patch)
+}
+
+// retainSecret strips the project's owner reference from a secret and marks it for adoption by a
+// later Project of the same name
+func (r *ProjectReconciler) retainSecret(ctx context.Context, project *edgev1alpha1.Project,
+	secretName string, policy edgev1alpha1.DeletionPolicy) error {
+	logger := log.FromContext(ctx)
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, secret)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(secret, project) {
+		return nil
+	}
+
+	patch := client.MergeFrom(secret.DeepCopy())
+	ownerReferences := []metav1.OwnerReference{}
+	for _, ownerRef := range secret.OwnerReferences {
+		if ownerRef.UID != project.UID {
+			ownerReferences = append(ownerReferences, ownerRef)
+		}
+	}
+	secret.OwnerReferences = ownerReferences
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	secret.Labels[common.LabelProject] = project.Name
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[common.AnnotationRetained] = string(policy)
+
+	logger.Info("Retaining secret", "name", secretName, "policy", policy)
+	if err := r.Patch(ctx, secret, patch); err != nil {
+		return err
+	}
+	r.event(project, corev1.EventTypeNormal, common.ReasonRetained, "Retained secret %s (%s)", secretName, policy)
+	return nil
+}
+
+// adoptRetainedSecrets takes ownership of the secrets a deleted Project of the same name retained.
+// Components then reuse their credentials instead of generating new ones. Retained releases need
+// no adoption: Helm upgrades an orphaned release and adopts kept PersistentVolumeClaims by name.
+func (r *ProjectReconciler) adoptRetainedSecrets(ctx context.Context, project *edgev1alpha1.Project) error {
+	logger := log.FromContext(ctx)
+
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, client.InNamespace(project.Namespace),
+		client.MatchingLabels{common.LabelProject: project.Name}); err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if _, retained := secret.Annotations[common.AnnotationRetained]; !retained ||
+			metav1.GetControllerOf(secret) != nil {
+			continue
+		}
+
+		patch := client.MergeFrom(secret.DeepCopy())
+		if err := controllerutil.SetControllerReference(project, secret, r.Scheme); err != nil {
+			return fmt.Errorf("failed to set owner reference: %w", err)
+		}
+		delete(secret.Annotations, common.AnnotationRetained)
+
+		logger.Info("Adopting retained secret", "name", secret.Name)
+		if err := r.Patch(ctx, secret, patch); err != nil {
+			return err
+		}
+		r.event(project, corev1.EventTypeNormal, common.ReasonAdopted, "Adopted retained secret %s", secret.Name)
+	}
+
+	return nil
+}
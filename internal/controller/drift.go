@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+const (
+	kindSecret  = "Secret"
+	kindRelease = "Release"
+)
+
+// drift is a managed resource that was deleted or modified since the last reconciliation
+type drift struct {
+	resource edgev1alpha1.ManagedResource
+	// current is the modified resource, nil if it was deleted
+	current client.Object
+}
+
+func (d drift) String() string {
+	if d.current == nil {
+		return fmt.Sprintf("%s/%s deleted", d.resource.Kind, d.resource.Name)
+	}
+	return fmt.Sprintf("%s/%s modified", d.resource.Kind, d.resource.Name)
+}
+
+// detectDrift compares the project's managed resources, read through reader, against the state
+// recorded after the last reconciliation.
+func (r *ProjectReconciler) detectDrift(ctx context.Context, reader client.Reader,
+	project *edgev1alpha1.Project) ([]drift, error) {
+	drifted := []drift{}
+
+	for _, res := range project.Status.ManagedResources {
+		var obj client.Object
+		switch res.Kind {
+		case kindSecret:
+			obj = &corev1.Secret{}
+		case kindRelease:
+			obj = &helmv1alpha1.Release{}
+		default:
+			continue
+		}
+
+		err := reader.Get(ctx, types.NamespacedName{Name: res.Name, Namespace: project.Namespace}, obj)
+		if errors.IsNotFound(err) {
+			drifted = append(drifted, drift{resource: res})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		hash, err := resourceHash(obj)
+		if err != nil {
+			return nil, err
+		}
+		if hash != res.Hash {
+			drifted = append(drifted, drift{resource: res, current: obj})
+		}
+	}
+
+	return drifted, nil
+}
+
+// confirmDrift re-runs detectDrift against the API server. The cache may not have caught up with
+// the controller's own writes yet, so drift seen in the cache is only acted upon once confirmed.
+func (r *ProjectReconciler) confirmDrift(ctx context.Context, project *edgev1alpha1.Project) ([]drift, error) {
+	drifted, err := r.detectDrift(ctx, r.Client, project)
+	if err != nil || len(drifted) == 0 || r.APIReader == nil {
+		return drifted, err
+	}
+
+	if err := r.APIReader.Get(ctx, client.ObjectKeyFromObject(project), project); err != nil {
+		return nil, err
+	}
+	return r.detectDrift(ctx, r.APIReader, project)
+}
+
+// partitionDrift splits drifted resources into those the reconciliation repairs, recreating
+// deleted resources and overwriting modified ones, and the secrets holding generated keys and
+// passwords nothing else is derived from. Only a hash of those is recorded, so their data can't be
+// restored, and regenerating a key or password the component's data was set up with would lock the
+// component out of it. The connection secrets of the roles are rebuilt from them, resetting the
+// roles' passwords.
+func partitionDrift(project *edgev1alpha1.Project, drifted []drift) (repairable, unrepairable []drift) {
+	underivable := map[string]bool{
+		project.Name + "-postgresql":                true,
+		project.Name + "-zitadel-masterkey":         true,
+		project.Name + "-keycloak-admin":            true,
+		storageRootSecretName(project, "minio"):     true,
+		storageRootSecretName(project, "seaweedfs"): true,
+	}
+
+	for _, d := range drifted {
+		if d.resource.Kind == kindSecret && underivable[d.resource.Name] {
+			unrepairable = append(unrepairable, d)
+		} else {
+			repairable = append(repairable, d)
+		}
+	}
+	return repairable, unrepairable
+}
+
+// reportUnrepairableDrift sets the Drifted condition listing the drifted credential secrets. The
+// project isn't reconciled until they're restored, which would regenerate the deleted ones.
+func (r *ProjectReconciler) reportUnrepairableDrift(ctx context.Context, project *edgev1alpha1.Project,
+	unrepairable []drift) error {
+	resources := make([]string, 0, len(unrepairable))
+	for _, d := range unrepairable {
+		resources = append(resources, d.String())
+	}
+	return r.setCondition(ctx, project, common.ConditionTypeDrifted, metav1.ConditionTrue,
+		common.ReasonCredentialsDrifted, fmt.Sprintf("%s: restore the last applied data, regenerated "+
+			"credentials wouldn't match the components' data. A change of the spec reconciles regardless",
+			strings.Join(resources, ", ")))
+}
+
+// clearDrifted resolves the Drifted condition once no credential secret drifted anymore
+func (r *ProjectReconciler) clearDrifted(ctx context.Context, project *edgev1alpha1.Project) error {
+	if !meta.IsStatusConditionTrue(project.Status.Conditions, common.ConditionTypeDrifted) {
+		return nil
+	}
+	return r.setCondition(ctx, project, common.ConditionTypeDrifted, metav1.ConditionFalse,
+		common.ReasonDriftResolved, "The credential secrets match their last applied data")
+}
+
+// recordManagedResources records the Secrets and Releases the project owns, and the hash of their
+// current data, as the state detectDrift verifies against.
+func (r *ProjectReconciler) recordManagedResources(ctx context.Context, project *edgev1alpha1.Project) error {
+	managed := []edgev1alpha1.ManagedResource{}
+
+	// Read past the cache, which may not reflect the writes of this reconciliation yet
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := reader.List(ctx, secrets, client.InNamespace(project.Namespace)); err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+	for i := range secrets.Items {
+		if !metav1.IsControlledBy(&secrets.Items[i], project) {
+			continue
+		}
+		hash, err := resourceHash(&secrets.Items[i])
+		if err != nil {
+			return err
+		}
+		managed = append(managed, edgev1alpha1.ManagedResource{
+			Kind: kindSecret, Name: secrets.Items[i].Name, Hash: hash})
+	}
+
+	releases := &helmv1alpha1.ReleaseList{}
+	if err := reader.List(ctx, releases, client.InNamespace(project.Namespace),
+		client.MatchingLabels{common.LabelProject: project.Name}); err != nil {
+		return fmt.Errorf("failed to list releases: %w", err)
+	}
+	for i := range releases.Items {
+		if !metav1.IsControlledBy(&releases.Items[i], project) {
+			continue
+		}
+		hash, err := resourceHash(&releases.Items[i])
+		if err != nil {
+			return err
+		}
+		managed = append(managed, edgev1alpha1.ManagedResource{
+			Kind: kindRelease, Name: releases.Items[i].Name, Hash: hash})
+	}
+
+	sort.Slice(managed, func(i, j int) bool {
+		if managed[i].Kind != managed[j].Kind {
+			return managed[i].Kind < managed[j].Kind
+		}
+		return managed[i].Name < managed[j].Name
+	})
+
+	patch := client.MergeFrom(project.DeepCopy())
+	project.Status.ManagedResources = managed
+	return r.patchStatus(ctx, project, patch)
+}
+
+// recordRepair records the drifted resources a reconciliation restored in the project's status
+func (r *ProjectReconciler) recordRepair(ctx context.Context, project *edgev1alpha1.Project, drifted []drift) error {
+	resources := make([]string, 0, len(drifted))
+	for _, d := range drifted {
+		resources = append(resources, d.String())
+	}
+
+	patch := client.MergeFrom(project.DeepCopy())
+	project.Status.LastRepair = &edgev1alpha1.Repair{
+		Time:      metav1.Now(),
+		Resources: resources,
+	}
+	return r.patchStatus(ctx, project, patch)
+}
+
+// resourceHash hashes the part of a managed resource the controller owns: a Secret's data or a
+// Release's spec.
+func resourceHash(obj client.Object) (string, error) {
+	var content any
+	switch o := obj.(type) {
+	case *corev1.Secret:
+		content = o.Data
+	case *helmv1alpha1.Release:
+		content = o.Spec
+	default:
+		return "", fmt.Errorf("unsupported managed resource %T", obj)
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestResourceHash(t *testing.T) {
+	a := &corev1.Secret{Data: map[string][]byte{}}
+	b := &corev1.Secret{Data: map[string][]byte{}}
+	keys := []string{"PGUSER", "PGPASSWORD", "PGHOST", "PGPORT", "conn-string"}
+	for i := range keys {
+		a.Data[keys[i]] = []byte(keys[i])
+		b.Data[keys[len(keys)-1-i]] = []byte(keys[len(keys)-1-i])
+	}
+	// Metadata isn't the controller's, only the data is verified
+	b.Labels = map[string]string{"team": "data"}
+
+	hashA, err := resourceHash(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashB, _ := resourceHash(b); hashA != hashB {
+		t.Errorf("hash depends on the key order or metadata: %s != %s", hashA, hashB)
+	}
+
+	b.Data["PGPASSWORD"] = []byte("changed")
+	if hashB, _ := resourceHash(b); hashA == hashB {
+		t.Error("modified data not detected")
+	}
+
+	if _, err := resourceHash(&corev1.ConfigMap{}); err == nil {
+		t.Error("expected an error for an unmanaged kind")
+	}
+}
+
+func TestPartitionDrift(t *testing.T) {
+	project := &edgev1alpha1.Project{ObjectMeta: metav1.ObjectMeta{Name: "acme"}}
+	modified := func(kind, name string) drift {
+		return drift{resource: edgev1alpha1.ManagedResource{Kind: kind, Name: name}, current: &corev1.Secret{}}
+	}
+	deleted := func(kind, name string) drift {
+		return drift{resource: edgev1alpha1.ManagedResource{Kind: kind, Name: name}}
+	}
+	drifted := []drift{
+		modified(kindSecret, "acme-zitadel-masterkey"),
+		deleted(kindSecret, "acme-postgresql"),
+		deleted(kindSecret, "acme-pguser-postgres"),
+		modified(kindSecret, "acme-pguser-zitadel"),
+		deleted(kindSecret, "acme-minio-root"),
+		modified(kindSecret, "acme-connection"),
+		modified(kindRelease, "acme-postgres"),
+	}
+
+	repairable, unrepairable := partitionDrift(project, drifted)
+	names := func(drifts []drift) []string {
+		out := []string{}
+		for _, d := range drifts {
+			out = append(out, d.resource.Name)
+		}
+		return out
+	}
+	if got, want := names(unrepairable), []string{"acme-zitadel-masterkey", "acme-postgresql",
+		"acme-minio-root"}; !slices.Equal(got, want) {
+		t.Errorf("got unrepairable %v, want %v", got, want)
+	}
+	// The role secrets are rebuilt from the superuser's credentials
+	if got, want := names(repairable), []string{"acme-pguser-postgres", "acme-pguser-zitadel",
+		"acme-connection", "acme-postgres"}; !slices.Equal(got, want) {
+		t.Errorf("got repairable %v, want %v", got, want)
+	}
+}
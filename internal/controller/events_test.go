@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+func TestRecordTransition(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &ProjectReconciler{Recorder: recorder}
+	project := &edgev1alpha1.Project{}
+
+	transition := func(status metav1.ConditionStatus, reason, message string) {
+		cond := metav1.Condition{Type: "PostgresReady", Status: status, Reason: reason, Message: message}
+		r.recordTransition(project, cond)
+		meta.SetStatusCondition(&project.Status.Conditions, cond)
+	}
+
+	transition(metav1.ConditionFalse, common.ReasonProgressing, "Installation in progress")
+	transition(metav1.ConditionFalse, common.ReasonProgressing, "Waiting for statefulset demo-postgresql (0/1 ready)")
+	transition(metav1.ConditionFalse, common.ReasonComponentError, "Release error: timed out")
+	transition(metav1.ConditionTrue, common.ReasonReady, "PostgreSQL is ready")
+
+	want := []string{
+		"Normal Progressing PostgresReady: Installation in progress",
+		"Warning ComponentError PostgresReady: Release error: timed out",
+		"Normal Ready PostgresReady: PostgreSQL is ready",
+	}
+	if len(recorder.Events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(recorder.Events), len(want))
+	}
+	for _, w := range want {
+		if got := <-recorder.Events; got != w {
+			t.Errorf("got event %q, want %q", got, w)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// availableExtension is an extension the server provides, from pg_available_extensions
+type availableExtension struct {
+	defaultVersion   string
+	installedVersion string
+	schema           string
+	versions         []string
+}
+
+// reconcileExtensions creates the extensions declared for the databases in spec.database.databases
+// or updates them to their version, in the built-in PostgreSQL or an external one. Extensions the
+// server doesn't provide, or fails to install, are reported in the project's status without
+// failing the reconciliation.
+func (r *ProjectReconciler) reconcileExtensions(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	statuses := []edgev1alpha1.ExtensionStatus{}
+	for _, database := range project.Spec.Database.Databases {
+		if len(database.Extensions) == 0 {
+			continue
+		}
+		databaseStatuses, err := r.ensureExtensions(ctx, project, ref, database)
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, databaseStatuses...)
+	}
+
+	for _, status := range statuses {
+		if !status.Available && !slices.Contains(project.Status.Extensions, status) {
+			r.event(project, corev1.EventTypeWarning, common.ReasonExtensionUnavailable,
+				"Extension %s of database %s: %s", status.Name, status.Database, status.Message)
+		}
+	}
+	if len(statuses) == 0 {
+		statuses = nil
+	}
+	if equality.Semantic.DeepEqual(project.Status.Extensions, statuses) {
+		return nil
+	}
+	patch := client.MergeFrom(project.DeepCopy())
+	project.Status.Extensions = statuses
+	return r.patchStatus(ctx, project, patch)
+}
+
+// ensureExtensions reconciles the extensions of a database
+func (r *ProjectReconciler) ensureExtensions(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef, database edgev1alpha1.PostgresDatabase) ([]edgev1alpha1.ExtensionStatus, error) {
+	logger := log.FromContext(ctx)
+
+	config, err := r.postgresDatabaseConnConfig(ctx, project, ref, database.Name)
+	if err != nil {
+		return nil, err
+	}
+	dbCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	pool, err := pgConnectWithRetry(dbCtx, config, 3, 2*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database %s: %w", database.Name, err)
+	}
+	defer pool.Close()
+
+	statuses := []edgev1alpha1.ExtensionStatus{}
+	for _, extension := range database.Extensions {
+		status := edgev1alpha1.ExtensionStatus{Database: database.Name, Name: extension.Name}
+
+		available, err := queryAvailableExtension(dbCtx, pool, extension.Name)
+		if err != nil {
+			return nil, err
+		}
+		stmts, unavailable := extensionStatements(extension, available)
+		if unavailable != "" {
+			status.Message = unavailable
+			if available != nil {
+				status.Version = available.installedVersion
+			}
+			statuses = append(statuses, status)
+			continue
+		}
+
+		status.Available = true
+		for _, stmt := range stmts {
+			logger.Info("Reconciling extension", "database", database.Name, "statement", stmt)
+			if _, err := pool.Exec(dbCtx, stmt); err != nil {
+				status.Message = fmt.Sprintf("%q failed: %v", stmt, err)
+				break
+			}
+		}
+		if status.Message == "" {
+			// Read back the version installed, ALTER EXTENSION UPDATE may stop short of the default
+			if available, err = queryAvailableExtension(dbCtx, pool, extension.Name); err != nil {
+				return nil, err
+			}
+		}
+		status.Version = available.installedVersion
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// queryAvailableExtension returns the versions of an extension the server provides and the one
+// installed in the connected database, or nil if the server doesn't provide it
+func queryAvailableExtension(ctx context.Context, pool *pgxpool.Pool, name string) (*availableExtension, error) {
+	available := &availableExtension{}
+	var installed, schema *string
+	err := pool.QueryRow(ctx, `SELECT a.default_version, a.installed_version, n.nspname
+		FROM pg_available_extensions a
+		LEFT JOIN pg_extension e ON e.extname = a.name
+		LEFT JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE a.name = $1`, name).Scan(&available.defaultVersion, &installed, &schema)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to query extension %s: %w", name, err)
+	}
+	if installed != nil {
+		available.installedVersion = *installed
+	}
+	if schema != nil {
+		available.schema = *schema
+	}
+
+	rows, err := pool.Query(ctx, "SELECT version FROM pg_available_extension_versions WHERE name = $1", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query versions of extension %s: %w", name, err)
+	}
+	if available.versions, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+		return nil, fmt.Errorf("failed to query versions of extension %s: %w", name, err)
+	}
+	return available, nil
+}
+
+// extensionStatements renders the statements creating or updating an extension, or the reason it
+// can't be installed
+func extensionStatements(extension edgev1alpha1.PostgresExtension, available *availableExtension) ([]string, string) {
+	if available == nil {
+		return nil, "not available on the server"
+	}
+	if extension.Version != "" && !slices.Contains(available.versions, extension.Version) {
+		return nil, fmt.Sprintf("version %s not available, the server provides %s", extension.Version,
+			strings.Join(available.versions, ", "))
+	}
+
+	name := pgx.Identifier{extension.Name}.Sanitize()
+	if available.installedVersion == "" {
+		stmt := fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", name)
+		if extension.Schema != "" {
+			stmt += " SCHEMA " + pgx.Identifier{extension.Schema}.Sanitize()
+		}
+		if extension.Version != "" {
+			stmt += " VERSION " + quoteLiteral(extension.Version)
+		}
+		return []string{stmt + " CASCADE"}, ""
+	}
+
+	stmts := []string{}
+	switch version := extension.Version; {
+	case version != "" && version != available.installedVersion:
+		stmts = append(stmts, fmt.Sprintf("ALTER EXTENSION %s UPDATE TO %s", name, quoteLiteral(version)))
+	case version == "" && available.installedVersion != available.defaultVersion:
+		stmts = append(stmts, fmt.Sprintf("ALTER EXTENSION %s UPDATE", name))
+	}
+	if extension.Schema != "" && extension.Schema != available.schema {
+		stmts = append(stmts, fmt.Sprintf("ALTER EXTENSION %s SET SCHEMA %s", name,
+			pgx.Identifier{extension.Schema}.Sanitize()))
+	}
+	return stmts, ""
+}
+
+// quoteLiteral quotes a SQL string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
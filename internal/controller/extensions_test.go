@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestExtensionStatements(t *testing.T) {
+	available := &availableExtension{defaultVersion: "0.8.0", versions: []string{"0.7.4", "0.8.0"}}
+	tests := []struct {
+		name        string
+		extension   edgev1alpha1.PostgresExtension
+		installed   string
+		schema      string
+		want        []string
+		unavailable bool
+	}{
+		{"create", edgev1alpha1.PostgresExtension{Name: "vector"}, "", "",
+			[]string{`CREATE EXTENSION IF NOT EXISTS "vector" CASCADE`}, false},
+		{"create version in schema", edgev1alpha1.PostgresExtension{Name: "vector", Version: "0.7.4", Schema: "ext"}, "", "",
+			[]string{`CREATE EXTENSION IF NOT EXISTS "vector" SCHEMA "ext" VERSION '0.7.4' CASCADE`}, false},
+		{"up to date", edgev1alpha1.PostgresExtension{Name: "vector"}, "0.8.0", "public", []string{}, false},
+		{"update to default", edgev1alpha1.PostgresExtension{Name: "vector"}, "0.7.4", "public",
+			[]string{`ALTER EXTENSION "vector" UPDATE`}, false},
+		{"update to version and move", edgev1alpha1.PostgresExtension{Name: "vector", Version: "0.8.0", Schema: "ext"},
+			"0.7.4", "public", []string{`ALTER EXTENSION "vector" UPDATE TO '0.8.0'`,
+				`ALTER EXTENSION "vector" SET SCHEMA "ext"`}, false},
+		{"pinned older version", edgev1alpha1.PostgresExtension{Name: "vector", Version: "0.7.4"}, "0.7.4", "public",
+			[]string{}, false},
+		{"version unavailable", edgev1alpha1.PostgresExtension{Name: "vector", Version: "0.9.0"}, "", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := *available
+			a.installedVersion, a.schema = tt.installed, tt.schema
+			got, unavailable := extensionStatements(tt.extension, &a)
+			if (unavailable != "") != tt.unavailable {
+				t.Fatalf("unexpected availability %q", unavailable)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("unexpected statements %q", got)
+			}
+		})
+	}
+
+	if _, unavailable := extensionStatements(edgev1alpha1.PostgresExtension{Name: "postgis"}, nil); unavailable == "" {
+		t.Error("extension missing on the server reported available")
+	}
+}
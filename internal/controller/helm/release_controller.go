@@ -20,27 +20,43 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
 	"github.com/edgeflare/edge/internal/common"
+	"github.com/edgeflare/edge/internal/metrics"
 	"github.com/edgeflare/edge/internal/util/helm"
 )
 
 type ReleaseReconciler struct {
 	client.Client
-	Scheme     *runtime.Scheme
+	Scheme *runtime.Scheme
+	// HelmClient's operations are recorded as the operator's Helm metrics
 	HelmClient *helm.Client
+	// Recorder records installs, upgrades, failed revisions and uninstalls as Events. No Events
+	// are recorded if nil
+	Recorder record.EventRecorder
 }
 
 const (
@@ -51,6 +67,8 @@ const (
 // +kubebuilder:rbac:groups=helm.edgeflare.io,resources=releases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=helm.edgeflare.io,resources=releases/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=helm.edgeflare.io,resources=releases/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=edgeflare.io,resources=projects,verbs=get;list;watch
 func (r *ReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("Starting reconciliation", "namespace", req.Namespace, "name", req.Name)
@@ -67,6 +85,7 @@ func (r *ReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		if err != nil {
 			return r.handleError(ctx, release, err)
 		}
+		helmClient.Observer = metrics.ObserveHelmOperation
 		r.HelmClient = helmClient
 	}
 
@@ -85,8 +104,33 @@ func (r *ReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, nil
 	}
 
+	// Leave the release as it is while it or its project is suspended
+	suspendedBy, err := r.suspendedBy(ctx, release)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if suspendedBy != "" {
+		logger.Info("Release is suspended", "by", suspendedBy)
+		return ctrl.Result{}, r.setSuspended(ctx, release, suspendedBy)
+	}
+
+	// A resumed release is upgraded even without changes, restoring what was altered meanwhile
+	resumed := meta.IsStatusConditionTrue(release.Status.Conditions, common.ConditionTypeSuspended)
+	if resumed {
+		meta.SetStatusCondition(&release.Status.Conditions, metav1.Condition{
+			Type:    common.ConditionTypeSuspended,
+			Status:  metav1.ConditionFalse,
+			Reason:  common.ReasonResumed,
+			Message: "Reconciliation resumed",
+		})
+		if err := r.Status().Update(ctx, release); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.event(release, corev1.EventTypeNormal, common.ReasonResumed, "Reconciliation resumed")
+	}
+
 	// Skip reconciliation if no changes detected
-	if !r.shouldReconcile(release) {
+	if !resumed && !r.shouldReconcile(release) {
 		logger.Info("No changes detected, skipping reconciliation")
 		return ctrl.Result{}, nil
 	}
@@ -100,7 +144,10 @@ func (r *ReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	}
 
 	releaseResult, err := r.HelmClient.Install(ctx, releaseSpec)
-	if err != nil {
+	if invalidValues := (&helm.InvalidValuesError{}); errors.As(err, &invalidValues) {
+		return r.handleInvalidValues(ctx, release, invalidValues)
+	} else if err != nil {
+		r.recordFailedRevision(release, releaseResult)
 		return r.handleError(ctx, release, err)
 	}
 
@@ -129,15 +176,7 @@ func (r *ReleaseReconciler) handleDeletion(ctx context.Context, release *helmv1a
 		if err != nil {
 			logger.Error(err, "Failed to list Helm releases, proceeding with finalizer removal")
 			// Update status to indicate error before removing finalizer
-			release.Status.Conditions = []metav1.Condition{
-				{
-					Type:               common.ConditionTypeError,
-					Status:             metav1.ConditionTrue,
-					LastTransitionTime: metav1.Now(),
-					Reason:             "UninstallError",
-					Message:            fmt.Sprintf("Failed to list Helm releases: %v", err),
-				},
-			}
+			r.setUninstallError(release, fmt.Sprintf("Failed to list Helm releases: %v", err))
 			if updateErr := r.Status().Update(ctx, release); updateErr != nil {
 				logger.Error(updateErr, "Failed to update release status")
 			}
@@ -151,25 +190,33 @@ func (r *ReleaseReconciler) handleDeletion(ctx context.Context, release *helmv1a
 				}
 			}
 
+			// The Project sets the deletion policy of its component's release
+			policy := edgev1alpha1.DeletionPolicy(release.Annotations[common.AnnotationDeletionPolicy])
+			var keepKinds []string
+			if policy == edgev1alpha1.DeletionPolicyRetain {
+				keepKinds = append(keepKinds, "PersistentVolumeClaim")
+			}
+
 			// Attempt uninstallation if release exists
-			if releaseExists {
-				if err := r.HelmClient.Uninstall(ctx, release.Name, release.Namespace); err != nil {
+			if releaseExists && policy == edgev1alpha1.DeletionPolicyOrphan {
+				logger.Info("Orphaning Helm release, skipping uninstallation")
+				r.event(release, corev1.EventTypeNormal, common.ReasonOrphaned, "Left Helm release installed")
+			} else if releaseExists {
+				if err := r.HelmClient.Uninstall(ctx, release.Name, release.Namespace, keepKinds...); err != nil {
 					logger.Error(err, "Failed to uninstall Helm release, proceeding with finalizer removal")
 					// Update status to indicate uninstall error before removing finalizer
-					release.Status.Conditions = []metav1.Condition{
-						{
-							Type:               common.ConditionTypeError,
-							Status:             metav1.ConditionTrue,
-							LastTransitionTime: metav1.Now(),
-							Reason:             "UninstallError",
-							Message:            fmt.Sprintf("Uninstall failed: %v", err),
-						},
-					}
+					r.setUninstallError(release, fmt.Sprintf("Uninstall failed: %v", err))
 					if updateErr := r.Status().Update(ctx, release); updateErr != nil {
 						logger.Error(updateErr, "Failed to update release status")
 					}
 				} else {
 					logger.Info("Successfully uninstalled Helm release")
+					if len(keepKinds) > 0 {
+						r.event(release, corev1.EventTypeNormal, common.ReasonUninstalled,
+							"Uninstalled Helm release, keeping %s", strings.Join(keepKinds, ", "))
+					} else {
+						r.event(release, corev1.EventTypeNormal, common.ReasonUninstalled, "Uninstalled Helm release")
+					}
 				}
 			} else {
 				logger.Info("Helm release not found, skipping uninstallation")
@@ -188,6 +235,48 @@ func (r *ReleaseReconciler) handleDeletion(ctx context.Context, release *helmv1a
 	return ctrl.Result{}, nil
 }
 
+// suspendedBy describes who suspended the release or the Project owning it, or returns "" if
+// neither is suspended
+func (r *ReleaseReconciler) suspendedBy(ctx context.Context, release *helmv1alpha1.Release) (string, error) {
+	if release.Spec.Suspend {
+		return common.SuspendedBy(release), nil
+	}
+
+	owner := metav1.GetControllerOf(release)
+	if owner == nil || owner.Kind != "Project" || owner.APIVersion != edgev1alpha1.GroupVersion.String() {
+		return "", nil
+	}
+	project := &edgev1alpha1.Project{}
+	err := r.Get(ctx, types.NamespacedName{Name: owner.Name, Namespace: release.Namespace}, project)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if !project.Spec.Suspend {
+		return "", nil
+	}
+	return fmt.Sprintf("Project %s (%s)", project.Name, common.SuspendedBy(project)), nil
+}
+
+// setSuspended records who suspended the release in its Suspended condition
+func (r *ReleaseReconciler) setSuspended(ctx context.Context, release *helmv1alpha1.Release, suspendedBy string) error {
+	message := fmt.Sprintf("Suspended by %s", suspendedBy)
+	if cond := meta.FindStatusCondition(release.Status.Conditions, common.ConditionTypeSuspended); cond != nil &&
+		cond.Status == metav1.ConditionTrue && cond.Message == message {
+		return nil
+	}
+
+	r.event(release, corev1.EventTypeNormal, common.ReasonSuspended, "%s", message)
+	meta.SetStatusCondition(&release.Status.Conditions, metav1.Condition{
+		Type:    common.ConditionTypeSuspended,
+		Status:  metav1.ConditionTrue,
+		Reason:  common.ReasonSuspended,
+		Message: message,
+	})
+	return r.Status().Update(ctx, release)
+}
+
 // shouldReconcile checks if reconciliation is needed based on changes to values or chart version.
 func (r *ReleaseReconciler) shouldReconcile(release *helmv1alpha1.Release) bool {
 	if release.Annotations == nil {
@@ -233,17 +322,24 @@ func (r *ReleaseReconciler) updateReleaseState(ctx context.Context, release *hel
 		return err
 	}
 
-	// Update status fields
-	release.Status.Conditions = []metav1.Condition{
-		{
-			Type:               common.ConditionTypeInstalled,
-			Status:             metav1.ConditionTrue,
-			LastTransitionTime: metav1.Now(),
-			Reason:             "InstallationSucceeded",
-			Message:            "Helm release installed/upgraded successfully",
-		},
+	if releaseResult.Version == 1 {
+		r.event(release, corev1.EventTypeNormal, common.ReasonInstalled, "Installed chart %s",
+			release.Spec.ChartURL)
+	} else {
+		r.event(release, corev1.EventTypeNormal, common.ReasonUpgraded, "Upgraded to revision %d of chart %s",
+			releaseResult.Version, release.Spec.ChartURL)
 	}
 
+	// Update status fields, earlier failures are resolved by the successful revision
+	meta.SetStatusCondition(&release.Status.Conditions, metav1.Condition{
+		Type:    common.ConditionTypeInstalled,
+		Status:  metav1.ConditionTrue,
+		Reason:  common.ReasonInstallationSucceeded,
+		Message: "Helm release installed/upgraded successfully",
+	})
+	meta.RemoveStatusCondition(&release.Status.Conditions, common.ConditionTypeError)
+	meta.RemoveStatusCondition(&release.Status.Conditions, common.ConditionTypeValuesInvalid)
+
 	release.Status.HelmStatus = releaseResult.Info.Status
 	release.Status.FirstDeployed = releaseResult.Info.FirstDeployed.String()
 	release.Status.LastDeployed = releaseResult.Info.LastDeployed.String()
@@ -257,16 +353,15 @@ func (r *ReleaseReconciler) handleError(ctx context.Context, release *helmv1alph
 	logger := log.FromContext(ctx)
 	logger.Error(err, "Reconciliation failed")
 
-	// Update status with error information
-	release.Status.Conditions = []metav1.Condition{
-		{
-			Type:               common.ConditionTypeError,
-			Status:             metav1.ConditionTrue,
-			LastTransitionTime: metav1.Now(),
-			Reason:             "InstallationFailed",
-			Message:            err.Error(),
-		},
-	}
+	r.event(release, corev1.EventTypeWarning, common.ReasonInstallationFailed, "%v", err)
+
+	// Update status with error information, keeping the conditions of the revision still installed
+	meta.SetStatusCondition(&release.Status.Conditions, metav1.Condition{
+		Type:    common.ConditionTypeError,
+		Status:  metav1.ConditionTrue,
+		Reason:  common.ReasonInstallationFailed,
+		Message: err.Error(),
+	})
 
 	if updateErr := r.Status().Update(ctx, release); updateErr != nil {
 		logger.Error(updateErr, "Failed to update error status")
@@ -276,10 +371,97 @@ func (r *ReleaseReconciler) handleError(ctx context.Context, release *helmv1alph
 	return ctrl.Result{}, err
 }
 
-// SetupWithManager sets up the controller with the Manager.
+// recordFailedRevision records the revision Helm marked failed during a failed install or upgrade.
+// Failed upgrades aren't rolled back, the previous revision's resources keep serving until the
+// spec is fixed, so Helm never reports a rolled back revision to the controller.
+func (r *ReleaseReconciler) recordFailedRevision(release *helmv1alpha1.Release, releaseResult *release.Release) {
+	if !failedRevision(releaseResult) {
+		return
+	}
+	release.Status.HelmStatus = releaseResult.Info.Status
+	r.event(release, corev1.EventTypeWarning, common.ReasonRevisionFailed, "Revision %d of chart %s failed: %s",
+		releaseResult.Version, release.Spec.ChartURL, releaseResult.Info.Description)
+}
+
+// failedRevision reports whether Helm marked the revision rel failed
+func failedRevision(rel *release.Release) bool {
+	return rel != nil && rel.Info != nil && rel.Info.Status == release.StatusFailed
+}
+
+// handleInvalidValues records a ValuesInvalid condition. Retrying can't fix the values,
+// so the release isn't requeued until its spec changes.
+func (r *ReleaseReconciler) handleInvalidValues(ctx context.Context, release *helmv1alpha1.Release,
+	invalidValues *helm.InvalidValuesError) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("Release values failed schema validation", "errors", invalidValues.Errors)
+
+	// Skip the status update if it's already recorded, it would only trigger another reconciliation
+	if cond := meta.FindStatusCondition(release.Status.Conditions, common.ConditionTypeValuesInvalid); cond != nil &&
+		cond.Status == metav1.ConditionTrue && cond.Message == invalidValues.Error() {
+		return ctrl.Result{}, nil
+	}
+
+	r.event(release, corev1.EventTypeWarning, common.ReasonSchemaValidationFailed, "%v", invalidValues)
+
+	meta.SetStatusCondition(&release.Status.Conditions, metav1.Condition{
+		Type:    common.ConditionTypeValuesInvalid,
+		Status:  metav1.ConditionTrue,
+		Reason:  common.ReasonSchemaValidationFailed,
+		Message: invalidValues.Error(),
+	})
+
+	if updateErr := r.Status().Update(ctx, release); updateErr != nil {
+		logger.Error(updateErr, "Failed to update values status")
+		return ctrl.Result{}, updateErr
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// setUninstallError records a failed uninstall as the release's Error condition and an Event
+func (r *ReleaseReconciler) setUninstallError(release *helmv1alpha1.Release, message string) {
+	r.event(release, corev1.EventTypeWarning, common.ReasonUninstallError, "%s", message)
+	meta.SetStatusCondition(&release.Status.Conditions, metav1.Condition{
+		Type:    common.ConditionTypeError,
+		Status:  metav1.ConditionTrue,
+		Reason:  common.ReasonUninstallError,
+		Message: message,
+	})
+}
+
+// event records an Event on the release if the reconciler has a recorder. The recorder aggregates
+// repeated Events, so retries of a failing release don't flood it.
+func (r *ReleaseReconciler) event(release *helmv1alpha1.Release, eventType, reason, messageFmt string, args ...any) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(release, eventType, reason, messageFmt, args...)
+	}
+}
+
+// releasesForProject maps a Project to the Releases of its components, which follow its suspension
+func (r *ReleaseReconciler) releasesForProject(ctx context.Context, obj client.Object) []reconcile.Request {
+	releases := &helmv1alpha1.ReleaseList{}
+	if err := r.List(ctx, releases, client.InNamespace(obj.GetNamespace()),
+		client.MatchingLabels{common.LabelProject: obj.GetName()}); err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(releases.Items))
+	for _, release := range releases.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&release)})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager. Projects are watched so that their
+// Releases are suspended and resumed with them.
 func (r *ReleaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.HelmClient != nil {
+		r.HelmClient.Observer = metrics.ObserveHelmOperation
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&helmv1alpha1.Release{}).
+		Watches(&edgev1alpha1.Project{}, handler.EnqueueRequestsFromMapFunc(r.releasesForProject),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Named("helm-release").
 		Complete(r)
 }
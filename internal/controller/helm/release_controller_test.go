@@ -18,11 +18,15 @@ package helm
 
 import (
 	"context"
+	"strings"
+	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/release"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -82,3 +86,31 @@ var _ = Describe("Release Controller", func() {
 		})
 	})
 })
+
+func TestRecordFailedRevision(t *testing.T) {
+	recorder := record.NewFakeRecorder(2)
+	r := &ReleaseReconciler{Recorder: recorder}
+	rel := &helmv1alpha1.Release{Spec: helmv1alpha1.ReleaseSpec{ChartURL: "registry-1.docker.io/bitnamicharts/minio:12.6.0"}}
+
+	r.recordFailedRevision(rel, &release.Release{Version: 3, Info: &release.Info{
+		Status: release.StatusFailed, Description: "Upgrade \"demo-minio\" failed: context deadline exceeded"}})
+	if rel.Status.HelmStatus != release.StatusFailed {
+		t.Errorf("got helm status %q", rel.Status.HelmStatus)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning RevisionFailed Revision 3 of chart") ||
+			!strings.Contains(event, "context deadline exceeded") {
+			t.Errorf("unexpected event %q", event)
+		}
+	default:
+		t.Error("no event for the failed revision")
+	}
+
+	// Errors before Helm recorded a revision, e.g. pulling the chart, have no revision to report
+	r.recordFailedRevision(rel, nil)
+	r.recordFailedRevision(rel, &release.Release{Version: 4, Info: &release.Info{Status: release.StatusDeployed}})
+	if len(recorder.Events) != 0 {
+		t.Errorf("got event %q without a failed revision", <-recorder.Events)
+	}
+}
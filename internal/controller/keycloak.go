@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/pgo/pkg/pgx/role"
+	"github.com/edgeflare/pgo/pkg/util/rand"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	keycloakAdminUser         = "admin"
+	keycloakAdminPasswordKey  = "admin-password"
+	keycloakPostgresDatabase  = "keycloak"
+	keycloakPostgresRole      = "keycloak"
+	keycloakDefaultRealm      = "master"
+	keycloakDefaultHTTPPrefix = "/"
+)
+
+// reconcileKeycloak handles the specific requirements for the Keycloak auth component.
+// It ensures the admin credentials secret and a dedicated PostgreSQL role and database
+// exist, and points the chart at them before the Helm release is created.
+func (r *ProjectReconciler) reconcileKeycloak(ctx context.Context, project *edgev1alpha1.Project,
+	name string, ref *edgev1alpha1.ComponentRef) error {
+	logger := log.FromContext(ctx)
+	compType := "auth"
+
+	// Parse the supplied values
+	keycloakValues := make(map[string]any)
+	if err := yaml.Unmarshal([]byte(ref.Release.ValuesContent), &keycloakValues); err != nil {
+		logger.Error(err, "Failed to parse Keycloak values")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Values error: %v", err), "")
+		return err
+	}
+	if keycloakValues == nil {
+		keycloakValues = make(map[string]any)
+	}
+
+	// Step 1: Ensure or verify the admin credentials secret
+	adminSecretName, err := r.reconcileKeycloakAdminSecret(ctx, project, keycloakValues)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile Keycloak admin secret")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Admin secret error: %v", err), "")
+		return err
+	}
+
+	// Step 2: Wait for PostgreSQL to be ready
+	if err := r.waitForPostgreSQLReady(ctx, project, capabilityCreateRole); err != nil {
+		logger.Error(err, "PostgreSQL is not ready")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Database error: %v", err), "")
+		return err
+	}
+
+	// Step 3: Ensure the Keycloak PostgreSQL role, database and connection secret
+	pgSecretName := fmt.Sprintf("%s-pguser-keycloak", project.Name)
+	keycloakPGRole := role.Role{
+		Name:      keycloakPostgresRole,
+		CanLogin:  true,
+		Inherit:   true,
+		ConnLimit: 100,
+	}
+	if err := r.ensurePostgresRoleSecret(ctx, project, pgSecretName, keycloakPGRole,
+		keycloakPostgresDatabase, true); err != nil {
+		logger.Error(err, "Failed to ensure Keycloak PostgreSQL connection secret")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Secret error: %v", err), "")
+		return err
+	}
+
+	// Step 4: Update values to use the admin secret and the project database
+	pgSecret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: pgSecretName, Namespace: project.Namespace}, pgSecret); err != nil {
+		return fmt.Errorf("failed to get PostgreSQL secret %s: %w", pgSecretName, err)
+	}
+	r.updateKeycloakValuesWithAdminSecret(keycloakValues, adminSecretName)
+	r.updateKeycloakValuesWithDatabase(keycloakValues, pgSecret)
+
+	updatedValues, err := yaml.Marshal(keycloakValues)
+	if err != nil {
+		logger.Error(err, "Failed to marshal updated Keycloak values")
+		return err
+	}
+
+	logger.V(4).Info("Updated Keycloak values", "values", string(updatedValues))
+	ref.Release.ValuesContent = string(updatedValues)
+
+	return nil
+}
+
+// reconcileKeycloakAdminSecret checks for an existing admin secret and either uses it or creates a new one.
+// Returns the secret name of the admin credentials to use.
+func (r *ProjectReconciler) reconcileKeycloakAdminSecret(ctx context.Context, project *edgev1alpha1.Project,
+	values map[string]any) (string, error) {
+	logger := log.FromContext(ctx)
+
+	if auth, ok := values["auth"].(map[string]any); ok {
+		if es, ok := auth["existingSecret"].(string); ok && es != "" {
+			passwordKey := keycloakAdminPasswordKey
+			if key, ok := auth["passwordSecretKey"].(string); ok && key != "" {
+				passwordKey = key
+			}
+			if err := r.verifyKeycloakAdminSecret(ctx, project.Namespace, es, passwordKey); err != nil {
+				return "", err
+			}
+			logger.Info("Verified existing Keycloak admin secret", "name", es)
+			return es, nil
+		}
+	}
+
+	secretName := fmt.Sprintf("%s-keycloak-admin", project.Name)
+	if err := r.ensureKeycloakAdminSecret(ctx, project, secretName); err != nil {
+		return "", err
+	}
+	return secretName, nil
+}
+
+// ensureKeycloakAdminSecret creates the Keycloak admin secret if it doesn't exist.
+// An existing password is never regenerated.
+func (r *ProjectReconciler) ensureKeycloakAdminSecret(ctx context.Context,
+	project *edgev1alpha1.Project, secretName string) error {
+	existingSecret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, existingSecret)
+
+	if err == nil {
+		if pw, exists := existingSecret.Data[keycloakAdminPasswordKey]; exists && len(pw) > 0 {
+			return nil
+		}
+
+		if existingSecret.Data == nil {
+			existingSecret.Data = make(map[string][]byte)
+		}
+		existingSecret.Data["admin-user"] = []byte(keycloakAdminUser)
+		existingSecret.Data[keycloakAdminPasswordKey] = []byte(rand.NewPassword(16))
+		return r.Update(ctx, existingSecret)
+	} else if errors.IsNotFound(err) {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: project.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: project.APIVersion,
+						Kind:       project.Kind,
+						Name:       project.Name,
+						UID:        project.UID,
+						Controller: ptr.To(true),
+					},
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{
+				"admin-user":             []byte(keycloakAdminUser),
+				keycloakAdminPasswordKey: []byte(rand.NewPassword(16)),
+			},
+		}
+
+		return r.createSecret(ctx, project, secret)
+	}
+
+	return err
+}
+
+// verifyKeycloakAdminSecret verifies that a Keycloak admin secret exists and has the password key.
+func (r *ProjectReconciler) verifyKeycloakAdminSecret(ctx context.Context,
+	namespace, secretName, passwordKey string) error {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: namespace}, secret); err != nil {
+		return fmt.Errorf("keycloak admin secret %s not found: %w", secretName, err)
+	}
+
+	if pw, exists := secret.Data[passwordKey]; !exists || len(pw) == 0 {
+		return fmt.Errorf("keycloak admin secret %s is missing required key: %s", secretName, passwordKey)
+	}
+
+	return nil
+}
+
+// updateKeycloakValuesWithAdminSecret updates the Keycloak values to reference the admin secret.
+func (r *ProjectReconciler) updateKeycloakValuesWithAdminSecret(values map[string]any, secretName string) {
+	auth, ok := values["auth"].(map[string]any)
+	if !ok {
+		auth = make(map[string]any)
+		values["auth"] = auth
+	}
+
+	if _, ok := auth["adminUser"].(string); !ok {
+		auth["adminUser"] = keycloakAdminUser
+	}
+	if auth["existingSecret"] != secretName {
+		auth["existingSecret"] = secretName
+		auth["passwordSecretKey"] = keycloakAdminPasswordKey
+	}
+
+	// Ensure adminPassword is not set as it would be ignored in favour of the secret
+	delete(auth, "adminPassword")
+}
+
+// updateKeycloakValuesWithDatabase points Keycloak at the project's PostgreSQL, built-in or
+// external, through its connection secret instead of the chart's bundled database.
+func (r *ProjectReconciler) updateKeycloakValuesWithDatabase(values map[string]any, pgSecret *corev1.Secret) {
+	postgresql, ok := values["postgresql"].(map[string]any)
+	if !ok {
+		postgresql = make(map[string]any)
+		values["postgresql"] = postgresql
+	}
+	postgresql["enabled"] = false
+
+	port, err := strconv.Atoi(string(pgSecret.Data["PGPORT"]))
+	if err != nil {
+		port = 5432
+	}
+	values["externalDatabase"] = map[string]any{
+		"host":                      string(pgSecret.Data["PGHOST"]),
+		"port":                      port,
+		"user":                      keycloakPostgresRole,
+		"database":                  string(pgSecret.Data["PGDATABASE"]),
+		"existingSecret":            pgSecret.Name,
+		"existingSecretPasswordKey": "PGPASSWORD",
+	}
+}
+
+// keycloakIssuerURL returns the OIDC issuer of the Keycloak master realm. The ingress hostname is
+// preferred when exposed, otherwise the in-cluster service is used.
+func keycloakIssuerURL(project *edgev1alpha1.Project, valuesContent string) string {
+	values := make(map[string]any)
+	_ = yaml.Unmarshal([]byte(valuesContent), &values)
+
+	relativePath := keycloakDefaultHTTPPrefix
+	if p, ok := values["httpRelativePath"].(string); ok && p != "" {
+		relativePath = p
+	}
+
+	base := fmt.Sprintf("http://%s-keycloak.%s.svc.cluster.local", project.Name, project.Namespace)
+	if ingress, ok := values["ingress"].(map[string]any); ok {
+		if enabled, _ := ingress["enabled"].(bool); enabled {
+			if hostname, ok := ingress["hostname"].(string); ok && hostname != "" {
+				scheme := "http"
+				if tls, _ := ingress["tls"].(bool); tls {
+					scheme = "https"
+				}
+				base = fmt.Sprintf("%s://%s", scheme, hostname)
+			}
+		}
+	}
+
+	return fmt.Sprintf("%s%s/realms/%s", base, strings.TrimSuffix(relativePath, "/"), keycloakDefaultRealm)
+}
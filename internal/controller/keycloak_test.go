@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestUpdateKeycloakValuesWithAdminSecret(t *testing.T) {
+	r := &ProjectReconciler{}
+	values := map[string]any{"auth": map[string]any{"adminPassword": "changeme"}}
+
+	r.updateKeycloakValuesWithAdminSecret(values, "demo-keycloak-admin")
+	auth := values["auth"].(map[string]any)
+	if auth["existingSecret"] != "demo-keycloak-admin" || auth["passwordSecretKey"] != keycloakAdminPasswordKey {
+		t.Errorf("admin secret not referenced: %v", auth)
+	}
+	if auth["adminUser"] != keycloakAdminUser {
+		t.Errorf("got admin user %v, want %s", auth["adminUser"], keycloakAdminUser)
+	}
+	// The chart ignores the secret if a password is set inline
+	if _, ok := auth["adminPassword"]; ok {
+		t.Error("inline admin password kept")
+	}
+
+	values = map[string]any{"auth": map[string]any{"adminUser": "root"}}
+	r.updateKeycloakValuesWithAdminSecret(values, "demo-keycloak-admin")
+	if user := values["auth"].(map[string]any)["adminUser"]; user != "root" {
+		t.Errorf("declared admin user replaced by %v", user)
+	}
+}
+
+func TestUpdateKeycloakValuesWithDatabase(t *testing.T) {
+	r := &ProjectReconciler{}
+	// The role secret points at the project's PostgreSQL, built-in or external
+	pgSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-pguser-keycloak", Namespace: "apps"},
+		Data: map[string][]byte{
+			"PGHOST":     []byte("db.example.com"),
+			"PGPORT":     []byte("6432"),
+			"PGDATABASE": []byte("keycloak"),
+		},
+	}
+	values := map[string]any{"postgresql": map[string]any{"enabled": true}}
+
+	r.updateKeycloakValuesWithDatabase(values, pgSecret)
+	if enabled := values["postgresql"].(map[string]any)["enabled"]; enabled != false {
+		t.Errorf("bundled PostgreSQL still enabled")
+	}
+	db := values["externalDatabase"].(map[string]any)
+	want := map[string]any{
+		"host":                      "db.example.com",
+		"port":                      6432,
+		"user":                      keycloakPostgresRole,
+		"database":                  "keycloak",
+		"existingSecret":            "demo-pguser-keycloak",
+		"existingSecretPasswordKey": "PGPASSWORD",
+	}
+	for key, value := range want {
+		if db[key] != value {
+			t.Errorf("got externalDatabase.%s %v, want %v", key, db[key], value)
+		}
+	}
+
+	delete(pgSecret.Data, "PGPORT")
+	r.updateKeycloakValuesWithDatabase(values, pgSecret)
+	if port := values["externalDatabase"].(map[string]any)["port"]; port != 5432 {
+		t.Errorf("got default port %v, want 5432", port)
+	}
+}
+
+func TestKeycloakIssuerURL(t *testing.T) {
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+
+	tests := []struct {
+		values string
+		want   string
+	}{
+		{values: "", want: "http://demo-keycloak.apps.svc.cluster.local/realms/master"},
+		{
+			values: "httpRelativePath: /auth/\ningress:\n  enabled: true\n  hostname: sso.example.com\n  tls: true\n",
+			want:   "https://sso.example.com/auth/realms/master",
+		},
+		// The hostname is only used once the ingress is enabled
+		{
+			values: "ingress:\n  hostname: sso.example.com\n",
+			want:   "http://demo-keycloak.apps.svc.cluster.local/realms/master",
+		},
+	}
+	for _, tt := range tests {
+		if got := keycloakIssuerURL(project, tt.values); got != tt.want {
+			t.Errorf("got issuer %s, want %s", got, tt.want)
+		}
+	}
+}
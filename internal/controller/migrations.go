@@ -0,0 +1,337 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+	"github.com/edgeflare/edge/internal/util/oci"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	migrationsTable = "edge_schema_migrations"
+	// A file whose first line is the marker runs outside a transaction, e.g. for
+	// CREATE INDEX CONCURRENTLY. Its statements still run as one implicit transaction, such
+	// statements have to be alone in their file.
+	noTransactionMarker = "-- edge:no-transaction"
+)
+
+// migration is a SQL file of the project's migration sources
+type migration struct {
+	version       string
+	sql           string
+	checksum      string
+	transactional bool
+}
+
+// reconcileMigrations applies the pending SQL migrations of spec.database.migrations in order and
+// records them in the edge_schema_migrations table of the target database. The outcome is
+// reported in the MigrationsApplied condition. Failures don't fail the reconciliation of the
+// other components, they're retried on the next resync.
+func (r *ProjectReconciler) reconcileMigrations(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	spec := project.Spec.Database.Migrations
+	if spec == nil {
+		if project.Status.Migrations == nil &&
+			meta.FindStatusCondition(project.Status.Conditions, common.ConditionTypeMigrations) == nil {
+			return nil
+		}
+		patch := client.MergeFrom(project.DeepCopy())
+		project.Status.Migrations = nil
+		meta.RemoveStatusCondition(&project.Status.Conditions, common.ConditionTypeMigrations)
+		return r.patchStatus(ctx, project, patch)
+	}
+
+	migrations, digest, err := r.loadMigrations(ctx, project, spec)
+	if err != nil {
+		return r.setMigrationsCondition(ctx, project, metav1.ConditionFalse, common.ReasonMigrationFailed,
+			fmt.Sprintf("Failed to load migrations: %v", err), nil)
+	}
+
+	status, blocked, err := r.applyMigrations(ctx, project, ref, spec, migrations)
+	status.SourceDigest = digest
+	switch {
+	case blocked != "":
+		return r.setMigrationsCondition(ctx, project, metav1.ConditionFalse, common.ReasonChecksumMismatch,
+			fmt.Sprintf("Checksum of applied migration %s changed, further migrations are blocked", blocked), status)
+	case err != nil:
+		return r.setMigrationsCondition(ctx, project, metav1.ConditionFalse, common.ReasonMigrationFailed,
+			err.Error(), status)
+	}
+
+	message := fmt.Sprintf("Applied %d migrations to %s", status.Applied, spec.GetDatabase())
+	if status.Version != "" {
+		message += ", latest " + status.Version
+	}
+	return r.setMigrationsCondition(ctx, project, metav1.ConditionTrue, common.ReasonMigrationsApplied,
+		message, status)
+}
+
+// applyMigrations applies the pending migrations in order, after verifying the checksums of the
+// applied ones. It returns the version of an applied migration whose checksum changed, if any.
+func (r *ProjectReconciler) applyMigrations(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef, spec *edgev1alpha1.Migrations,
+	migrations []migration) (*edgev1alpha1.MigrationsStatus, string, error) {
+	logger := log.FromContext(ctx)
+	status := &edgev1alpha1.MigrationsStatus{}
+	if project.Status.Migrations != nil {
+		status.Version, status.Applied = project.Status.Migrations.Version, project.Status.Migrations.Applied
+	}
+
+	config, err := r.postgresDatabaseConnConfig(ctx, project, ref, spec.GetDatabase())
+	if err != nil {
+		return status, "", err
+	}
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	pool, err := pgConnectWithRetry(dbCtx, config, 3, 2*time.Second)
+	if err != nil {
+		return status, "", fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer pool.Close()
+	conn, err := pool.Acquire(dbCtx)
+	if err != nil {
+		return status, "", fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer conn.Release()
+
+	table := pgx.Identifier{migrationsTable}.Sanitize()
+	if _, err := conn.Exec(dbCtx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version text PRIMARY KEY,
+		checksum text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now())`, table)); err != nil {
+		return status, "", fmt.Errorf("failed to create %s: %w", migrationsTable, err)
+	}
+	rows, err := conn.Query(dbCtx, fmt.Sprintf("SELECT version, checksum FROM %s", table))
+	if err != nil {
+		return status, "", fmt.Errorf("failed to read %s: %w", migrationsTable, err)
+	}
+	applied := map[string]string{}
+	var version, checksum string
+	if _, err := pgx.ForEachRow(rows, []any{&version, &checksum}, func() error {
+		applied[version] = checksum
+		return nil
+	}); err != nil {
+		return status, "", fmt.Errorf("failed to read %s: %w", migrationsTable, err)
+	}
+
+	pending := []migration{}
+	status.Applied = 0
+	for _, m := range migrations {
+		checksum, ok := applied[m.version]
+		if !ok {
+			pending = append(pending, m)
+			continue
+		}
+		if checksum != m.checksum {
+			return status, m.version, nil
+		}
+		status.Version = m.version
+		status.Applied++
+	}
+
+	record := fmt.Sprintf("INSERT INTO %s (version, checksum) VALUES ($1, $2)", table)
+	for _, m := range pending {
+		logger.Info("Applying migration", "version", m.version, "database", spec.GetDatabase())
+		if err := applyMigration(dbCtx, conn.Conn(), m, spec.Role, record); err != nil {
+			return status, "", fmt.Errorf("migration %s failed: %w", m.version, err)
+		}
+		status.Version = m.version
+		status.Applied++
+	}
+
+	// Grant the declared privileges on the tables the migrations created
+	if len(pending) > 0 {
+		if grants := declaredGrants(project.Spec.Database.Roles); len(grants) > 0 && !ref.IsExternal() {
+			if err := r.applyGrants(ctx, project, grants, nil); err != nil {
+				return status, "", err
+			}
+		}
+	}
+	return status, "", nil
+}
+
+// applyMigration runs a migration as role, if set, and records it as the connecting user, in one
+// transaction unless the migration opts out
+func applyMigration(ctx context.Context, conn *pgx.Conn, m migration, role, record string) error {
+	statements := []string{m.sql}
+	if role != "" {
+		setRole := "SET ROLE "
+		if m.transactional {
+			setRole = "SET LOCAL ROLE "
+		}
+		statements = []string{setRole + pgx.Identifier{role}.Sanitize(), m.sql, "RESET ROLE"}
+	}
+
+	if !m.transactional {
+		for _, stmt := range statements {
+			if _, err := conn.Exec(ctx, stmt); err != nil {
+				_, _ = conn.Exec(ctx, "RESET ROLE")
+				return err
+			}
+		}
+		_, err := conn.Exec(ctx, record, m.version, m.checksum)
+		return err
+	}
+
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.Exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(ctx, record, m.version, m.checksum)
+		return err
+	})
+}
+
+// loadMigrations reads the SQL files of the migration sources in the order they're applied, and
+// returns them along with a digest of the set
+func (r *ProjectReconciler) loadMigrations(ctx context.Context, project *edgev1alpha1.Project,
+	spec *edgev1alpha1.Migrations) ([]migration, string, error) {
+	files := map[string][]byte{}
+	add := func(name string, data []byte, source string) error {
+		if !strings.HasSuffix(name, ".sql") {
+			return nil
+		}
+		if _, exists := files[name]; exists {
+			return fmt.Errorf("migration %s of %s is defined twice", name, source)
+		}
+		files[name] = data
+		return nil
+	}
+
+	for _, name := range spec.ConfigMaps {
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: project.Namespace}, configMap); err != nil {
+			return nil, "", fmt.Errorf("failed to get ConfigMap %s: %w", name, err)
+		}
+		for key, data := range configMap.Data {
+			if err := add(key, []byte(data), "ConfigMap "+name); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	if spec.OCI != nil {
+		var creds *oci.Credentials
+		if spec.OCI.PullSecret != "" {
+			secret := &corev1.Secret{}
+			if err := r.Get(ctx, types.NamespacedName{Name: spec.OCI.PullSecret, Namespace: project.Namespace},
+				secret); err != nil {
+				return nil, "", fmt.Errorf("failed to get pull secret %s: %w", spec.OCI.PullSecret, err)
+			}
+			var err error
+			if creds, err = oci.CredentialsFromDockerConfig(secret.Data[corev1.DockerConfigJsonKey],
+				spec.OCI.Ref); err != nil {
+				return nil, "", err
+			}
+		}
+		pullCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		artifact, err := oci.PullFiles(pullCtx, spec.OCI.Ref, creds)
+		if err != nil {
+			return nil, "", err
+		}
+		for name, data := range artifact {
+			if err := add(name, data, spec.OCI.Ref); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	migrations, digest := orderMigrations(files)
+	return migrations, digest, nil
+}
+
+// orderMigrations sorts SQL files by name into migrations and digests the set
+func orderMigrations(files map[string][]byte) ([]migration, string) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	migrations := make([]migration, 0, len(names))
+	set := sha256.New()
+	for _, name := range names {
+		sum := sha256.Sum256(files[name])
+		m := migration{
+			version:       strings.TrimSuffix(name, ".sql"),
+			sql:           string(files[name]),
+			checksum:      hex.EncodeToString(sum[:]),
+			transactional: !strings.HasPrefix(strings.TrimSpace(string(files[name])), noTransactionMarker),
+		}
+		migrations = append(migrations, m)
+		fmt.Fprintf(set, "%s %s\n", m.version, m.checksum)
+	}
+	return migrations, hex.EncodeToString(set.Sum(nil))
+}
+
+// migrationsChanged reports whether the migration sources differ from the set last applied. It's
+// checked on resyncs that would otherwise skip the reconciliation, e.g. for a new artifact pushed
+// under the same tag
+func (r *ProjectReconciler) migrationsChanged(ctx context.Context, project *edgev1alpha1.Project) bool {
+	if project.Spec.Database == nil || project.Spec.Database.Migrations == nil {
+		return project.Status.Migrations != nil
+	}
+	if project.Status.Migrations == nil {
+		return true
+	}
+	_, digest, err := r.loadMigrations(ctx, project, project.Spec.Database.Migrations)
+	return err != nil || digest != project.Status.Migrations.SourceDigest
+}
+
+func (r *ProjectReconciler) setMigrationsCondition(ctx context.Context, project *edgev1alpha1.Project,
+	status metav1.ConditionStatus, reason, message string, migrations *edgev1alpha1.MigrationsStatus) error {
+	patch := client.MergeFrom(project.DeepCopy())
+	cond := metav1.Condition{
+		Type:               common.ConditionTypeMigrations,
+		Status:             status,
+		ObservedGeneration: project.Generation,
+		Reason:             reason,
+		Message:            message,
+	}
+	r.recordTransition(project, cond)
+	changed := meta.SetStatusCondition(&project.Status.Conditions, cond)
+	if migrations != nil && (project.Status.Migrations == nil || *project.Status.Migrations != *migrations) {
+		project.Status.Migrations = migrations
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return r.patchStatus(ctx, project, patch)
+}
+
+// projectsForMigrationConfigMap maps a ConfigMap to the Projects sourcing migrations from it
+func (r *ProjectReconciler) projectsForMigrationConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	projects := &edgev1alpha1.ProjectList{}
+	if err := r.List(ctx, projects, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, project := range projects.Items {
+		if project.Spec.Database != nil && project.Spec.Database.Migrations != nil &&
+			slices.Contains(project.Spec.Database.Migrations.ConfigMaps, obj.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&project)})
+		}
+	}
+	return requests
+}
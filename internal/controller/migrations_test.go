@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestOrderMigrations(t *testing.T) {
+	files := map[string][]byte{
+		"0002_index.sql": []byte("-- edge:no-transaction\nCREATE INDEX CONCURRENTLY orders_customer ON orders (customer);\n"),
+		"0001_init.sql":  []byte("CREATE TABLE orders (id bigint PRIMARY KEY, customer text);\n"),
+	}
+	migrations, digest := orderMigrations(files)
+	if len(migrations) != 2 || migrations[0].version != "0001_init" || migrations[1].version != "0002_index" {
+		t.Fatalf("unexpected order %+v", migrations)
+	}
+	if !migrations[0].transactional || migrations[1].transactional {
+		t.Error("only the marked migration should run outside a transaction")
+	}
+	if len(migrations[0].checksum) != 64 {
+		t.Errorf("unexpected checksum %q", migrations[0].checksum)
+	}
+
+	files["0001_init.sql"] = []byte("CREATE TABLE orders (id bigint PRIMARY KEY);\n")
+	changed, changedDigest := orderMigrations(files)
+	if changedDigest == digest || changed[0].checksum == migrations[0].checksum {
+		t.Error("changing a file didn't change its checksum and the digest")
+	}
+	if _, again := orderMigrations(files); again != changedDigest {
+		t.Error("digest isn't stable")
+	}
+}
+
+func TestMigrationConfigMapRequests(t *testing.T) {
+	demo := &edgev1alpha1.Project{}
+	demo.Name, demo.Namespace = "demo", "apps"
+	demo.Spec.Database = &edgev1alpha1.Database{
+		Migrations: &edgev1alpha1.Migrations{ConfigMaps: []string{"schema"}},
+	}
+	other := &edgev1alpha1.Project{}
+	other.Name, other.Namespace = "other", "apps"
+
+	scheme := runtime.NewScheme()
+	if err := edgev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	r := &ProjectReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(demo, other).Build()}
+	mapFn := withOwnerProject(r.projectsForMigrationConfigMap)
+
+	// A migrations ConfigMap is mapped to the Projects sourcing it, and the connection ConfigMap
+	// to the Project owning it
+	schema := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "schema", Namespace: "apps"}}
+	connection := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other-connection", Namespace: "apps",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: edgev1alpha1.GroupVersion.String(), Kind: "Project",
+			Name: "other", UID: "uid", Controller: ptr.To(true)}}}}
+
+	tests := []struct {
+		configMap *corev1.ConfigMap
+		want      []string
+	}{
+		{configMap: schema, want: []string{"demo"}},
+		{configMap: connection, want: []string{"other"}},
+		{configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "apps"}}},
+	}
+	for _, tt := range tests {
+		requests := mapFn(context.Background(), tt.configMap)
+		want := []reconcile.Request{}
+		for _, name := range tt.want {
+			want = append(want, reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "apps"}})
+		}
+		if !slices.Equal(requests, want) {
+			t.Errorf("got requests %v for ConfigMap %s, want %v", requests, tt.configMap.Name, want)
+		}
+	}
+}
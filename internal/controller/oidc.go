@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// oidcHTTPTimeout bounds every request made against an identity provider
+const oidcHTTPTimeout = 10 * time.Second
+
+// oidcMaxBodySize bounds the responses read from an identity provider. Discovery documents and
+// key sets are a few KiB
+const oidcMaxBodySize = 1 << 20
+
+// Keys of the secret referenced by an external identity provider
+const (
+	idpIssuerKey       = "issuer"
+	idpClientIDKey     = "client-id"
+	idpClientSecretKey = "client-secret"
+	idpAudienceKey     = "audience"
+)
+
+// oidcDiscovery holds the subset of the OpenID Provider metadata used by the controller
+type oidcDiscovery struct {
+	Issuer        string `json:"issuer"`
+	JWKSURI       string `json:"jwks_uri"`
+	TokenEndpoint string `json:"token_endpoint"`
+}
+
+// oidcDiscoveryURL returns the URL of issuer's OpenID Provider metadata
+func oidcDiscoveryURL(issuer string) string {
+	return strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+}
+
+// discoverOIDC fetches the OpenID Provider metadata of issuer from baseURL, the URL the provider
+// is reached at. A built-in provider is reached through its service while naming its external
+// issuer: the requests then carry the issuer's host, which Zitadel resolves its instance from, and
+// the endpoints under the issuer are rewritten onto baseURL.
+func discoverOIDC(ctx context.Context, baseURL, issuer string) (*oidcDiscovery, error) {
+	discoveryURL := oidcDiscoveryURL(baseURL)
+	body, err := httpGet(ctx, discoveryURL, issuerHost(baseURL, issuer))
+	if err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+
+	discovery := &oidcDiscovery{}
+	if err := json.Unmarshal(body, discovery); err != nil {
+		return nil, fmt.Errorf("invalid OIDC discovery document: %w", err)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document of %s has no jwks_uri", issuer)
+	}
+	// Tokens are validated against the issuer, a mismatch means the URL doesn't name this provider
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("OIDC discovery document of %s names issuer %q", baseURL, discovery.Issuer)
+	}
+
+	discovery.JWKSURI = rebaseURL(discovery.JWKSURI, baseURL, issuer)
+	discovery.TokenEndpoint = rebaseURL(discovery.TokenEndpoint, baseURL, issuer)
+	return discovery, nil
+}
+
+// issuerHost returns the host requests against baseURL are sent for, or "" if baseURL is the issuer
+func issuerHost(baseURL, issuer string) string {
+	if strings.TrimSuffix(baseURL, "/") == strings.TrimSuffix(issuer, "/") {
+		return ""
+	}
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// rebaseURL moves endpoint from under issuer onto baseURL. Other endpoints are returned unchanged
+func rebaseURL(endpoint, baseURL, issuer string) string {
+	issuer = strings.TrimSuffix(issuer, "/")
+	if rest, ok := strings.CutPrefix(endpoint, issuer); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+		return strings.TrimSuffix(baseURL, "/") + rest
+	}
+	return endpoint
+}
+
+// fetchJWKS discovers the issuer's JWKS URI through baseURL and returns the raw key set
+func fetchJWKS(ctx context.Context, baseURL, issuer string) ([]byte, error) {
+	discovery, err := discoverOIDC(ctx, baseURL, issuer)
+	if err != nil {
+		return nil, err
+	}
+	return fetchKeySet(ctx, discovery.JWKSURI, issuerHost(baseURL, issuer))
+}
+
+// fetchKeySet fetches the JWKS at jwksURI for host and checks that it holds at least one key
+func fetchKeySet(ctx context.Context, jwksURI, host string) ([]byte, error) {
+	jwks, err := httpGet(ctx, jwksURI, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	var keySet struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(jwks, &keySet); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	if len(keySet.Keys) == 0 {
+		return nil, fmt.Errorf("JWKS at %s contains no keys", jwksURI)
+	}
+
+	return jwks, nil
+}
+
+// verifyClientCredentials requests a token from tokenEndpoint with the client credentials grant.
+// It returns an error if the provider rejects the client, and false if the credentials couldn't be
+// confirmed because the client isn't allowed the grant.
+func verifyClientCredentials(ctx context.Context, tokenEndpoint, clientID, clientSecret,
+	audience string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, oidcHTTPTimeout)
+	defer cancel()
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if audience != "" {
+		form.Set("audience", audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("token request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusOK {
+		return true, nil
+	}
+
+	// RFC 6749 section 5.2 error response
+	var tokenErr struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, oidcMaxBodySize))
+	_ = json.Unmarshal(body, &tokenErr)
+
+	switch tokenErr.Error {
+	case "unauthorized_client", "unsupported_grant_type", "invalid_scope", "invalid_target":
+		return false, nil
+	case "":
+		return false, fmt.Errorf("token request returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("token request rejected: %s %s", tokenErr.Error, tokenErr.ErrorDescription)
+	}
+}
+
+// httpGet fetches url. The request is sent for host instead of the URL's host if host is set
+func httpGet(ctx context.Context, url, host string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, oidcHTTPTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if host != "" {
+		req.Host = host
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > oidcMaxBodySize {
+		return nil, fmt.Errorf("GET %s returned more than %d bytes", url, oidcMaxBodySize)
+	}
+	return body, nil
+}
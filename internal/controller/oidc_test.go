@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestIdP serves a discovery document, a JWKS and a token endpoint accepting client "app"
+// with secret "secret". tokenError is returned by the token endpoint for any other client.
+func newTestIdP(t *testing.T, tokenError string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":         srv.URL,
+			"jwks_uri":       srv.URL + "/keys",
+			"token_endpoint": srv.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"keys":[{"kty":"RSA","kid":"1","n":"AQAB","e":"AQAB"}]}`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); ok && id == "app" && secret == "secret" {
+			_, _ = w.Write([]byte(`{"access_token":"token","token_type":"Bearer"}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": tokenError})
+	})
+
+	return srv
+}
+
+func TestDiscoverOIDC(t *testing.T) {
+	srv := newTestIdP(t, "invalid_client")
+
+	discovery, err := discoverOIDC(context.Background(), srv.URL+"/", srv.URL+"/")
+	if err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	if discovery.JWKSURI != srv.URL+"/keys" || discovery.TokenEndpoint != srv.URL+"/token" {
+		t.Errorf("unexpected discovery document %+v", discovery)
+	}
+	if _, err := fetchKeySet(context.Background(), discovery.JWKSURI, ""); err != nil {
+		t.Errorf("JWKS rejected: %v", err)
+	}
+
+	other := newTestIdP(t, "invalid_client")
+	if _, err := discoverOIDC(context.Background(), other.URL+"/realms/other", other.URL+"/realms/other"); err == nil {
+		t.Error("expected an error for a missing discovery document")
+	}
+}
+
+func TestFetchJWKSThroughService(t *testing.T) {
+	const issuer = "https://localhost:8080"
+
+	// Like Zitadel, the provider resolves its instance from the host and names its external issuer
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "localhost:8080" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":         issuer,
+			"jwks_uri":       issuer + "/oauth/v2/keys",
+			"token_endpoint": issuer + "/oauth/v2/token",
+		})
+	})
+	mux.HandleFunc("/oauth/v2/keys", func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "localhost:8080" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"keys":[{"kty":"RSA","kid":"1","n":"AQAB","e":"AQAB"}]}`))
+	})
+
+	discovery, err := discoverOIDC(context.Background(), srv.URL, issuer)
+	if err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	if discovery.JWKSURI != srv.URL+"/oauth/v2/keys" || discovery.TokenEndpoint != srv.URL+"/oauth/v2/token" {
+		t.Errorf("endpoints not rebased onto the service: %+v", discovery)
+	}
+	if _, err := fetchJWKS(context.Background(), srv.URL, issuer); err != nil {
+		t.Errorf("JWKS not fetched through the service: %v", err)
+	}
+
+	// The service must still name the expected issuer
+	if _, err := discoverOIDC(context.Background(), srv.URL, issuer+"/other"); err == nil {
+		t.Error("expected an error for an issuer the provider doesn't name")
+	}
+}
+
+func TestVerifyClientCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	srv := newTestIdP(t, "invalid_client")
+	if confirmed, err := verifyClientCredentials(ctx, srv.URL+"/token", "app", "secret", ""); err != nil || !confirmed {
+		t.Errorf("valid credentials: confirmed %v, err %v", confirmed, err)
+	}
+	if _, err := verifyClientCredentials(ctx, srv.URL+"/token", "app", "wrong", ""); err == nil {
+		t.Error("expected invalid credentials to be rejected")
+	}
+
+	noGrant := newTestIdP(t, "unauthorized_client")
+	if confirmed, err := verifyClientCredentials(ctx, noGrant.URL+"/token", "web", "secret", "api"); err != nil ||
+		confirmed {
+		t.Errorf("client without grant: confirmed %v, err %v", confirmed, err)
+	}
+}
+
+func TestHTTPGetLimit(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/limit", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("a"), oidcMaxBodySize))
+	})
+	mux.HandleFunc("/oversized", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("a"), oidcMaxBodySize+1))
+	})
+
+	if body, err := httpGet(context.Background(), srv.URL+"/limit", ""); err != nil || len(body) != oidcMaxBodySize {
+		t.Errorf("body at the limit not read: %d bytes, %v", len(body), err)
+	}
+	if _, err := httpGet(context.Background(), srv.URL+"/oversized", ""); err == nil {
+		t.Error("expected an error for a body over the limit")
+	}
+}
@@ -0,0 +1,363 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/pgo/pkg/pgx/role"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	pgoRole           = "pgo"
+	pgoConfigKey      = "config.yaml"
+	pgoRESTListenAddr = ":8001"
+	pgoPublication    = "pgo_pub"
+	pgoSlot           = "pgo_slot"
+)
+
+// reconcilePGO prepares the project database for PGO's rest and pipeline commands and writes
+// the config secret mounted by the chart.
+func (r *ProjectReconciler) reconcilePGO(ctx context.Context, project *edgev1alpha1.Project,
+	name string, ref *edgev1alpha1.ComponentRef) error {
+	logger := log.FromContext(ctx)
+	compType := "pubsub"
+
+	pgoValues := make(map[string]any)
+	if err := yaml.Unmarshal([]byte(ref.Release.ValuesContent), &pgoValues); err != nil {
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Values error: %v", err), "")
+		return fmt.Errorf("error parsing PGO values: %w", err)
+	}
+
+	// Step 1: Wait for PostgreSQL to be ready
+	if err := r.waitForPostgreSQLReady(ctx, project, capabilityCreateRole,
+		capabilityLogicalReplication); err != nil {
+		logger.Error(err, "PostgreSQL is not ready")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Database error: %v", err), "")
+		return err
+	}
+
+	// Step 2: Ensure the replication role and its connection secret
+	pgSecretName := fmt.Sprintf("%s-pguser-%s", project.Name, pgoRole)
+	if err := r.ensurePostgresRoleSecret(ctx, project, pgSecretName, pgoReplicationRole(),
+		projectDatabase, false); err != nil {
+		logger.Error(err, "Failed to ensure PGO replication role")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Secret error: %v", err), "")
+		return err
+	}
+
+	// Step 3: Ensure the publication, replication slot and read grants
+	replication := project.Spec.PubSub.Replication
+	if replication != nil {
+		if err := r.ensurePGOReplication(ctx, project, replication); err != nil {
+			logger.Error(err, "Failed to ensure PGO replication")
+			_ = r.updateComponentStatus(ctx, project, compType, name, false,
+				fmt.Sprintf("Replication error: %v", err), "")
+			return err
+		}
+	}
+
+	// Step 4: Resolve the issuer of the project's auth component for pgo rest
+	issuer := ""
+	if project.Spec.Auth != nil {
+		var ready bool
+		if issuer, ready = projectIssuer(project); !ready {
+			err := fmt.Errorf("%w: waiting for auth component", errDependencyNotReady)
+			_ = r.updateComponentStatus(ctx, project, compType, name, false,
+				fmt.Sprintf("Auth error: %v", err), "")
+			return err
+		}
+	}
+
+	// Step 5: Render the PGO config secret
+	configSecretName := fmt.Sprintf("%s-pgo-config", project.Name)
+	if err := r.ensurePGOConfigSecret(ctx, project, configSecretName, pgSecretName, issuer, replication); err != nil {
+		logger.Error(err, "Failed to ensure PGO config secret")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Secret error: %v", err), "")
+		return err
+	}
+
+	// Step 6: Point the chart at the config secret
+	config, ok := pgoValues["config"].(map[string]any)
+	if !ok {
+		config = make(map[string]any)
+		pgoValues["config"] = config
+	}
+	config["existingSecret"] = configSecretName
+
+	updatedValues, err := yaml.Marshal(pgoValues)
+	if err != nil {
+		logger.Error(err, "Failed to marshal updated PGO values")
+		return err
+	}
+
+	ref.Release.ValuesContent = string(updatedValues)
+	return nil
+}
+
+// ensurePGOReplication creates or updates the publication for the declared tables, creates the
+// logical replication slot, and grants the pgo role read access to the published tables.
+func (r *ProjectReconciler) ensurePGOReplication(ctx context.Context, project *edgev1alpha1.Project,
+	replication *edgev1alpha1.Replication) error {
+	logger := log.FromContext(ctx)
+
+	pool, err := r.connectPostgresAsSuperuser(ctx, project, projectDatabase)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer pool.Close()
+
+	publication := pgoPublicationName(replication)
+	slot := pgoSlotName(replication)
+
+	var pubAllTables bool
+	err = pool.QueryRow(ctx, "SELECT puballtables FROM pg_publication WHERE pubname = $1",
+		publication).Scan(&pubAllTables)
+	exists := err == nil
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("failed to check publication %s: %w", publication, err)
+	}
+
+	for _, stmt := range publicationStatements(publication, exists, pubAllTables, replication.Tables) {
+		if _, err := pool.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", stmt, err)
+		}
+	}
+
+	// Let pgo serve REST requests as authn when PostgREST's roles are present
+	if _, err := role.Get(ctx, pool, postgrestAuthnRole); err == nil {
+		stmt := fmt.Sprintf("GRANT %s TO %s", pgx.Identifier{postgrestAuthnRole}.Sanitize(),
+			pgx.Identifier{pgoRole}.Sanitize())
+		if _, err := pool.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", stmt, err)
+		}
+	}
+
+	if err := ensureReplicationSlot(ctx, pool, slot); err != nil {
+		return err
+	}
+
+	logger.Info("Replication ensured", "publication", publication, "slot", slot)
+	return nil
+}
+
+// pgoReplicationRole is the login role pgo streams changes and serves REST requests with
+func pgoReplicationRole() role.Role {
+	return role.Role{
+		Name:        pgoRole,
+		CanLogin:    true,
+		Inherit:     true,
+		Replication: true,
+		ConnLimit:   100,
+	}
+}
+
+// publicationStatements returns the statements creating or updating the publication of the
+// declared tables, given whether it exists and publishes all tables, and the read grants of the
+// pgo role
+func publicationStatements(publication string, exists, pubAllTables bool, tables []string) []string {
+	allTables, target, grants := publicationTargets(tables)
+	pubIdent := pgx.Identifier{publication}.Sanitize()
+
+	// Publications can't be switched to or from FOR ALL TABLES, so they're recreated instead
+	statements := []string{}
+	switch {
+	case exists && pubAllTables != allTables:
+		statements = append(statements, fmt.Sprintf("DROP PUBLICATION %s", pubIdent),
+			fmt.Sprintf("CREATE PUBLICATION %s FOR %s", pubIdent, target))
+	case exists && !allTables:
+		statements = append(statements, fmt.Sprintf("ALTER PUBLICATION %s SET %s", pubIdent, target))
+	case !exists:
+		statements = append(statements, fmt.Sprintf("CREATE PUBLICATION %s FOR %s", pubIdent, target))
+	}
+	return append(statements, grants...)
+}
+
+// ensureReplicationSlot creates the pgoutput logical replication slot if it doesn't exist.
+func ensureReplicationSlot(ctx context.Context, pool *pgxpool.Pool, slot string) error {
+	var exists bool
+	if err := pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)",
+		slot).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check replication slot %s: %w", slot, err)
+	}
+	if exists {
+		return nil
+	}
+
+	if _, err := pool.Exec(ctx, "SELECT pg_create_logical_replication_slot($1, 'pgoutput')", slot); err != nil {
+		return fmt.Errorf("failed to create replication slot %s: %w", slot, err)
+	}
+	return nil
+}
+
+// publicationTargets translates PGO table patterns into the target of a CREATE/ALTER PUBLICATION
+// statement and the grants the pgo role needs to read the published tables.
+func publicationTargets(tables []string) (bool, string, []string) {
+	pgo := pgx.Identifier{pgoRole}.Sanitize()
+
+	for _, t := range tables {
+		if t == "*" || t == "*.*" {
+			return true, "ALL TABLES", []string{fmt.Sprintf("GRANT pg_read_all_data TO %s", pgo)}
+		}
+	}
+
+	targets := []string{}
+	grants := []string{}
+	schemas := map[string]bool{}
+	for _, t := range tables {
+		schema, table, found := strings.Cut(t, ".")
+		if !found {
+			schema, table = "public", t
+		}
+
+		schemaIdent := pgx.Identifier{schema}.Sanitize()
+		if !schemas[schema] {
+			schemas[schema] = true
+			grants = append(grants, fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", schemaIdent, pgo))
+		}
+
+		if table == "*" {
+			targets = append(targets, fmt.Sprintf("TABLES IN SCHEMA %s", schemaIdent))
+			grants = append(grants, fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA %s TO %s", schemaIdent, pgo))
+			continue
+		}
+
+		tableIdent := pgx.Identifier{schema, table}.Sanitize()
+		targets = append(targets, fmt.Sprintf("TABLE %s", tableIdent))
+		grants = append(grants, fmt.Sprintf("GRANT SELECT ON TABLE %s TO %s", tableIdent, pgo))
+	}
+
+	return false, strings.Join(targets, ", "), grants
+}
+
+func pgoPublicationName(replication *edgev1alpha1.Replication) string {
+	if replication != nil && replication.Publication != "" {
+		return replication.Publication
+	}
+	return pgoPublication
+}
+
+func pgoSlotName(replication *edgev1alpha1.Replication) string {
+	if replication != nil && replication.Slot != "" {
+		return replication.Slot
+	}
+	return pgoSlot
+}
+
+// ensurePGOConfigSecret renders the equivalent of internal/stack/pgo/config.template.yaml for the
+// project and creates or updates the secret holding it. The OIDC client of pgo rest is read from
+// the optional <project>-pgo-oidc secret, holding client-id and client-secret.
+func (r *ProjectReconciler) ensurePGOConfigSecret(ctx context.Context, project *edgev1alpha1.Project,
+	secretName, pgSecretName, issuer string, replication *edgev1alpha1.Replication) error {
+	pgSecret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: pgSecretName, Namespace: project.Namespace}, pgSecret); err != nil {
+		return fmt.Errorf("failed to get PostgreSQL secret %s: %w", pgSecretName, err)
+	}
+	connString := string(pgSecret.Data["conn-string"])
+	if sslMode := string(pgSecret.Data["PGSSLMODE"]); sslMode != unverifiedSSLMode(sslMode) {
+		connString = strings.Replace(connString, "sslmode="+sslMode, "sslmode="+unverifiedSSLMode(sslMode), 1)
+	}
+
+	rest := map[string]any{
+		"listenAddr": pgoRESTListenAddr,
+		"pg":         map[string]any{"connString": connString},
+		"basicAuth":  map[string]any{},
+		"anonRole":   postgrestAnonRole,
+		"omitempty":  true,
+	}
+	if issuer != "" {
+		oidcSecret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-pgo-oidc", project.Name),
+			Namespace: project.Namespace}, oidcSecret)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get PGO OIDC secret: %w", err)
+		}
+		rest["oidc"] = map[string]any{
+			"issuer":       issuer,
+			"clientID":     string(oidcSecret.Data["client-id"]),
+			"clientSecret": string(oidcSecret.Data["client-secret"]),
+			"roleClaimKey": postgrestRoleClaimKey,
+		}
+	}
+
+	config := map[string]any{"rest": rest}
+
+	if replication != nil {
+		sourcePeer := fmt.Sprintf("%s-postgres-%s", project.Name, projectDatabase)
+		config["pipeline"] = map[string]any{
+			"peers": []any{
+				map[string]any{
+					"name":      sourcePeer,
+					"connector": "postgres",
+					"config": map[string]any{
+						"connString": connString + " replication=database",
+						"replication": map[string]any{
+							"publication": pgoPublicationName(replication),
+							"slot":        pgoSlotName(replication),
+							"tables":      replication.Tables,
+						},
+					},
+				},
+				map[string]any{
+					"name":      "debug",
+					"connector": "debug",
+				},
+			},
+			"pipelines": []any{
+				map[string]any{
+					"name":    fmt.Sprintf("%s-cdc", project.Name),
+					"sources": []any{map[string]any{"name": sourcePeer}},
+					"sinks":   []any{map[string]any{"name": "debug"}},
+				},
+			},
+		}
+	}
+
+	rendered, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to render PGO config: %w", err)
+	}
+	secretData := map[string][]byte{pgoConfigKey: rendered}
+
+	secret := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, secret)
+	if err == nil {
+		secret.Data = secretData
+		return r.Update(ctx, secret)
+	} else if errors.IsNotFound(err) {
+		newSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: project.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: project.APIVersion,
+						Kind:       project.Kind,
+						Name:       project.Name,
+						UID:        project.UID,
+						Controller: ptr.To(true),
+					},
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: secretData,
+		}
+		return r.createSecret(ctx, project, newSecret)
+	}
+
+	return err
+}
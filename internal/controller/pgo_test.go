@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestPGOReplicationRole(t *testing.T) {
+	role := pgoReplicationRole()
+	if role.Name != pgoRole || !role.CanLogin || !role.Replication || role.Superuser {
+		t.Errorf("unexpected replication role %+v", role)
+	}
+}
+
+func TestPublicationStatements(t *testing.T) {
+	tests := []struct {
+		name         string
+		exists       bool
+		pubAllTables bool
+		tables       []string
+		want         []string
+	}{
+		{
+			name:   "create for tables",
+			tables: []string{"orders", "sales.*"},
+			want: []string{
+				`CREATE PUBLICATION "pgo_pub" FOR TABLE "public"."orders", TABLES IN SCHEMA "sales"`,
+				`GRANT USAGE ON SCHEMA "public" TO "pgo"`,
+				`GRANT SELECT ON TABLE "public"."orders" TO "pgo"`,
+				`GRANT USAGE ON SCHEMA "sales" TO "pgo"`,
+				`GRANT SELECT ON ALL TABLES IN SCHEMA "sales" TO "pgo"`,
+			},
+		},
+		{
+			name:   "update tables",
+			exists: true,
+			tables: []string{"public.orders"},
+			want: []string{
+				`ALTER PUBLICATION "pgo_pub" SET TABLE "public"."orders"`,
+				`GRANT USAGE ON SCHEMA "public" TO "pgo"`,
+				`GRANT SELECT ON TABLE "public"."orders" TO "pgo"`,
+			},
+		},
+		{
+			name:   "switch to all tables",
+			exists: true,
+			tables: []string{"*"},
+			want: []string{
+				`DROP PUBLICATION "pgo_pub"`,
+				`CREATE PUBLICATION "pgo_pub" FOR ALL TABLES`,
+				`GRANT pg_read_all_data TO "pgo"`,
+			},
+		},
+		{
+			name:         "all tables unchanged",
+			exists:       true,
+			pubAllTables: true,
+			tables:       []string{"*.*"},
+			want:         []string{`GRANT pg_read_all_data TO "pgo"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := publicationStatements(pgoPublication, tt.exists, tt.pubAllTables, tt.tables)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got statements %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsurePGOConfigSecret(t *testing.T) {
+	ctx := context.Background()
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+	pgSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-pguser-pgo", Namespace: "apps"},
+		Data:       map[string][]byte{"conn-string": []byte("host=db user=pgo dbname=main")},
+	}
+	r := &ProjectReconciler{Client: fake.NewClientBuilder().WithObjects(pgSecret).Build()}
+
+	replication := &edgev1alpha1.Replication{Tables: []string{"orders"}, Slot: "demo_slot"}
+	if err := r.ensurePGOConfigSecret(ctx, project, "demo-pgo-config", pgSecret.Name,
+		"https://iam.example.com", replication); err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: "demo-pgo-config", Namespace: "apps"}, secret); err != nil {
+		t.Fatal(err)
+	}
+
+	config := struct {
+		REST struct {
+			PG   struct{ ConnString string } `json:"pg"`
+			OIDC struct{ Issuer string }     `json:"oidc"`
+		} `json:"rest"`
+		Pipeline struct {
+			Peers []struct {
+				Name   string `json:"name"`
+				Config struct {
+					ConnString  string `json:"connString"`
+					Replication struct {
+						Publication string   `json:"publication"`
+						Slot        string   `json:"slot"`
+						Tables      []string `json:"tables"`
+					} `json:"replication"`
+				} `json:"config"`
+			} `json:"peers"`
+		} `json:"pipeline"`
+	}{}
+	if err := yaml.Unmarshal(secret.Data[pgoConfigKey], &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.REST.PG.ConnString != "host=db user=pgo dbname=main" || config.REST.OIDC.Issuer != "https://iam.example.com" {
+		t.Errorf("unexpected rest config %+v", config.REST)
+	}
+	if len(config.Pipeline.Peers) != 2 {
+		t.Fatalf("got %d peers, want the source and the debug sink", len(config.Pipeline.Peers))
+	}
+	source := config.Pipeline.Peers[0]
+	if source.Name != "demo-postgres-main" || source.Config.ConnString != "host=db user=pgo dbname=main replication=database" ||
+		source.Config.Replication.Publication != pgoPublication || source.Config.Replication.Slot != "demo_slot" ||
+		!slices.Equal(source.Config.Replication.Tables, []string{"orders"}) {
+		t.Errorf("unexpected source peer %+v", source)
+	}
+}
@@ -1,10 +1,13 @@
 package controller
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/x509"
 	"fmt"
-	"os"
+	"maps"
+	"net"
+	"net/url"
 	"strings"
 	"time"
 
@@ -12,9 +15,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+	"github.com/edgeflare/edge/internal/metrics"
+	"github.com/edgeflare/pgo/pkg/pgx/role"
 	"github.com/edgeflare/pgo/pkg/util/rand"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/xeipuuv/gojsonschema"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/ptr"
@@ -22,126 +29,89 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
-func (r *ProjectReconciler) reconcileDatabase(ctx context.Context, project *edgev1alpha1.Project,
+// projectDatabase is the application database created in the project's PostgreSQL
+const projectDatabase = "main"
+
+// pgCAKey is the optional key of an external database secret holding the server's CA certificates
+const pgCAKey = "ca.crt"
+
+// preparePostgres ensures the superuser secret and connection secret exist before the PostgreSQL
+// release, generating the superuser passwords unless an existingSecret is supplied in the values.
+func (r *ProjectReconciler) preparePostgres(ctx context.Context, project *edgev1alpha1.Project,
 	name string, ref *edgev1alpha1.ComponentRef) error {
 	logger := log.FromContext(ctx)
 	compType := "database"
-	logger.Info("Reconciling database", "component", name)
 
-	// For external database components, verify the secret exists and update component status
-	if ref.IsExternal() {
-		if err := r.verifyExternalDatabaseSecret(ctx, project, name, ref); err != nil {
-			logger.Error(err, "External database secret verification failed")
-			_ = r.updateComponentStatus(ctx, project, compType, name, false,
-				fmt.Sprintf("Secret error: %v", err), "")
-			return err
-		}
-		return r.handleExternalComponent(ctx, project, compType, name, ref)
+	// Parse the supplied values
+	pgValues := make(map[string]any)
+	if err := yaml.Unmarshal([]byte(ref.Release.ValuesContent), &pgValues); err != nil {
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Values error: %v", err), "")
+		return fmt.Errorf("error parsing PostgreSQL values: %w", err)
+	}
+	if pgValues == nil {
+		pgValues = make(map[string]any)
 	}
 
-	if name == "postgres" {
-		pgSchema, err := os.ReadFile("hack/postgresql.values.schema.json")
-		if err != nil {
-			logger.Error(err, "Failed to load PostgreSQL schema")
-			return err
-		}
-
-		// Parse the supplied values
-		var pgValues map[string]any
-		if ref.Release.ValuesContent != "" {
-			pgValues, err = r.parseValues(ref.Release.ValuesContent, pgSchema)
-			if err != nil {
-				logger.Error(err, "Failed to parse PostgreSQL values")
-				_ = r.updateComponentStatus(ctx, project, compType, name, false,
-					fmt.Sprintf("Values error: %v", err), "")
-				return err
-			}
-		} else {
-			pgValues = make(map[string]any)
-		}
-
-		// Check if existingSecret is set
-		existingSecret := ""
-		if global, ok := pgValues["global"].(map[string]any); ok {
-			if postgresql, ok := global["postgresql"].(map[string]any); ok {
-				if auth, ok := postgresql["auth"].(map[string]any); ok {
-					if es, ok := auth["existingSecret"].(string); ok && es != "" {
-						existingSecret = es
-					}
+	// Check if existingSecret is set
+	existingSecret := ""
+	if global, ok := pgValues["global"].(map[string]any); ok {
+		if postgresql, ok := global["postgresql"].(map[string]any); ok {
+			if auth, ok := postgresql["auth"].(map[string]any); ok {
+				if es, ok := auth["existingSecret"].(string); ok && es != "" {
+					existingSecret = es
 				}
 			}
 		}
+	}
 
-		// Generate or verify secret
-		secretName := fmt.Sprintf("%s-postgresql", project.Name)
-		if existingSecret != "" {
-			secretName = existingSecret
-			// Verify the existing secret
-			if err := r.verifyPostgresSecret(ctx, project.Namespace, secretName); err != nil {
-				logger.Error(err, "Existing PostgreSQL secret verification failed")
-				_ = r.updateComponentStatus(ctx, project, compType, name, false,
-					fmt.Sprintf("Secret error: %v", err), "")
-				return err
-			}
-		} else {
-			// Create or update the secret with generated passwords
-			if err := r.ensurePostgresSecret(ctx, project, secretName); err != nil {
-				logger.Error(err, "Failed to ensure PostgreSQL secret")
-				return err
-			}
-
-			// Update the values to use created secret
-			r.updateValuesWithSecret(pgValues, secretName)
-		}
-
-		// Create user connection secret
-		if err := r.ensurePostgresUserSecret(ctx, project, secretName); err != nil {
-			logger.Error(err, "Failed to ensure PostgreSQL user secret")
+	// Generate or verify secret
+	secretName := fmt.Sprintf("%s-postgresql", project.Name)
+	if existingSecret != "" {
+		secretName = existingSecret
+		// Verify the existing secret
+		if err := r.verifyPostgresSecret(ctx, project.Namespace, secretName); err != nil {
+			logger.Error(err, "Existing PostgreSQL secret verification failed")
+			_ = r.updateComponentStatus(ctx, project, compType, name, false,
+				fmt.Sprintf("Secret error: %v", err), "")
 			return err
 		}
-
-		// Update the values content in the component ref
-		updatedValues, err := yaml.Marshal(pgValues)
-		if err != nil {
-			logger.Error(err, "Failed to marshal updated PostgreSQL values")
+	} else {
+		// Create or update the secret with generated passwords
+		if err := r.ensurePostgresSecret(ctx, project, secretName); err != nil {
+			logger.Error(err, "Failed to ensure PostgreSQL secret")
 			return err
 		}
 
-		ref.Release.ValuesContent = string(updatedValues)
+		// Update the values to use created secret
+		r.updateValuesWithSecret(pgValues, secretName)
 	}
 
-	return r.handleComponentRelease(ctx, project, compType, name, ref)
-}
-
-// Parse and validate YAML values against the schema
-func (r *ProjectReconciler) parseValues(yamlString string, schemaBytes []byte) (map[string]any, error) {
-	var yamlData map[string]any
-	if err := yaml.Unmarshal([]byte(yamlString), &yamlData); err != nil {
-		return nil, fmt.Errorf("error parsing YAML: %v", err)
+	// The controller's pg_dump Jobs replace the chart's backup CronJob
+	if project.Spec.Database.Backup != nil {
+		backup, _ := pgValues["backup"].(map[string]any)
+		if backup == nil {
+			backup = make(map[string]any)
+		}
+		backup["enabled"] = false
+		pgValues["backup"] = backup
 	}
 
-	jsonData, err := json.Marshal(yamlData)
-	if err != nil {
-		return nil, fmt.Errorf("error converting to JSON: %v", err)
+	// Create user connection secret
+	if err := r.ensurePostgresUserSecret(ctx, project, secretName); err != nil {
+		logger.Error(err, "Failed to ensure PostgreSQL user secret")
+		return err
 	}
 
-	schemaLoader := gojsonschema.NewBytesLoader(schemaBytes)
-	documentLoader := gojsonschema.NewBytesLoader(jsonData)
-
-	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
+	// Update the values content in the component ref
+	updatedValues, err := yaml.Marshal(pgValues)
 	if err != nil {
-		return nil, fmt.Errorf("error during validation: %v", err)
-	}
-
-	if !result.Valid() {
-		var errMsgs []string
-		for _, desc := range result.Errors() {
-			errMsgs = append(errMsgs, desc.String())
-		}
-		return nil, fmt.Errorf("invalid PostgreSQL values: %s", strings.Join(errMsgs, "; "))
+		logger.Error(err, "Failed to marshal updated PostgreSQL values")
+		return err
 	}
 
-	return yamlData, nil
+	ref.Release.ValuesContent = string(updatedValues)
+	return nil
 }
 
 // Verify an existing PostgreSQL secret
@@ -209,7 +179,7 @@ func (r *ProjectReconciler) ensurePostgresSecret(ctx context.Context, project *e
 			},
 		}
 
-		return r.Create(ctx, secret)
+		return r.createSecret(ctx, project, secret)
 	}
 
 	return err
@@ -228,25 +198,31 @@ func (r *ProjectReconciler) ensurePostgresUserSecret(ctx context.Context, projec
 		return fmt.Errorf("postgres-password not found in secret %s", authSecretName)
 	}
 
-	// TODO: improve instead of hack
-	serviceName := fmt.Sprintf("%s-postgres-postgresql-primary.%s.svc.cluster.local", project.Name, project.Namespace)
-	connString := fmt.Sprintf("host=%s port=5432 user=postgres password=%s dbname=postgres sslmode=prefer",
-		serviceName, pgPassword)
+	sslMode, ca, err := r.postgresSSLMode(ctx, project)
+	if err != nil {
+		return err
+	}
+
+	connString := fmt.Sprintf("host=%s port=5432 user=postgres password=%s dbname=postgres sslmode=%s",
+		postgresPrimaryHost(project), pgPassword, sslMode)
 
 	// Create or update the user secret
 	userSecretName := fmt.Sprintf("%s-pguser-postgres", project.Name)
 	userSecret := &corev1.Secret{}
-	err := r.Get(ctx, types.NamespacedName{Name: userSecretName, Namespace: project.Namespace}, userSecret)
+	err = r.Get(ctx, types.NamespacedName{Name: userSecretName, Namespace: project.Namespace}, userSecret)
 
 	secretData := map[string][]byte{
 		"PGDATABASE":  []byte("postgres"),
-		"PGHOST":      []byte(serviceName),
+		"PGHOST":      []byte(postgresPrimaryHost(project)),
 		"PGPASSWORD":  []byte(pgPassword),
 		"PGPORT":      []byte("5432"),
-		"PGSSLMODE":   []byte("prefer"),
+		"PGSSLMODE":   []byte(sslMode),
 		"PGUSER":      []byte("postgres"),
 		"conn-string": []byte(connString),
 	}
+	if len(ca) > 0 {
+		secretData[pgCAKey] = ca
+	}
 
 	if err == nil {
 		// Update existing secret
@@ -272,7 +248,7 @@ func (r *ProjectReconciler) ensurePostgresUserSecret(ctx context.Context, projec
 			Data: secretData,
 		}
 
-		return r.Create(ctx, newSecret)
+		return r.createSecret(ctx, project, newSecret)
 	}
 
 	return err
@@ -303,14 +279,10 @@ func (r *ProjectReconciler) updateValuesWithSecret(values map[string]any, secret
 }
 
 func (r *ProjectReconciler) verifyExternalDatabaseSecret(ctx context.Context, project *edgev1alpha1.Project,
-	name string, ref *edgev1alpha1.ComponentRef) error {
+	name string, ref *edgev1alpha1.ComponentRef) (*corev1.Secret, error) {
 	_ = name
 
-	// Skip if secret name is not provided
 	secretName := ref.GetSecretName()
-	if secretName == "" {
-		return nil
-	}
 
 	// Check if secret exists
 	secret := &corev1.Secret{}
@@ -320,11 +292,11 @@ func (r *ProjectReconciler) verifyExternalDatabaseSecret(ctx context.Context, pr
 	}, secret)
 
 	if errors.IsNotFound(err) {
-		return fmt.Errorf("required external database secret %s not found", secretName)
+		return nil, fmt.Errorf("required external database secret %s not found", secretName)
 	}
 
 	if err != nil {
-		return fmt.Errorf("failed to get external database secret: %w", err)
+		return nil, fmt.Errorf("failed to get external database secret: %w", err)
 	}
 
 	// Verify secret contains required libpq environment variables
@@ -338,19 +310,165 @@ func (r *ProjectReconciler) verifyExternalDatabaseSecret(ctx context.Context, pr
 	}
 
 	if len(missingFields) > 0 {
-		return fmt.Errorf("external database secret %s is missing required fields: %v",
+		return nil, fmt.Errorf("external database secret %s is missing required fields: %v",
 			secretName, strings.Join(missingFields, ", "))
 	}
 
+	return secret, nil
+}
+
+// verifyExternalPostgres connects to an external PostgreSQL with the credentials of its secret and
+// probes the server's version and the capabilities the project's components depend on.
+func (r *ProjectReconciler) verifyExternalPostgres(ctx context.Context, project *edgev1alpha1.Project,
+	name string, ref *edgev1alpha1.ComponentRef) (edgev1alpha1.ComponentStatus, error) {
+	status := edgev1alpha1.ComponentStatus{}
+
+	secret, err := r.verifyExternalDatabaseSecret(ctx, project, name, ref)
+	if err != nil {
+		return status, err
+	}
+
+	info, err := probePostgres(ctx, secret)
+	if err != nil {
+		return status, err
+	}
+
+	status.Endpoint = string(secret.Data["PGHOST"])
+	status.Database = info
+	status.Message = fmt.Sprintf("Connected to PostgreSQL %s", info.Version)
+	return status, nil
+}
+
+// postgresConnConfig builds the connection config of a libpq environment secret. PGSSLMODE defaults
+// to prefer as with libpq, and the PEM bundle under pgCAKey, if any, replaces the system roots.
+func postgresConnConfig(secret *corev1.Secret) (*pgx.ConnConfig, error) {
+	sslMode := string(secret.Data["PGSSLMODE"])
+	if sslMode == "" {
+		sslMode = "prefer"
+	}
+
+	dbURI := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(string(secret.Data["PGUSER"]), string(secret.Data["PGPASSWORD"])),
+		Host:     net.JoinHostPort(string(secret.Data["PGHOST"]), string(secret.Data["PGPORT"])),
+		Path:     string(secret.Data["PGDATABASE"]),
+		RawQuery: url.Values{"sslmode": {sslMode}, "connect_timeout": {"10"}}.Encode(),
+	}
+	config, err := pgx.ParseConfig(dbURI.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection settings: %w", err)
+	}
+
+	if ca := secret.Data[pgCAKey]; len(ca) > 0 {
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("%s holds no PEM certificates", pgCAKey)
+		}
+		// verify-ca and verify-full check the server certificate against RootCAs
+		if config.TLSConfig != nil {
+			config.TLSConfig.RootCAs = roots
+		}
+		for _, fallback := range config.Fallbacks {
+			if fallback.TLSConfig != nil {
+				fallback.TLSConfig.RootCAs = roots
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// probePostgres connects with the credentials of a libpq environment secret and reports the server
+// version, wal_level, available extensions and the privileges of the user
+func probePostgres(ctx context.Context, secret *corev1.Secret) (*edgev1alpha1.DatabaseInfo, error) {
+	config, err := postgresConnConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL at %s: %w", config.Host, err)
+	}
+	defer func() {
+		_ = conn.Close(ctx)
+	}()
+
+	info := &edgev1alpha1.DatabaseInfo{}
+	if err := conn.QueryRow(ctx, "SHOW server_version").Scan(&info.Version); err != nil {
+		return nil, fmt.Errorf("failed to query server version: %w", err)
+	}
+	if err := conn.QueryRow(ctx, "SHOW wal_level").Scan(&info.WALLevel); err != nil {
+		return nil, fmt.Errorf("failed to query wal_level: %w", err)
+	}
+	if err := conn.QueryRow(ctx, `SELECT rolsuper OR rolcreaterole, rolsuper OR rolreplication
+		FROM pg_roles WHERE rolname = current_user`).Scan(&info.CanCreateRole, &info.CanReplicate); err != nil {
+		return nil, fmt.Errorf("failed to query role privileges: %w", err)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT name FROM pg_available_extensions ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query available extensions: %w", err)
+	}
+	info.Extensions, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to query available extensions: %w", err)
+	}
+
+	return info, nil
+}
+
+// databaseCapability is a capability a component requires of the project's PostgreSQL
+type databaseCapability int
+
+const (
+	// capabilityCreateRole lets the controller create the component's login role
+	capabilityCreateRole databaseCapability = iota
+	// capabilityLogicalReplication lets the component stream changes through a replication slot
+	capabilityLogicalReplication
+)
+
+// requireDatabaseCapabilities checks the capabilities probed on an external PostgreSQL. The
+// built-in PostgreSQL is managed as superuser with logical wal_level and has every capability.
+func requireDatabaseCapabilities(project *edgev1alpha1.Project, capabilities ...databaseCapability) error {
+	info := project.Status.ComponentStatuses["database-postgres"].Database
+	if info == nil {
+		return nil
+	}
+
+	missing := []string{}
+	for _, capability := range capabilities {
+		switch capability {
+		case capabilityCreateRole:
+			if !info.CanCreateRole {
+				missing = append(missing, "the CREATEROLE privilege")
+			}
+		case capabilityLogicalReplication:
+			if info.WALLevel != "logical" {
+				missing = append(missing, fmt.Sprintf("wal_level logical (is %s)", info.WALLevel))
+			}
+			if !info.CanReplicate {
+				missing = append(missing, "the REPLICATION privilege")
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: external PostgreSQL lacks %s", errMissingCapability, strings.Join(missing, ", "))
+	}
+
 	return nil
 }
 
-func pgConnectWithRetry(ctx context.Context, connString string, maxRetries int, retryInterval time.Duration) (*pgxpool.Pool, error) {
+func pgConnectWithRetry(ctx context.Context, config *pgx.ConnConfig, maxRetries int, retryInterval time.Duration) (*pgxpool.Pool, error) {
 	var pool *pgxpool.Pool
-	var err error
+	poolConfig, err := pgxpool.ParseConfig("")
+	if err != nil {
+		return nil, err
+	}
+	poolConfig.ConnConfig = config
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		pool, err = pgxpool.New(ctx, connString)
+		pool, err = pgxpool.NewWithConfig(ctx, poolConfig)
 		if err == nil {
 			// Test the connection with a simple query
 			if err = pool.Ping(ctx); err == nil {
@@ -365,6 +483,7 @@ func pgConnectWithRetry(ctx context.Context, connString string, maxRetries int,
 			case <-ctx.Done():
 				return nil, fmt.Errorf("context canceled while connecting to PostgreSQL: %w", ctx.Err())
 			case <-time.After(retryInterval):
+				metrics.PostgresConnectionRetries.Inc()
 				// Log retry attempt
 				fmt.Println("Retrying PostgreSQL connection",
 					"attempt", attempt,
@@ -376,3 +495,275 @@ func pgConnectWithRetry(ctx context.Context, connString string, maxRetries int,
 
 	return nil, fmt.Errorf("failed to connect to PostgreSQL after %d attempts: %w", maxRetries, err)
 }
+
+// ensurePostgresRoleSecret ensures a login role for a project component exists in the project's
+// PostgreSQL and creates/updates the corresponding connection secret. The database is created if
+// missing, owned by the role when ownDatabase is set.
+func (r *ProjectReconciler) ensurePostgresRoleSecret(ctx context.Context, project *edgev1alpha1.Project,
+	secretName string, pgRole role.Role, database string, ownDatabase bool) error {
+	return r.ensurePostgresRoleSecretAt(ctx, project, secretName, "", pgRole, database, ownDatabase)
+}
+
+// ensurePostgresRoleSecretAt is ensurePostgresRoleSecret with the connection secret pointing at
+// host, e.g. the read replicas' service, instead of the primary
+func (r *ProjectReconciler) ensurePostgresRoleSecretAt(ctx context.Context, project *edgev1alpha1.Project,
+	secretName, host string, pgRole role.Role, database string, ownDatabase bool) error {
+	logger := log.FromContext(ctx)
+	// Create a new context with timeout for database operations
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// 1. Get the PostgreSQL superuser secret, or the external database's
+	ref := postgresRef(project)
+	if ref == nil {
+		return fmt.Errorf("project %s declares no PostgreSQL", project.Name)
+	}
+	pgSuperuserSecret, err := r.postgresAdminSecret(ctx, project, ref)
+	if err != nil {
+		return err
+	}
+
+	// Validate required secret data exists
+	requiredFields := []string{"PGHOST", "PGPORT", "PGPASSWORD"}
+	for _, field := range requiredFields {
+		if _, exists := pgSuperuserSecret.Data[field]; !exists {
+			return fmt.Errorf("PostgreSQL superuser secret missing required field: %s", field)
+		}
+	}
+
+	// 2. Extract connection info
+	pgHost := string(pgSuperuserSecret.Data["PGHOST"])
+	pgPort := string(pgSuperuserSecret.Data["PGPORT"])
+	if host != "" {
+		pgHost = host
+	}
+	// The project's database on an external PostgreSQL is the one its secret names
+	if ref.IsExternal() && database == projectDatabase {
+		database = string(pgSuperuserSecret.Data["PGDATABASE"])
+	}
+
+	// Keep the password of an existing connection secret, it only changes on rotation
+	roleSecret := &corev1.Secret{}
+	secretErr := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, roleSecret)
+	if secretErr != nil && !errors.IsNotFound(secretErr) {
+		return fmt.Errorf("error getting PostgreSQL secret %s: %w", secretName, secretErr)
+	}
+	if pgRole.Password == "" && secretErr == nil {
+		pgRole.Password = string(roleSecret.Data["PGPASSWORD"])
+	}
+	if pgRole.Password == "" {
+		pgRole.Password = newAlphaNumericPassword(16)
+	}
+
+	// 3. Build connection configs, verifying the server like the superuser secret does
+	superUserConfig, err := r.postgresDatabaseConnConfig(ctx, project, ref, "postgres")
+	if err != nil {
+		return err
+	}
+	sslMode := string(pgSuperuserSecret.Data["PGSSLMODE"])
+	if sslMode == "" {
+		sslMode = "require"
+	}
+
+	// 4. Prepare secret data
+	secretData := map[string][]byte{
+		"PGDATABASE": []byte(database),
+		"PGHOST":     []byte(pgHost),
+		"PGPASSWORD": []byte(pgRole.Password),
+		"PGPORT":     []byte(pgPort),
+		"PGSSLMODE":  []byte(sslMode),
+		"PGUSER":     []byte(pgRole.Name),
+	}
+	secretData["conn-string"] = []byte(roleConnString(secretData))
+	if ca := pgSuperuserSecret.Data[pgCAKey]; len(ca) > 0 {
+		secretData[pgCAKey] = ca
+	}
+
+	// 5. Create the connection secret or update it if it differs
+	if errors.IsNotFound(secretErr) {
+		newSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: project.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: project.APIVersion,
+						Kind:       project.Kind,
+						Name:       project.Name,
+						UID:        project.UID,
+						Controller: ptr.To(true),
+					},
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: secretData,
+		}
+		if err := r.createSecret(ctx, project, newSecret); err != nil {
+			return fmt.Errorf("failed to create PostgreSQL secret %s: %w", secretName, err)
+		}
+		logger.Info("Created PostgreSQL secret", "name", secretName, "namespace", project.Namespace)
+	} else if !maps.EqualFunc(roleSecret.Data, secretData, bytes.Equal) {
+		roleSecret.Data = secretData
+		if err := r.Update(ctx, roleSecret); err != nil {
+			return fmt.Errorf("failed to update PostgreSQL secret %s: %w", secretName, err)
+		}
+		logger.Info("Updated PostgreSQL secret", "name", secretName, "namespace", project.Namespace)
+	}
+
+	// 6. Connect to PostgreSQL with retry
+	pool, err := pgConnectWithRetry(dbCtx, superUserConfig, 5, 2*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer pool.Close()
+
+	// 7. Create or update the PostgreSQL role
+	existingRole, err := role.Get(dbCtx, pool, pgRole.Name)
+	if err != nil {
+		if err == role.ErrRoleNotFound {
+			// Role doesn't exist, create it
+			if err := role.Create(dbCtx, pool, pgRole); err != nil {
+				return fmt.Errorf("failed to create PostgreSQL role %s: %w", pgRole.Name, err)
+			}
+			logger.Info("Created PostgreSQL role", "name", pgRole.Name)
+			r.event(project, corev1.EventTypeNormal, common.ReasonRoleCreated, "Created PostgreSQL role %s", pgRole.Name)
+		} else {
+			return fmt.Errorf("error checking for existing PostgreSQL role: %w", err)
+		}
+	} else {
+		// Role exists, update it
+		logger.Info("Existing role found, updating", "name", existingRole.Name, "oid", existingRole.OID)
+		if err := role.Update(dbCtx, pool, pgRole); err != nil {
+			return fmt.Errorf("failed to update PostgreSQL role %s: %w", pgRole.Name, err)
+		}
+		logger.Info("Updated PostgreSQL role", "name", pgRole.Name)
+	}
+
+	// 8. Ensure database exists
+	return ensurePostgresDatabase(dbCtx, pool, database, pgRole.Name, ownDatabase)
+}
+
+// roleConnString renders the libpq connection string of a role's connection secret data
+func roleConnString(data map[string][]byte) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		data["PGHOST"], data["PGPORT"], data["PGUSER"], data["PGPASSWORD"], data["PGDATABASE"], data["PGSSLMODE"])
+}
+
+// ensurePostgresDatabase creates the database if it doesn't exist yet, optionally owned by owner.
+func ensurePostgresDatabase(ctx context.Context, pool *pgxpool.Pool, database, owner string, setOwner bool) error {
+	logger := log.FromContext(ctx)
+
+	// An external PostgreSQL's user may lack CREATEDB, which CREATE DATABASE checks first
+	var exists bool
+	if err := pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)",
+		database).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check for database %s: %w", database, err)
+	}
+	if exists {
+		logger.Info("Database already exists", "database", database)
+		return nil
+	}
+
+	stmt := fmt.Sprintf("CREATE DATABASE %s", pgx.Identifier{database}.Sanitize())
+	if setOwner {
+		stmt += fmt.Sprintf(" OWNER %s", pgx.Identifier{owner}.Sanitize())
+	}
+
+	if _, err := pool.Exec(ctx, stmt); err != nil {
+		// Check for specific PostgreSQL error code for "database already exists" (42P04)
+		pgErr, isPgError := err.(*pgconn.PgError)
+		if isPgError && pgErr.Code == "42P04" {
+			logger.Info("Database already exists", "database", database)
+			return nil
+		}
+		return fmt.Errorf("failed to create database %s: %w", database, err)
+	}
+
+	logger.Info("Created database", "database", database)
+	return nil
+}
+
+// postgresRef returns the project's PostgreSQL component, nil if the project declares no database
+func postgresRef(project *edgev1alpha1.Project) *edgev1alpha1.ComponentRef {
+	if project.Spec.Database == nil {
+		return nil
+	}
+	return project.Spec.Database.GetComponentRef("postgres")
+}
+
+// connectPostgresAsSuperuser connects to database on the project's PostgreSQL as the superuser of
+// the built-in one's primary, or with the credentials of an external one.
+func (r *ProjectReconciler) connectPostgresAsSuperuser(ctx context.Context, project *edgev1alpha1.Project,
+	database string) (*pgxpool.Pool, error) {
+	ref := postgresRef(project)
+	if ref == nil {
+		return nil, fmt.Errorf("project %s declares no PostgreSQL", project.Name)
+	}
+	config, err := r.postgresDatabaseConnConfig(ctx, project, ref, database)
+	if err != nil {
+		return nil, err
+	}
+	return pgConnectWithRetry(ctx, config, 5, 2*time.Second)
+}
+
+// postgresPrimaryService returns the service of the built-in PostgreSQL's primary
+func postgresPrimaryService(project *edgev1alpha1.Project) string {
+	return project.Name + "-postgres-postgresql-primary"
+}
+
+// postgresPrimaryHost returns the in-cluster host name of the built-in PostgreSQL's primary
+func postgresPrimaryHost(project *edgev1alpha1.Project) string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", postgresPrimaryService(project), project.Namespace)
+}
+
+// superuserConnConfig builds the config of the controller's connections to database on the
+// project's PostgreSQL primary from the <project>-pguser-postgres secret. The server is verified
+// against the secret's CA with verify-full if the project's certificate was issued.
+func superuserConnConfig(project *edgev1alpha1.Project, secret *corev1.Secret,
+	database string) (*pgx.ConnConfig, error) {
+	secret = secret.DeepCopy()
+	secret.Data["PGHOST"] = []byte(postgresPrimaryHost(project))
+	secret.Data["PGUSER"] = []byte("postgres")
+	secret.Data["PGDATABASE"] = []byte(database)
+	if len(secret.Data["PGPORT"]) == 0 {
+		secret.Data["PGPORT"] = []byte("5432")
+	}
+	if len(secret.Data["PGSSLMODE"]) == 0 {
+		secret.Data["PGSSLMODE"] = []byte("require")
+	}
+	return postgresConnConfig(secret)
+}
+
+// postgresAdminSecret returns the libpq environment secret the controller manages the project's
+// PostgreSQL with, the superuser's of the built-in one or the external one's
+func (r *ProjectReconciler) postgresAdminSecret(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) (*corev1.Secret, error) {
+	name := project.Name + "-pguser-postgres"
+	if ref.IsExternal() {
+		name = ref.GetSecretName()
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: project.Namespace}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get PostgreSQL secret %s: %w", name, err)
+	}
+	return secret, nil
+}
+
+// postgresDatabaseConnConfig builds the config of the controller's connections to database of the
+// project's PostgreSQL, as the superuser of the built-in one or with the credentials of an external
+// one. The external secret's database stands in for the project's and the maintenance database.
+func (r *ProjectReconciler) postgresDatabaseConnConfig(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef, database string) (*pgx.ConnConfig, error) {
+	secret, err := r.postgresAdminSecret(ctx, project, ref)
+	if err != nil {
+		return nil, err
+	}
+	if !ref.IsExternal() {
+		return superuserConnConfig(project, secret, database)
+	}
+	secret = secret.DeepCopy()
+	if database != projectDatabase && database != "postgres" {
+		secret.Data["PGDATABASE"] = []byte(database)
+	}
+	return postgresConnConfig(secret)
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestPostgresConnConfig(t *testing.T) {
+	secret := &corev1.Secret{Data: map[string][]byte{
+		"PGHOST":     []byte("db.example.com"),
+		"PGPORT":     []byte("5432"),
+		"PGUSER":     []byte("app"),
+		"PGPASSWORD": []byte("p@ss word"),
+		"PGDATABASE": []byte("main"),
+		"PGSSLMODE":  []byte("verify-full"),
+	}}
+
+	config, err := postgresConnConfig(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Host != "db.example.com" || config.Port != 5432 || config.User != "app" ||
+		config.Password != "p@ss word" || config.Database != "main" {
+		t.Errorf("unexpected connection config %+v", config.Config)
+	}
+	if config.TLSConfig == nil || config.TLSConfig.ServerName != "db.example.com" {
+		t.Error("verify-full should verify the server name")
+	}
+
+	secret.Data[pgCAKey] = []byte("not a certificate")
+	if _, err := postgresConnConfig(secret); err == nil {
+		t.Error("expected an error for an invalid CA bundle")
+	}
+}
+
+func TestRequireDatabaseCapabilities(t *testing.T) {
+	project := &edgev1alpha1.Project{}
+	if err := requireDatabaseCapabilities(project, capabilityCreateRole, capabilityLogicalReplication); err != nil {
+		t.Errorf("built-in database rejected: %v", err)
+	}
+
+	project.Status.ComponentStatuses = map[string]edgev1alpha1.ComponentStatus{
+		"database-postgres": {Ready: true, Database: &edgev1alpha1.DatabaseInfo{
+			Version: "16.4", WALLevel: "replica", CanCreateRole: true,
+		}},
+	}
+	if err := requireDatabaseCapabilities(project, capabilityCreateRole); err != nil {
+		t.Errorf("CREATEROLE rejected: %v", err)
+	}
+
+	err := requireDatabaseCapabilities(project, capabilityCreateRole, capabilityLogicalReplication)
+	if !errors.Is(err, errMissingCapability) {
+		t.Fatalf("expected errMissingCapability, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "wal_level logical (is replica)") ||
+		!strings.Contains(err.Error(), "REPLICATION") {
+		t.Errorf("unexpected message %q", err)
+	}
+}
+
+func TestExternalPostgresDependent(t *testing.T) {
+	// Stands in for the external server, recording the controller dialing it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+	dialed := make(chan struct{}, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			select {
+			case dialed <- struct{}{}:
+			default:
+			}
+			_ = conn.Close()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	external := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "acme-db", Namespace: "default"},
+		Data: map[string][]byte{
+			"PGHOST": []byte(host), "PGPORT": []byte(port), "PGUSER": []byte("admin"),
+			"PGPASSWORD": []byte("secret"), "PGDATABASE": []byte("appdb"), "PGSSLMODE": []byte("disable"),
+		},
+	}
+	project := &edgev1alpha1.Project{
+		ObjectMeta: metav1.ObjectMeta{Name: "acme", Namespace: "default"},
+		Spec: edgev1alpha1.ProjectSpec{Database: &edgev1alpha1.Database{
+			Postgres: &edgev1alpha1.ComponentRef{External: &edgev1alpha1.ExternalRef{SecretName: "acme-db"}},
+		}},
+		Status: edgev1alpha1.ProjectStatus{ComponentStatuses: map[string]edgev1alpha1.ComponentStatus{
+			"database-postgres": {Ready: true, Database: &edgev1alpha1.DatabaseInfo{CanCreateRole: true}},
+		}},
+	}
+	// No <project>-pguser-postgres secret or in-cluster primary exists for an external database
+	r := &ProjectReconciler{Client: fake.NewClientBuilder().WithObjects(external).Build()}
+
+	config, err := r.postgresDatabaseConnConfig(context.Background(), project, project.Spec.Database.Postgres, projectDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != host || config.User != "admin" || config.Database != "appdb" {
+		t.Errorf("not connecting with the external secret: %+v", config.Config)
+	}
+
+	// dial runs a dependent's step until it dials the external server
+	dial := func(step func(ctx context.Context) error) {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		go func() {
+			select {
+			case <-dialed:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		if err := step(ctx); err == nil {
+			t.Fatal("expected the stand-in server to refuse the connection")
+		} else if ctx.Err() != context.Canceled {
+			t.Fatalf("external server not dialed: %v", err)
+		}
+	}
+
+	dial(func(ctx context.Context) error {
+		return r.waitForPostgreSQLReady(ctx, project, capabilityCreateRole)
+	})
+	dial(func(ctx context.Context) error {
+		return r.ensureZitadelPostgresSecretAndRole(ctx, project, "acme-pguser-zitadel")
+	})
+
+	roleSecret := &corev1.Secret{}
+	if err := r.Get(context.Background(), types.NamespacedName{Name: "acme-pguser-zitadel", Namespace: "default"},
+		roleSecret); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(roleSecret.Data["PGHOST"]); got != host {
+		t.Errorf("role secret points at %s, not the external server", got)
+	}
+	if got := string(roleSecret.Data["PGDATABASE"]); got != "appdb" {
+		t.Errorf("role secret names database %s, not the external one", got)
+	}
+}
@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+	"github.com/edgeflare/pgo/pkg/pgx/role"
+	"github.com/jackc/pgx/v5"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	postgrestAuthenticatorRole = "authenticator"
+	postgrestAnonRole          = "anon"
+	postgrestAuthnRole         = "authn"
+	postgrestRoleClaimKey      = ".policy.pgrole"
+	postgrestSchemas           = "public"
+)
+
+// reconcilePostgREST prepares the project database for PostgREST the same way the docker-compose
+// init-db-postgresql service does, and wires the JWKS of the project's auth component.
+func (r *ProjectReconciler) reconcilePostgREST(ctx context.Context, project *edgev1alpha1.Project,
+	name string, ref *edgev1alpha1.ComponentRef) error {
+	logger := log.FromContext(ctx)
+	compType := "api"
+
+	postgrestValues := make(map[string]any)
+	if err := yaml.Unmarshal([]byte(ref.Release.ValuesContent), &postgrestValues); err != nil {
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Values error: %v", err), "")
+		return fmt.Errorf("error parsing PostgREST values: %w", err)
+	}
+
+	// Step 1: Wait for PostgreSQL to be ready
+	if err := r.waitForPostgreSQLReady(ctx, project, capabilityCreateRole); err != nil {
+		logger.Error(err, "PostgreSQL is not ready")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Database error: %v", err), "")
+		return err
+	}
+
+	// Step 2: Ensure the authenticator role and its connection secret
+	pgSecretName := fmt.Sprintf("%s-pguser-%s", project.Name, postgrestAuthenticatorRole)
+	authenticatorRole := role.Role{
+		Name:      postgrestAuthenticatorRole,
+		CanLogin:  true,
+		ConnLimit: 100,
+	}
+	if err := r.ensurePostgresRoleSecret(ctx, project, pgSecretName, authenticatorRole,
+		projectDatabase, false); err != nil {
+		logger.Error(err, "Failed to ensure PostgREST authenticator role")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Secret error: %v", err), "")
+		return err
+	}
+
+	// Step 3: Ensure the anon/authn roles and their grants
+	if err := r.ensurePostgRESTRoles(ctx, project); err != nil {
+		logger.Error(err, "Failed to ensure PostgREST roles")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Role error: %v", err), "")
+		return err
+	}
+
+	// Step 4: Resolve the JWT key set of the project's auth component
+	jwks, err := r.postgrestJWKS(ctx, project)
+	if err != nil {
+		logger.Error(err, "Failed to resolve JWKS for PostgREST")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Auth error: %v", err), "")
+		return err
+	}
+
+	// Step 5: Write the PostgREST configuration secret
+	configSecretName := fmt.Sprintf("%s-postgrest", project.Name)
+	if err := r.ensurePostgRESTSecret(ctx, project, configSecretName, pgSecretName, jwks); err != nil {
+		logger.Error(err, "Failed to ensure PostgREST secret")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Secret error: %v", err), "")
+		return err
+	}
+
+	// Step 6: Load the configuration secret into the PostgREST container
+	r.updatePostgRESTValuesWithSecret(postgrestValues, configSecretName)
+
+	updatedValues, err := yaml.Marshal(postgrestValues)
+	if err != nil {
+		logger.Error(err, "Failed to marshal updated PostgREST values")
+		return err
+	}
+
+	ref.Release.ValuesContent = string(updatedValues)
+	return nil
+}
+
+// ensurePostgRESTRoles creates the anon and authn NOLOGIN roles and lets the authenticator switch to them.
+func (r *ProjectReconciler) ensurePostgRESTRoles(ctx context.Context, project *edgev1alpha1.Project) error {
+	logger := log.FromContext(ctx)
+
+	pool, err := r.connectPostgresAsSuperuser(ctx, project, projectDatabase)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer pool.Close()
+
+	for _, name := range []string{postgrestAnonRole, postgrestAuthnRole} {
+		if _, err := role.Get(ctx, pool, name); err == nil {
+			continue
+		} else if err != role.ErrRoleNotFound {
+			return fmt.Errorf("error checking for existing PostgreSQL role: %w", err)
+		}
+
+		if err := role.Create(ctx, pool, role.Role{Name: name, Inherit: true}); err != nil {
+			return fmt.Errorf("failed to create PostgreSQL role %s: %w", name, err)
+		}
+		logger.Info("Created PostgreSQL role", "name", name)
+		r.event(project, corev1.EventTypeNormal, common.ReasonRoleCreated, "Created PostgreSQL role %s", name)
+	}
+
+	for _, stmt := range postgrestRoleStatements() {
+		if _, err := pool.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", stmt, err)
+		}
+	}
+
+	return nil
+}
+
+// postgrestRoleStatements lets the authenticator switch to authn, which inherits anon. The
+// authenticator doesn't inherit their privileges, PostgREST switches roles per request.
+func postgrestRoleStatements() []string {
+	anon := pgx.Identifier{postgrestAnonRole}.Sanitize()
+	authn := pgx.Identifier{postgrestAuthnRole}.Sanitize()
+	authenticator := pgx.Identifier{postgrestAuthenticatorRole}.Sanitize()
+	return []string{
+		fmt.Sprintf("GRANT %s TO %s", anon, authn),
+		fmt.Sprintf("GRANT %s TO %s", authn, authenticator),
+		fmt.Sprintf("ALTER ROLE %s NOINHERIT", authenticator),
+		fmt.Sprintf("GRANT USAGE ON SCHEMA public TO %s", anon),
+	}
+}
+
+// postgrestJWKS returns the JWKS of the project's auth component, or nil if the project has no
+// auth component. An error is returned while the auth component isn't ready yet.
+func (r *ProjectReconciler) postgrestJWKS(ctx context.Context, project *edgev1alpha1.Project) ([]byte, error) {
+	if project.Spec.Auth == nil {
+		return nil, nil
+	}
+
+	status, ready := projectAuthStatus(project)
+	if !ready {
+		return nil, fmt.Errorf("%w: waiting for auth component", errDependencyNotReady)
+	}
+	if status.Endpoint == "" {
+		log.FromContext(ctx).Info("Auth component has no issuer endpoint, PostgREST JWT verification disabled")
+		return nil, nil
+	}
+
+	return fetchJWKS(ctx, status.Endpoint, statusIssuer(status))
+}
+
+// projectAuthStatus returns the status of the project's auth component and whether it's ready
+func projectAuthStatus(project *edgev1alpha1.Project) (edgev1alpha1.ComponentStatus, bool) {
+	for _, name := range []string{"zitadel", "keycloak"} {
+		status, ok := project.Status.ComponentStatuses[fmt.Sprintf("auth-%s", name)]
+		if ok && status.Ready {
+			return status, true
+		}
+	}
+	return edgev1alpha1.ComponentStatus{}, false
+}
+
+// projectIssuer returns the issuer of the project's auth component and whether it's ready
+func projectIssuer(project *edgev1alpha1.Project) (string, bool) {
+	status, ready := projectAuthStatus(project)
+	return statusIssuer(status), ready
+}
+
+// statusIssuer returns the issuer of an auth component's status, which defaults to its endpoint
+func statusIssuer(status edgev1alpha1.ComponentStatus) string {
+	if status.Issuer != "" {
+		return status.Issuer
+	}
+	return status.Endpoint
+}
+
+// ensurePostgRESTSecret creates or updates the secret holding PostgREST's PGRST_* environment.
+func (r *ProjectReconciler) ensurePostgRESTSecret(ctx context.Context, project *edgev1alpha1.Project,
+	secretName, pgSecretName string, jwks []byte) error {
+	pgSecret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: pgSecretName, Namespace: project.Namespace}, pgSecret); err != nil {
+		return fmt.Errorf("failed to get PostgreSQL secret %s: %w", pgSecretName, err)
+	}
+
+	dbURI := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(string(pgSecret.Data["PGUSER"]), string(pgSecret.Data["PGPASSWORD"])),
+		Host:     net.JoinHostPort(string(pgSecret.Data["PGHOST"]), string(pgSecret.Data["PGPORT"])),
+		Path:     string(pgSecret.Data["PGDATABASE"]),
+		RawQuery: "sslmode=" + unverifiedSSLMode(string(pgSecret.Data["PGSSLMODE"])),
+	}
+
+	secretData := map[string][]byte{
+		"PGRST_ADMIN_SERVER_PORT": []byte("3001"),
+		"PGRST_DB_ANON_ROLE":      []byte(postgrestAnonRole),
+		"PGRST_DB_SCHEMAS":        []byte(postgrestSchemas),
+		"PGRST_DB_URI":            []byte(dbURI.String()),
+		"PGRST_ROLE_CLAIM_KEY":    []byte(postgrestRoleClaimKey),
+	}
+	if len(jwks) > 0 {
+		secretData["PGRST_JWT_SECRET"] = jwks
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, secret)
+	if err == nil {
+		secret.Data = secretData
+		return r.Update(ctx, secret)
+	} else if errors.IsNotFound(err) {
+		newSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: project.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: project.APIVersion,
+						Kind:       project.Kind,
+						Name:       project.Name,
+						UID:        project.UID,
+						Controller: ptr.To(true),
+					},
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: secretData,
+		}
+		return r.createSecret(ctx, project, newSecret)
+	}
+
+	return err
+}
+
+// updatePostgRESTValuesWithSecret adds the configuration secret to the chart's envFrom.
+func (r *ProjectReconciler) updatePostgRESTValuesWithSecret(values map[string]any, secretName string) {
+	envFrom, _ := values["envFrom"].([]any)
+	for _, source := range envFrom {
+		if s, ok := source.(map[string]any); ok {
+			if ref, ok := s["secretRef"].(map[string]any); ok && ref["name"] == secretName {
+				return
+			}
+		}
+	}
+
+	values["envFrom"] = append(envFrom, map[string]any{
+		"secretRef": map[string]any{"name": secretName},
+	})
+}
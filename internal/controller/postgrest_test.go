@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestPostgRESTRoleStatements(t *testing.T) {
+	want := []string{
+		`GRANT "anon" TO "authn"`,
+		`GRANT "authn" TO "authenticator"`,
+		`ALTER ROLE "authenticator" NOINHERIT`,
+		`GRANT USAGE ON SCHEMA public TO "anon"`,
+	}
+	if got := postgrestRoleStatements(); !slices.Equal(got, want) {
+		t.Errorf("got statements %q, want %q", got, want)
+	}
+}
+
+func TestEnsurePostgRESTSecret(t *testing.T) {
+	ctx := context.Background()
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+	pgSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-pguser-authenticator", Namespace: "apps"},
+		Data: map[string][]byte{
+			"PGUSER":     []byte("authenticator"),
+			"PGPASSWORD": []byte("p@ss"),
+			"PGHOST":     []byte("demo-postgres-postgresql-primary.apps.svc.cluster.local"),
+			"PGPORT":     []byte("5432"),
+			"PGDATABASE": []byte("main"),
+			"PGSSLMODE":  []byte("require"),
+		},
+	}
+	r := &ProjectReconciler{Client: fake.NewClientBuilder().WithObjects(pgSecret).Build()}
+
+	if err := r.ensurePostgRESTSecret(ctx, project, "demo-postgrest", pgSecret.Name, nil); err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: "demo-postgrest", Namespace: "apps"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"PGRST_ADMIN_SERVER_PORT": "3001",
+		"PGRST_DB_ANON_ROLE":      "anon",
+		"PGRST_DB_SCHEMAS":        "public",
+		"PGRST_DB_URI": "postgres://authenticator:p%40ss@" +
+			"demo-postgres-postgresql-primary.apps.svc.cluster.local:5432/main?sslmode=require",
+		"PGRST_ROLE_CLAIM_KEY": postgrestRoleClaimKey,
+	}
+	for key, value := range want {
+		if got := string(secret.Data[key]); got != value {
+			t.Errorf("got %s %q, want %q", key, got, value)
+		}
+	}
+	if _, ok := secret.Data["PGRST_JWT_SECRET"]; ok {
+		t.Error("JWT secret set without an auth component")
+	}
+
+	// The JWKS is added once the auth component is ready
+	if err := r.ensurePostgRESTSecret(ctx, project, "demo-postgrest", pgSecret.Name, []byte(`{"keys":[]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Name: "demo-postgrest", Namespace: "apps"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(secret.Data["PGRST_JWT_SECRET"]); got != `{"keys":[]}` {
+		t.Errorf("got JWT secret %q", got)
+	}
+}
+
+func TestUpdatePostgRESTValuesWithSecret(t *testing.T) {
+	r := &ProjectReconciler{}
+	values := map[string]any{"envFrom": []any{map[string]any{"configMapRef": map[string]any{"name": "extra"}}}}
+
+	r.updatePostgRESTValuesWithSecret(values, "demo-postgrest")
+	r.updatePostgRESTValuesWithSecret(values, "demo-postgrest")
+	envFrom := values["envFrom"].([]any)
+	if len(envFrom) != 2 {
+		t.Fatalf("got envFrom %v, want the declared source and the secret once", envFrom)
+	}
+	if ref := envFrom[1].(map[string]any)["secretRef"].(map[string]any); ref["name"] != "demo-postgrest" {
+		t.Errorf("got secretRef %v", ref)
+	}
+}
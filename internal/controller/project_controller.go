@@ -3,41 +3,74 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
 	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
 	"github.com/edgeflare/edge/internal/common"
+	"github.com/edgeflare/edge/internal/metrics"
+	"github.com/edgeflare/edge/internal/util/helm"
 )
 
 const (
 	finalizerName = "edgeflare.io/finalizer"
 	requeueShort  = 2 * time.Second
 	requeueLong   = 5 * time.Minute
+	// requeueProgressing polls components that are still rolling out
+	requeueProgressing = 15 * time.Second
+	// labelInstance is set to the release name on the workloads of Helm charts
+	labelInstance = "app.kubernetes.io/instance"
 )
 
 // ProjectReconciler reconciles Project resources
 type ProjectReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// HelmClient locates charts to read their values schema. Embedded schemas are used if nil
+	HelmClient *helm.Client
+	// APIReader reads past the cache to confirm drift of managed resources. The cache is used if nil
+	APIReader client.Reader
+	// Recorder records generated secrets and roles, readiness changes and repairs of drifted
+	// resources as Events. No Events are recorded if nil
+	Recorder record.EventRecorder
 }
 
 // Reconcile handles the reconciliation of Project resources
 // +kubebuilder:rbac:groups=edgeflare.io,resources=projects,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=edgeflare.io,resources=projects/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=edgeflare.io,resources=projects/finalizers,verbs=update
+// +kubebuilder:rbac:groups=edgeflare.io,resources=projecttemplates,verbs=get;list;watch
 // +kubebuilder:rbac:groups=helm.edgeflare.io,resources=releases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=helm.edgeflare.io,resources=releases/status,verbs=get
-// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=batch,resources=cronjobs;jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes;tcproutes,verbs=get;list;watch;create;update;patch;delete
 func (r *ProjectReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("Reconciling", "project", req.NamespacedName)
@@ -53,10 +86,75 @@ func (r *ProjectReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return r.finalize(ctx, project)
 	}
 
-	// Skip if no changes since last reconciliation
-	if project.Status.Generation == project.Generation {
-		logger.Info("No changes detected")
-		return ctrl.Result{RequeueAfter: requeueLong}, nil
+	// Leave the project's secrets, roles and releases as they are while it's suspended
+	if project.Spec.Suspend {
+		logger.Info("Project is suspended")
+		return ctrl.Result{}, r.setSuspended(ctx, project)
+	}
+	resumed := meta.IsStatusConditionTrue(project.Status.Conditions, common.ConditionTypeSuspended)
+	if resumed {
+		if err := r.setCondition(ctx, project, common.ConditionTypeSuspended, metav1.ConditionFalse,
+			common.ReasonResumed, "Reconciliation resumed"); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Pick the ProjectTemplate spec the project resolves against, if it references one
+	resolution, err := r.resolveTemplate(ctx, project)
+	if errors.IsNotFound(err) {
+		logger.Info("Waiting for ProjectTemplate", "name", project.Spec.TemplateRef.Name)
+		return ctrl.Result{}, r.setCondition(ctx, project, common.ConditionTypeReady, metav1.ConditionFalse,
+			common.ReasonTemplateNotFound, err.Error())
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Skip if the current generation and template were reconciled, all components are ready, none
+	// of the managed resources drifted, no certificate was renewed, the migrations are applied and
+	// no backup finished.
+	// A resumed project and one whose credentials are to be rotated are reconciled in full
+	var drifted []drift
+	rotation := rotationDue(project, time.Now()) ||
+		(project.Status.CredentialRotation != nil && len(project.Status.CredentialRotation.PendingRestarts) > 0)
+	if project.Status.Generation == project.Generation && (resolution == nil || !resolution.rollout) &&
+		!resumed && !rotation &&
+		meta.IsStatusConditionTrue(project.Status.Conditions, common.ConditionTypeReady) &&
+		!meta.IsStatusConditionFalse(project.Status.Conditions, common.ConditionTypeRoutesReady) &&
+		!meta.IsStatusConditionFalse(project.Status.Conditions, common.ConditionTypeMigrations) &&
+		!meta.IsStatusConditionFalse(project.Status.Conditions, common.ConditionTypeStorageSSO) {
+		if drifted, err = r.confirmDrift(ctx, project); err != nil {
+			return ctrl.Result{}, err
+		}
+		// Drifted secrets holding generated keys and passwords are left as found until restored,
+		// reconciling would regenerate the deleted ones
+		var unrepairable []drift
+		drifted, unrepairable = partitionDrift(project, drifted)
+		if len(unrepairable) > 0 {
+			logger.Info("Generated keys or passwords drifted, waiting for them to be restored", "resources", unrepairable)
+			return ctrl.Result{RequeueAfter: requeueLong}, r.reportUnrepairableDrift(ctx, project, unrepairable)
+		}
+		if err := r.clearDrifted(ctx, project); err != nil {
+			return ctrl.Result{}, err
+		}
+		renewed, err := r.certificatesRenewed(ctx, project)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if len(drifted) == 0 && !renewed && !r.migrationsChanged(ctx, project) && !r.backupsChanged(ctx, project) {
+			logger.Info("No changes detected")
+			// Replication lag changes without any change to the project
+			if err := r.reconcileReplicaStatus(ctx, project); err != nil {
+				logger.Error(err, "Failed to report the replicas")
+			}
+			return ctrl.Result{RequeueAfter: requeueLong}, nil
+		}
+
+		if len(drifted) > 0 {
+			logger.Info("Repairing drifted resources", "resources", drifted)
+			for _, d := range drifted {
+				r.event(project, corev1.EventTypeWarning, common.ReasonDriftDetected, "%s", d)
+			}
+		}
 	}
 
 	// Ensure finalizer exists
@@ -67,40 +165,82 @@ func (r *ProjectReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, err
 	}
 
-	// Set reconciling status
-	if err := r.setCondition(ctx, project, common.ConditionTypeReady, metav1.ConditionFalse,
-		common.ReasonReconciling, "Reconciling project components"); err != nil {
+	// Reconcile the spec merged onto the template from here on, the stored spec stays untouched
+	if resolution != nil {
+		if err := r.applyTemplate(ctx, project, resolution); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Take over the secrets a deleted Project of the same name retained
+	if err := r.adoptRetainedSecrets(ctx, project); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Rotate the generated database credentials if requested or due, before the components
+	// derive their secrets from them
+	if err := r.rotateCredentials(ctx, project); err != nil {
 		return ctrl.Result{}, err
 	}
 
 	// Reconcile all components
-	if err := r.reconcileComponents(ctx, project); err != nil {
-		logger.Error(err, "Component reconciliation failed")
-		_ = r.setCondition(ctx, project, common.ConditionTypeError, metav1.ConditionTrue,
-			common.ReasonComponentError, fmt.Sprintf("Failed: %v", err))
+	reconcileErr := r.reconcileComponents(ctx, project)
+	if reconcileErr != nil {
+		logger.Error(reconcileErr, "Component reconciliation failed")
+	}
+
+	// Roll the component conditions up into Ready, Progressing and Degraded
+	ready, err := r.updateReadiness(ctx, project)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Publish the connection details of the ready components, also while others are failing
+	if err := r.publishConnection(ctx, project); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	// Mark as ready and update observed generation
-	if err := r.setCondition(ctx, project, common.ConditionTypeReady, metav1.ConditionTrue,
-		common.ReasonReady, "All components ready"); err != nil {
+	// Expose the ready components on the project's Gateway
+	if err := r.reconcileRoutes(ctx, project); err != nil {
 		return ctrl.Result{}, err
 	}
+	if err := r.pruneCertificates(ctx, project); err != nil {
+		return ctrl.Result{}, err
+	}
+	if reconcileErr != nil {
+		return ctrl.Result{}, reconcileErr
+	}
 
-	if err := r.updateObservedGeneration(ctx, project); err != nil {
-		return ctrl.Result{Requeue: true}, nil
+	// Roll the workloads reading the rotated credentials now that their secrets are updated
+	if err := r.restartRotated(ctx, project); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Record the resources the components created as the state to verify on resync
+	if err := r.recordManagedResources(ctx, project); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.clearDrifted(ctx, project); err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(drifted) > 0 {
+		if err := r.recordRepair(ctx, project, drifted); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.event(project, corev1.EventTypeNormal, common.ReasonDriftRepaired,
+			"Restored %d drifted resources", len(drifted))
+	}
+
+	// Requeue until every release is installed and its workloads are available
+	if !ready {
+		logger.Info("Waiting for components to become ready")
+		return ctrl.Result{RequeueAfter: requeueProgressing}, nil
 	}
 
 	logger.Info("Reconciliation successful")
 	return ctrl.Result{RequeueAfter: requeueLong}, nil
 }
 
-func (r *ProjectReconciler) updateObservedGeneration(ctx context.Context, project *edgev1alpha1.Project) error {
-	patch := client.MergeFrom(project.DeepCopy())
-	project.Status.Generation = project.Generation
-	return r.Status().Patch(ctx, project, patch)
-}
-
 func (r *ProjectReconciler) ensureFinalizer(ctx context.Context, project *edgev1alpha1.Project) error {
 	if !controllerutil.ContainsFinalizer(project, finalizerName) {
 		controllerutil.AddFinalizer(project, finalizerName)
@@ -109,34 +249,124 @@ func (r *ProjectReconciler) ensureFinalizer(ctx context.Context, project *edgev1
 	return nil
 }
 
+// reconcileComponents reconciles every declared component through its registered provider.
+// Components waiting for another component are marked as such and don't stop the others.
 func (r *ProjectReconciler) reconcileComponents(ctx context.Context, project *edgev1alpha1.Project) error {
-	// Process database components
-	if db := project.Spec.Database; db != nil {
-		if ref := db.GetComponentRef("postgres"); ref != nil {
-			if err := r.reconcileDatabase(ctx, project, "postgres", ref); err != nil {
-				return err
-			}
+	for _, p := range Providers() {
+		ref := p.ComponentRef(&project.Spec)
+		if ref == nil {
+			// A component removed from the spec isn't reported anymore
+			metrics.DeleteComponent(project.Namespace, project.Name, p.Name())
+			continue
 		}
-	}
 
-	if auth := project.Spec.Auth; auth != nil {
-		if ref := auth.GetComponentRef("zitadel"); ref != nil {
-			if err := r.reconcileAuth(ctx, project, "zitadel", ref); err != nil {
-				return err
-			}
+		start := time.Now()
+		err := r.reconcileComponent(ctx, project, p, ref)
+		metrics.ComponentReconcileDuration.WithLabelValues(p.Name()).Observe(time.Since(start).Seconds())
+		if isDependencyNotReady(err) {
+			_ = r.setComponentStatus(ctx, project, p.Type(), p.Name(), false,
+				common.ReasonWaitingForDependency, err.Error(), "")
+			continue
+		} else if isMissingCapability(err) {
+			_ = r.setComponentStatus(ctx, project, p.Type(), p.Name(), false,
+				common.ReasonMissingCapability, err.Error(), "")
+			continue
+		} else if err != nil {
+			metrics.ComponentReconcileErrors.WithLabelValues(p.Name()).Inc()
+			return err
 		}
 	}
 
 	return nil
 }
 
+// updateReadiness rolls the conditions of the declared components up into the Project's Ready,
+// Progressing and Degraded conditions, and records the reconciled generation. It reports whether
+// every component is ready.
+func (r *ProjectReconciler) updateReadiness(ctx context.Context, project *edgev1alpha1.Project) (bool, error) {
+	patch := client.MergeFrom(project.DeepCopy())
+
+	var total, ready int32
+	progressing, degraded := []string{}, []string{}
+	for _, p := range Providers() {
+		if p.ComponentRef(&project.Spec) == nil {
+			continue
+		}
+		total++
+
+		cond := meta.FindStatusCondition(project.Status.Conditions, componentConditionType(p.Name()))
+		switch {
+		case cond == nil:
+			progressing = append(progressing, p.Name())
+		case cond.Status == metav1.ConditionTrue:
+			ready++
+		case cond.Reason == common.ReasonComponentError, cond.Reason == common.ReasonMissingCapability:
+			degraded = append(degraded, p.Name())
+		default:
+			progressing = append(progressing, p.Name())
+		}
+	}
+
+	readyCond := metav1.Condition{
+		Type:    common.ConditionTypeReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  common.ReasonComponentsReady,
+		Message: "All components ready",
+	}
+	progressingCond := metav1.Condition{
+		Type:    common.ConditionTypeProgressing,
+		Status:  metav1.ConditionFalse,
+		Reason:  common.ReasonComponentsReady,
+		Message: "No components in progress",
+	}
+	degradedCond := metav1.Condition{
+		Type:    common.ConditionTypeDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  common.ReasonComponentsReady,
+		Message: "No components failing",
+	}
+	if len(progressing) > 0 {
+		readyCond.Status = metav1.ConditionFalse
+		readyCond.Reason = common.ReasonComponentsProgressing
+		readyCond.Message = fmt.Sprintf("%d of %d components ready", ready, total)
+		progressingCond.Status = metav1.ConditionTrue
+		progressingCond.Reason = common.ReasonComponentsProgressing
+		progressingCond.Message = fmt.Sprintf("Waiting for %s", strings.Join(progressing, ", "))
+	}
+	if len(degraded) > 0 {
+		readyCond.Status = metav1.ConditionFalse
+		readyCond.Reason = common.ReasonComponentsDegraded
+		readyCond.Message = fmt.Sprintf("%d of %d components ready", ready, total)
+		degradedCond.Status = metav1.ConditionTrue
+		degradedCond.Reason = common.ReasonComponentsDegraded
+		degradedCond.Message = fmt.Sprintf("Failing: %s", strings.Join(degraded, ", "))
+	}
+
+	r.recordTransition(project, readyCond)
+	for _, cond := range []metav1.Condition{readyCond, progressingCond, degradedCond} {
+		cond.ObservedGeneration = project.Generation
+		meta.SetStatusCondition(&project.Status.Conditions, cond)
+	}
+	// Superseded by Degraded
+	meta.RemoveStatusCondition(&project.Status.Conditions, common.ConditionTypeError)
+
+	project.Status.ReadyComponents = ready
+	project.Status.TotalComponents = total
+	project.Status.Generation = project.Generation
+	metrics.SetReady(metrics.ProjectReady.WithLabelValues(project.Namespace, project.Name),
+		readyCond.Status == metav1.ConditionTrue)
+
+	return readyCond.Status == metav1.ConditionTrue, r.patchStatus(ctx, project, patch)
+}
+
 func (r *ProjectReconciler) handleComponentRelease(ctx context.Context, project *edgev1alpha1.Project,
-	compType, name string, ref *edgev1alpha1.ComponentRef) error {
+	p ComponentProvider, ref *edgev1alpha1.ComponentRef) error {
 	logger := log.FromContext(ctx)
+	compType, name := p.Type(), p.Name()
 	releaseName := fmt.Sprintf("%s-%s", project.Name, name)
 
 	// Create or update the release
-	if err := r.upsertRelease(ctx, project, compType, name, ref); err != nil {
+	if err := r.upsertRelease(ctx, project, p, ref); err != nil {
 		logger.Error(err, "Release creation failed")
 		_ = r.updateComponentStatus(ctx, project, compType, name, false,
 			fmt.Sprintf("Release error: %v", err), "")
@@ -157,56 +387,126 @@ func (r *ProjectReconciler) handleComponentRelease(ctx context.Context, project
 		return err
 	}
 
-	// Default status values
-	ready := false
-	message := "Installation in progress"
-	endpoint := ""
-
-	// Check release conditions
+	// A failed install or invalid values degrade the component, anything else is progress
 	for _, condition := range release.Status.Conditions {
-		if condition.Type == common.ConditionTypeInstalled && condition.Status == metav1.ConditionTrue {
-			ready = true
-			message = "Component ready"
-
-			// Set endpoint for postgres
-			if compType == "database" && name == "postgres" {
-				endpoint = fmt.Sprintf("%s-postgresql.%s.svc.cluster.local",
-					project.Name, project.Namespace)
-			}
-			break
+		if condition.Status != metav1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case common.ConditionTypeError, common.ConditionTypeValuesInvalid:
+			return r.setComponentStatus(ctx, project, compType, name, false, common.ReasonComponentError,
+				fmt.Sprintf("Release error: %s", condition.Message), "")
 		}
 	}
 
-	// Update status
-	return r.updateComponentStatus(ctx, project, compType, name, ready, message, endpoint)
+	if !meta.IsStatusConditionTrue(release.Status.Conditions, common.ConditionTypeInstalled) {
+		return r.setComponentStatus(ctx, project, compType, name, false, common.ReasonProgressing,
+			"Installation in progress", "")
+	}
+
+	// The release is installed, wait for its workloads to roll out
+	available, message, err := r.releaseWorkloadsAvailable(ctx, project.Namespace, releaseName)
+	if err != nil {
+		return err
+	}
+	if !available {
+		return r.setComponentStatus(ctx, project, compType, name, false, common.ReasonProgressing, message, "")
+	}
+
+	ready, message, err := p.Ready(ctx, r, project, ref)
+	if err != nil {
+		return r.setComponentStatus(ctx, project, compType, name, false, common.ReasonProgressing,
+			fmt.Sprintf("Readiness error: %v", err), "")
+	}
+	if !ready {
+		return r.setComponentStatus(ctx, project, compType, name, false, common.ReasonProgressing, message, "")
+	}
+
+	// Keep the replicas PostReady reported while the component stays ready
+	previous := project.Status.ComponentStatuses[fmt.Sprintf("%s-%s", compType, name)]
+	return r.writeComponentStatus(ctx, project, compType, name, edgev1alpha1.ComponentStatus{
+		Ready:              true,
+		Message:            message,
+		Endpoint:           p.Endpoint(project, ref),
+		Issuer:             p.Issuer(project, ref),
+		Replicas:           previous.Replicas,
+		ReplicasObservedAt: previous.ReplicasObservedAt,
+	}, common.ReasonReady)
+}
+
+// releaseWorkloadsAvailable reports whether the Deployments and StatefulSets of a release have
+// rolled out. Workloads are matched by the app.kubernetes.io/instance label Helm charts set.
+func (r *ProjectReconciler) releaseWorkloadsAvailable(ctx context.Context, namespace,
+	releaseName string) (bool, string, error) {
+	selector := client.MatchingLabels{labelInstance: releaseName}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(namespace), selector); err != nil {
+		return false, "", fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		desired := ptr.Deref(d.Spec.Replicas, 1)
+		if d.Status.ObservedGeneration < d.Generation || d.Status.UpdatedReplicas < desired ||
+			d.Status.AvailableReplicas < desired {
+			return false, fmt.Sprintf("Waiting for deployment %s (%d/%d available)",
+				d.Name, d.Status.AvailableReplicas, desired), nil
+		}
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulSets, client.InNamespace(namespace), selector); err != nil {
+		return false, "", fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, s := range statefulSets.Items {
+		desired := ptr.Deref(s.Spec.Replicas, 1)
+		if s.Status.ObservedGeneration < s.Generation || s.Status.UpdatedReplicas < desired ||
+			s.Status.ReadyReplicas < desired {
+			return false, fmt.Sprintf("Waiting for statefulset %s (%d/%d ready)",
+				s.Name, s.Status.ReadyReplicas, desired), nil
+		}
+	}
+
+	return true, "", nil
 }
 
+// handleExternalComponent marks a verified external component ready with the status its provider published
 func (r *ProjectReconciler) handleExternalComponent(ctx context.Context, project *edgev1alpha1.Project,
-	compType, name string, ref *edgev1alpha1.ComponentRef) error {
-	_ = ref
-	// External resources are marked ready if secret exists
-	return r.updateComponentStatus(ctx, project, compType, name, true, "Using external resource", "")
+	compType, name string, status edgev1alpha1.ComponentStatus) error {
+	status.Ready = true
+	if status.Message == "" {
+		status.Message = "Using external resource"
+	}
+	return r.writeComponentStatus(ctx, project, compType, name, status, common.ReasonReady)
 }
 
 func (r *ProjectReconciler) upsertRelease(ctx context.Context, project *edgev1alpha1.Project,
-	compType, name string, ref *edgev1alpha1.ComponentRef) error {
+	p ComponentProvider, ref *edgev1alpha1.ComponentRef) error {
 	logger := log.FromContext(ctx)
 
 	if !ref.IsRelease() {
 		return nil
 	}
 
+	compType, name := p.Type(), p.Name()
 	releaseName := fmt.Sprintf("%s-%s", project.Name, name)
-	releaseSpec := ref.GetReleaseSpec(compType, project.Name)
+	releaseSpec := p.DefaultRelease(project.Name)
+	if ref.Release != nil {
+		releaseSpec = *ref.Release
+	}
 
 	// Prepare release object
+	deletionPolicy := string(ref.GetDeletionPolicy(project.Spec.DeletionPolicy))
 	release := &helmv1alpha1.Release{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      releaseName,
 			Namespace: project.Namespace,
+			Annotations: map[string]string{
+				common.AnnotationDeletionPolicy: deletionPolicy,
+			},
 			Labels: map[string]string{
 				common.LabelManagedBy: "edge",
 				common.LabelComponent: compType,
+				common.LabelName:      name,
 				common.LabelProject:   project.Name,
 			},
 		},
@@ -231,12 +531,37 @@ func (r *ProjectReconciler) upsertRelease(ctx context.Context, project *edgev1al
 
 	// Update existing release
 	existing.Spec = release.Spec
+	if existing.Annotations == nil {
+		existing.Annotations = map[string]string{}
+	}
+	existing.Annotations[common.AnnotationDeletionPolicy] = deletionPolicy
 	logger.Info("Updating release", "name", release.Name)
 	return r.Update(ctx, existing)
 }
 
+// updateComponentStatus records a component as ready, or as failing with message.
 func (r *ProjectReconciler) updateComponentStatus(ctx context.Context, project *edgev1alpha1.Project,
 	compType, name string, ready bool, message, endpoint string) error {
+	reason := common.ReasonComponentError
+	if ready {
+		reason = common.ReasonReady
+	}
+	return r.setComponentStatus(ctx, project, compType, name, ready, reason, message, endpoint)
+}
+
+// setComponentStatus records the status of a component and its <Name>Ready condition.
+func (r *ProjectReconciler) setComponentStatus(ctx context.Context, project *edgev1alpha1.Project,
+	compType, name string, ready bool, reason, message, endpoint string) error {
+	return r.writeComponentStatus(ctx, project, compType, name, edgev1alpha1.ComponentStatus{
+		Ready:    ready,
+		Message:  message,
+		Endpoint: endpoint,
+	}, reason)
+}
+
+// writeComponentStatus records status as the status of a component and its <Name>Ready condition.
+func (r *ProjectReconciler) writeComponentStatus(ctx context.Context, project *edgev1alpha1.Project,
+	compType, name string, status edgev1alpha1.ComponentStatus, reason string) error {
 
 	// Create status patch
 	patch := client.MergeFrom(project.DeepCopy())
@@ -248,13 +573,53 @@ func (r *ProjectReconciler) updateComponentStatus(ctx context.Context, project *
 
 	// Update component status
 	key := fmt.Sprintf("%s-%s", compType, name)
-	project.Status.ComponentStatuses[key] = edgev1alpha1.ComponentStatus{
-		Ready:    ready,
-		Message:  message,
-		Endpoint: endpoint,
+	project.Status.ComponentStatuses[key] = status
+
+	conditionStatus := metav1.ConditionFalse
+	if status.Ready {
+		conditionStatus = metav1.ConditionTrue
+	}
+	cond := metav1.Condition{
+		Type:               componentConditionType(name),
+		Status:             conditionStatus,
+		ObservedGeneration: project.Generation,
+		Reason:             reason,
+		Message:            status.Message,
+	}
+	r.recordTransition(project, cond)
+	meta.SetStatusCondition(&project.Status.Conditions, cond)
+	metrics.SetReady(metrics.ComponentReady.WithLabelValues(project.Namespace, project.Name, name), status.Ready)
+
+	return r.patchStatus(ctx, project, patch)
+}
+
+// patchStatus patches the project's status. The response carries the stored spec, which would
+// replace the spec resolved from the project's template, so the resolved spec is kept.
+func (r *ProjectReconciler) patchStatus(ctx context.Context, project *edgev1alpha1.Project,
+	patch client.Patch) error {
+	spec := project.Spec.DeepCopy()
+	err := r.Status().Patch(ctx, project, patch)
+	project.Spec = *spec
+	return err
+}
+
+// setSuspended records who or what suspended the project in its Suspended condition
+func (r *ProjectReconciler) setSuspended(ctx context.Context, project *edgev1alpha1.Project) error {
+	message := fmt.Sprintf("Suspended by %s", common.SuspendedBy(project))
+	if cond := meta.FindStatusCondition(project.Status.Conditions, common.ConditionTypeSuspended); cond != nil &&
+		cond.Status == metav1.ConditionTrue && cond.Message == message {
+		return nil
 	}
+	return r.setCondition(ctx, project, common.ConditionTypeSuspended, metav1.ConditionTrue,
+		common.ReasonSuspended, message)
+}
 
-	return r.Status().Patch(ctx, project, patch)
+// componentConditionType returns the condition type of a component, e.g. PostgresReady
+func componentConditionType(name string) string {
+	if name == "" {
+		return common.ConditionTypeReady
+	}
+	return strings.ToUpper(name[:1]) + name[1:] + common.ConditionTypeReady
 }
 
 func (r *ProjectReconciler) setCondition(ctx context.Context, project *edgev1alpha1.Project,
@@ -263,49 +628,17 @@ func (r *ProjectReconciler) setCondition(ctx context.Context, project *edgev1alp
 	// Create status patch
 	patch := client.MergeFrom(project.DeepCopy())
 
-	// Prepare new condition
-	newCondition := metav1.Condition{
+	cond := metav1.Condition{
 		Type:               condType,
 		Status:             status,
-		LastTransitionTime: metav1.Now(),
+		ObservedGeneration: project.Generation,
 		Reason:             reason,
 		Message:            message,
 	}
+	r.recordTransition(project, cond)
+	meta.SetStatusCondition(&project.Status.Conditions, cond)
 
-	// Update conditions list
-	found := false
-	newConditions := []metav1.Condition{}
-
-	// Clear error conditions if setting Ready to true
-	if condType == common.ConditionTypeReady && status == metav1.ConditionTrue {
-		for _, cond := range project.Status.Conditions {
-			if cond.Type != common.ConditionTypeError {
-				if cond.Type == condType {
-					newConditions = append(newConditions, newCondition)
-					found = true
-				} else {
-					newConditions = append(newConditions, cond)
-				}
-			}
-		}
-	} else {
-		// Update/add the specified condition
-		for _, cond := range project.Status.Conditions {
-			if cond.Type == condType {
-				newConditions = append(newConditions, newCondition)
-				found = true
-			} else {
-				newConditions = append(newConditions, cond)
-			}
-		}
-	}
-
-	if !found {
-		newConditions = append(newConditions, newCondition)
-	}
-
-	project.Status.Conditions = newConditions
-	return r.Status().Patch(ctx, project, patch)
+	return r.patchStatus(ctx, project, patch)
 }
 
 func (r *ProjectReconciler) finalize(ctx context.Context, project *edgev1alpha1.Project) (ctrl.Result, error) {
@@ -324,6 +657,21 @@ func (r *ProjectReconciler) finalize(ctx context.Context, project *edgev1alpha1.
 
 		// If releases still exist, explicitly delete them
 		if len(releaseList.Items) > 0 {
+			// Keep what the components' deletion policies retain before anything is deleted. The
+			// policies may come from the template spec the project was last resolved against
+			resolved := project.DeepCopy()
+			if template := project.Status.Template; template != nil && template.Spec != nil &&
+				project.Spec.TemplateRef != nil {
+				merged, err := mergeTemplate(template.Spec, &project.Spec)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				resolved.Spec = *merged
+			}
+			if err := r.retainComponents(ctx, resolved); err != nil {
+				return ctrl.Result{}, err
+			}
+
 			logger.Info("Deleting associated releases", "count", len(releaseList.Items))
 			for _, release := range releaseList.Items {
 				if err := r.Delete(ctx, &release); err != nil && !errors.IsNotFound(err) {
@@ -341,16 +689,82 @@ func (r *ProjectReconciler) finalize(ctx context.Context, project *edgev1alpha1.
 		if err := r.Update(ctx, project); err != nil {
 			return ctrl.Result{}, err
 		}
+		metrics.DeleteProject(project.Namespace, project.Name)
 		logger.Info("Project finalized")
 	}
 
 	return ctrl.Result{}, nil
 }
 
-// SetupWithManager sets up the controller with the Manager
+// createSecret creates a secret generated for the project and records it as an Event
+func (r *ProjectReconciler) createSecret(ctx context.Context, project *edgev1alpha1.Project,
+	secret *corev1.Secret) error {
+	if err := r.Create(ctx, secret); err != nil {
+		return err
+	}
+	r.event(project, corev1.EventTypeNormal, common.ReasonSecretGenerated, "Generated secret %s", secret.Name)
+	return nil
+}
+
+// recordTransition records an Event if cond changes the status or reason of the project's
+// condition of its type. Progress messages within the same reason don't repeat the Event.
+func (r *ProjectReconciler) recordTransition(project *edgev1alpha1.Project, cond metav1.Condition) {
+	if current := meta.FindStatusCondition(project.Status.Conditions, cond.Type); current != nil &&
+		current.Status == cond.Status && current.Reason == cond.Reason {
+		return
+	}
+
+	eventType := corev1.EventTypeNormal
+	switch cond.Reason {
+	case common.ReasonComponentError, common.ReasonMissingCapability, common.ReasonComponentsDegraded,
+		common.ReasonSchemaValidationFailed, common.ReasonTemplateNotFound, common.ReasonGatewayAPIMissing,
+		common.ReasonMigrationFailed, common.ReasonChecksumMismatch, common.ReasonCredentialsDrifted,
+		common.ReasonOIDCClientMissing:
+		eventType = corev1.EventTypeWarning
+	}
+	r.event(project, eventType, cond.Reason, "%s: %s", cond.Type, cond.Message)
+}
+
+// event records an Event on the project if the reconciler has a recorder. The recorder aggregates
+// repeated Events into one with a count.
+func (r *ProjectReconciler) event(project *edgev1alpha1.Project, eventType, reason, messageFmt string, args ...any) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(project, eventType, reason, messageFmt, args...)
+	}
+}
+
+// withOwnerProject adds the Project controlling an object to the requests of mapFn. A type both
+// owned by Projects and mapped to them is watched once, rather than through Owns and Watches.
+func withOwnerProject(mapFn handler.MapFunc) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		requests := mapFn(ctx, obj)
+		owner := metav1.GetControllerOf(obj)
+		if owner == nil || owner.Kind != "Project" ||
+			!strings.HasPrefix(owner.APIVersion, edgev1alpha1.GroupVersion.Group+"/") {
+			return requests
+		}
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Name: owner.Name, Namespace: obj.GetNamespace()}}
+		if !slices.Contains(requests, request) {
+			requests = append(requests, request)
+		}
+		return requests
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager. Owned Secrets and Releases are
+// watched so that deleting or modifying them triggers a repair, the secrets of issued certificates
+// so that renewals roll out, ConfigMaps so that new migrations are applied, Jobs so that finished
+// backups and restores are recorded, and ProjectTemplates so that their changes roll out to the
+// Projects referencing them.
 func (r *ProjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&edgev1alpha1.Project{}).
 		Owns(&helmv1alpha1.Release{}).
+		Owns(&batchv1.CronJob{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(withOwnerProject(r.projectForCertificateSecret))).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(withOwnerProject(r.projectsForMigrationConfigMap))).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(withOwnerProject(r.projectForBackupJob))).
+		Watches(&edgev1alpha1.ProjectTemplate{}, handler.EnqueueRequestsFromMapFunc(r.projectsForTemplate),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
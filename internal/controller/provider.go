@@ -0,0 +1,237 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+	"github.com/edgeflare/edge/internal/component"
+	"github.com/edgeflare/edge/internal/util/helm"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ComponentProvider plugs a project component into the ProjectReconciler. Each provider knows
+// where its component is declared in the ProjectSpec, which chart it defaults to, and which
+// secrets, roles and values the release depends on.
+type ComponentProvider interface {
+	component.Component
+	// VerifyExternal checks an external component and its secret. It returns the endpoint and
+	// other details published in the component's status once verified
+	VerifyExternal(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+		ref *edgev1alpha1.ComponentRef) (edgev1alpha1.ComponentStatus, error)
+	// PreInstall ensures the secrets and database roles the release depends on. It may rewrite
+	// ref.Release.ValuesContent to point the chart at them
+	PreInstall(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+		ref *edgev1alpha1.ComponentRef) error
+	// Ready probes the component once its release is installed
+	Ready(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+		ref *edgev1alpha1.ComponentRef) (bool, string, error)
+	// Endpoint returns the endpoint published in the component's status
+	Endpoint(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string
+	// Issuer returns the OIDC issuer of an auth component if it differs from its endpoint, or ""
+	Issuer(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string
+	// PostReady runs every reconciliation once the component is ready, e.g. to create buckets
+	PostReady(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+		ref *edgev1alpha1.ComponentRef) error
+	// Secrets returns the names of the credential secrets the component generates. They're kept
+	// when the component's deletion policy is Retain or Orphan
+	Secrets(project *edgev1alpha1.Project) []string
+	// Routes returns the services of a built-in component exposed through the project's Gateway
+	Routes(project *edgev1alpha1.Project) []routeTarget
+	// CertificateDNSNames returns the DNS names of a built-in component's server certificate, or
+	// nil if the component serves no TLS
+	CertificateDNSNames(project *edgev1alpha1.Project) []string
+	// InjectTLS points the release values at the secret of the issued certificate
+	InjectTLS(values map[string]any, cert issuedCertificate)
+}
+
+// errDependencyNotReady is wrapped by the errors of components waiting for another component.
+// Such components are reported as progressing rather than failing.
+var errDependencyNotReady = errors.New("dependency not ready")
+
+func isDependencyNotReady(err error) bool {
+	return errors.Is(err, errDependencyNotReady)
+}
+
+// errMissingCapability is wrapped by the errors of components blocked by a database lacking a
+// capability they require. Such components are reported as degraded until the database is fixed.
+var errMissingCapability = errors.New("missing database capability")
+
+func isMissingCapability(err error) bool {
+	return errors.Is(err, errMissingCapability)
+}
+
+// providers holds the registered providers in reconciliation order
+var providers []ComponentProvider
+
+// RegisterProvider adds p to the providers reconciled for every Project. Providers are reconciled
+// in registration order, so a component must be registered after the components it depends on.
+// The component of a provider not built in is registered for the webhooks too.
+func RegisterProvider(p ComponentProvider) {
+	for _, existing := range providers {
+		if existing.Type() == p.Type() && existing.Name() == p.Name() {
+			panic(fmt.Sprintf("component provider %s/%s already registered", p.Type(), p.Name()))
+		}
+	}
+	if component.Lookup(p.Type(), p.Name()) == nil {
+		component.Register(p)
+	}
+	providers = append(providers, p)
+}
+
+// Providers returns the registered providers in reconciliation order
+func Providers() []ComponentProvider {
+	return append([]ComponentProvider(nil), providers...)
+}
+
+// baseProvider implements the optional hooks of ComponentProvider as no-ops for the component it
+// reconciles. Providers embed it and override the hooks they need.
+type baseProvider struct{ component.Component }
+
+func (b baseProvider) VerifyExternal(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) (edgev1alpha1.ComponentStatus, error) {
+	return edgev1alpha1.ComponentStatus{}, nil
+}
+
+func (b baseProvider) PreInstall(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	return nil
+}
+
+func (b baseProvider) Ready(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) (bool, string, error) {
+	return true, "Component ready", nil
+}
+
+func (b baseProvider) Endpoint(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string {
+	return ""
+}
+
+func (b baseProvider) Issuer(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string {
+	return ""
+}
+
+func (b baseProvider) PostReady(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	return nil
+}
+
+func (b baseProvider) Secrets(project *edgev1alpha1.Project) []string { return nil }
+
+func (b baseProvider) Routes(project *edgev1alpha1.Project) []routeTarget { return nil }
+
+func (b baseProvider) CertificateDNSNames(project *edgev1alpha1.Project) []string { return nil }
+
+func (b baseProvider) InjectTLS(values map[string]any, cert issuedCertificate) {}
+
+// reconcileComponent reconciles a single declared component through its provider.
+// External components are verified and marked ready; built-in ones get their default release,
+// validated values, certificate and pre-install dependencies before the Helm release is created or updated.
+func (r *ProjectReconciler) reconcileComponent(ctx context.Context, project *edgev1alpha1.Project,
+	p ComponentProvider, ref *edgev1alpha1.ComponentRef) error {
+	logger := log.FromContext(ctx)
+	compType, name := p.Type(), p.Name()
+	logger.Info("Reconciling component", "type", compType, "component", name)
+
+	if ref.IsExternal() {
+		status, err := p.VerifyExternal(ctx, r, project, ref)
+		if err != nil {
+			logger.Error(err, "External component verification failed")
+			_ = r.updateComponentStatus(ctx, project, compType, name, false,
+				fmt.Sprintf("Verification error: %v", err), "")
+			return err
+		}
+		if err := r.handleExternalComponent(ctx, project, compType, name, status); err != nil {
+			return err
+		}
+		return p.PostReady(ctx, r, project, ref)
+	}
+
+	// Fall back to the provider's chart and values if no release was supplied
+	if ref.Release == nil {
+		releaseSpec := p.DefaultRelease(project.Name)
+		ref.Release = &releaseSpec
+	}
+
+	if err := r.validateValues(ctx, project, p, ref); err != nil {
+		return err
+	}
+
+	// Point the release at the certificate issued for the component, if the project requests TLS
+	if err := r.reconcileCertificate(ctx, project, p, ref); err != nil {
+		return err
+	}
+
+	if err := p.PreInstall(ctx, r, project, ref); err != nil {
+		return err
+	}
+
+	if err := r.handleComponentRelease(ctx, project, p, ref); err != nil {
+		return err
+	}
+
+	if status := project.Status.ComponentStatuses[fmt.Sprintf("%s-%s", compType, name)]; !status.Ready {
+		return nil
+	}
+	return p.PostReady(ctx, r, project, ref)
+}
+
+// validateValues validates the component's values against its chart's values schema before the
+// release is created or updated, and records the outcome in the Project's ValuesInvalid condition.
+func (r *ProjectReconciler) validateValues(ctx context.Context, project *edgev1alpha1.Project,
+	p ComponentProvider, ref *edgev1alpha1.ComponentRef) error {
+	logger := log.FromContext(ctx)
+	compType, name := p.Type(), p.Name()
+
+	schema, err := r.valuesSchema(ctx, p, ref.Release.ChartURL)
+	if err != nil {
+		logger.Error(err, "Failed to load values schema")
+		return err
+	}
+
+	err = helm.ValidateValues(ref.Release.ValuesContent, schema)
+	invalidValues := &helm.InvalidValuesError{}
+	if errors.As(err, &invalidValues) {
+		logger.Error(err, "Failed to validate values")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Values error: %v", err), "")
+		_ = r.setCondition(ctx, project, common.ConditionTypeValuesInvalid, metav1.ConditionTrue,
+			common.ReasonSchemaValidationFailed, fmt.Sprintf("%s: %v", name, err))
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	// Clear the condition once the component that raised it has valid values
+	if cond := meta.FindStatusCondition(project.Status.Conditions, common.ConditionTypeValuesInvalid); cond != nil &&
+		cond.Status == metav1.ConditionTrue && strings.HasPrefix(cond.Message, name+": ") {
+		return r.setCondition(ctx, project, common.ConditionTypeValuesInvalid, metav1.ConditionFalse,
+			common.ReasonValuesValid, "Values are valid")
+	}
+
+	return nil
+}
+
+// valuesSchema returns the values schema of chartURL. The chart's own values.schema.json is
+// preferred, then the schema embedded in the binary, then the provider's.
+func (r *ProjectReconciler) valuesSchema(ctx context.Context, p ComponentProvider, chartURL string) ([]byte, error) {
+	if r.HelmClient != nil {
+		schema, err := r.HelmClient.ValuesSchema(chartURL)
+		if err == nil && len(schema) > 0 {
+			return schema, nil
+		}
+		if err != nil {
+			log.FromContext(ctx).Info("Falling back to embedded values schema", "chart", chartURL, "error", err.Error())
+		}
+	}
+
+	if schema := helm.EmbeddedValuesSchema(helm.ChartName(chartURL)); schema != nil {
+		return schema, nil
+	}
+	return p.ValuesSchema()
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/component"
+)
+
+func TestProviders(t *testing.T) {
+	names := []string{}
+	for _, p := range Providers() {
+		names = append(names, p.Type()+"/"+p.Name())
+	}
+	// The dependencies of a component are reconciled before it
+	want := []string{"database/postgres", "auth/zitadel", "auth/keycloak", "api/postgrest",
+		"storage/minio", "storage/seaweedfs", "pubsub/pgo"}
+	if !slices.Equal(names, want) {
+		t.Errorf("got providers %v, want %v", names, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a provider twice didn't panic")
+		}
+		if len(Providers()) != len(want) {
+			t.Errorf("got %d providers after the duplicate registration", len(Providers()))
+		}
+	}()
+	RegisterProvider(zitadelProvider{baseProvider{component.Builtin("auth", "zitadel")}})
+}
+
+func TestProviderEndpointAndSecrets(t *testing.T) {
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+	ref := &edgev1alpha1.ComponentRef{Release: &helmv1alpha1.ReleaseSpec{}}
+
+	tests := []struct {
+		provider string
+		endpoint string
+		secrets  []string
+	}{
+		{
+			provider: "database/postgres",
+			endpoint: "demo-postgres-postgresql-primary.apps.svc.cluster.local",
+			secrets:  []string{"demo-postgresql", "demo-pguser-postgres", "demo-pguser-readonly"},
+		},
+		{
+			provider: "auth/zitadel",
+			endpoint: "http://demo-zitadel.apps.svc.cluster.local:8080",
+			secrets:  []string{"demo-zitadel-masterkey", "demo-zitadel-firstinstance", "demo-pguser-zitadel"},
+		},
+		{
+			provider: "auth/keycloak",
+			endpoint: "http://demo-keycloak.apps.svc.cluster.local/realms/master",
+			secrets:  []string{"demo-keycloak-admin", "demo-pguser-keycloak"},
+		},
+		{
+			provider: "api/postgrest",
+			endpoint: "http://demo-postgrest.apps.svc.cluster.local",
+			secrets:  []string{"demo-pguser-authenticator"},
+		},
+		{
+			provider: "storage/minio",
+			endpoint: "http://demo-minio.apps.svc.cluster.local:9000",
+			secrets:  []string{"demo-minio-root", "demo-s3"},
+		},
+		{
+			provider: "storage/seaweedfs",
+			endpoint: "http://demo-seaweedfs-s3.apps.svc.cluster.local:8333",
+			secrets:  []string{"demo-seaweedfs-root", "demo-s3"},
+		},
+		{
+			provider: "pubsub/pgo",
+			endpoint: "http://demo-pgo.apps.svc.cluster.local:8001",
+			secrets:  []string{"demo-pguser-pgo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			i := slices.IndexFunc(Providers(), func(p ComponentProvider) bool {
+				return p.Type()+"/"+p.Name() == tt.provider
+			})
+			if i < 0 {
+				t.Fatalf("provider %s not registered", tt.provider)
+			}
+			p := Providers()[i]
+			if endpoint := p.Endpoint(project, ref); endpoint != tt.endpoint {
+				t.Errorf("got endpoint %s, want %s", endpoint, tt.endpoint)
+			}
+			if secrets := p.Secrets(project); !slices.Equal(secrets, tt.secrets) {
+				t.Errorf("got secrets %v, want %v", secrets, tt.secrets)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/component"
+)
+
+// Built-in providers, in dependency order: every component but the database itself needs the
+// project's PostgreSQL, and the API, storage and pub/sub components wire the auth component's issuer.
+func init() {
+	RegisterProvider(postgresProvider{baseProvider{component.Builtin("database", "postgres")}})
+	RegisterProvider(zitadelProvider{baseProvider{component.Builtin("auth", "zitadel")}})
+	RegisterProvider(keycloakProvider{baseProvider{component.Builtin("auth", "keycloak")}})
+	RegisterProvider(postgrestProvider{baseProvider{component.Builtin("api", "postgrest")}})
+	RegisterProvider(storageProvider{baseProvider{component.Builtin("storage", "minio")}})
+	RegisterProvider(storageProvider{baseProvider{component.Builtin("storage", "seaweedfs")}})
+	RegisterProvider(pgoProvider{baseProvider{component.Builtin("pubsub", "pgo")}})
+}
+
+// postgresProvider installs the project's PostgreSQL. Declaring spec.database alone is enough
+type postgresProvider struct{ baseProvider }
+
+func (p postgresProvider) VerifyExternal(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) (edgev1alpha1.ComponentStatus, error) {
+	return r.verifyExternalPostgres(ctx, project, p.Name(), ref)
+}
+
+func (p postgresProvider) PreInstall(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	return r.preparePostgres(ctx, project, p.Name(), ref)
+}
+
+func (p postgresProvider) Endpoint(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string {
+	return postgresPrimaryHost(project)
+}
+
+func (p postgresProvider) PostReady(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	if err := r.reconcileDatabaseObjects(ctx, project, ref); err != nil {
+		return err
+	}
+	// Restore the databases before they're backed up or migrated
+	if restored, err := r.reconcileRestore(ctx, project, ref); err != nil || !restored {
+		return err
+	}
+	if err := r.reconcileReadonlyRole(ctx, project, ref); err != nil {
+		return err
+	}
+	if err := r.reconcileBackups(ctx, project, ref); err != nil {
+		return err
+	}
+	if err := r.reconcileExtensions(ctx, project, ref); err != nil {
+		return err
+	}
+	if err := r.reconcileMigrations(ctx, project, ref); err != nil {
+		return err
+	}
+	return r.reconcileReplicaStatus(ctx, project)
+}
+
+func (p postgresProvider) Secrets(project *edgev1alpha1.Project) []string {
+	return append([]string{project.Name + "-postgresql", project.Name + "-pguser-postgres",
+		readonlySecretName(project)}, declaredLoginRoleSecrets(project)...)
+}
+
+func (p postgresProvider) Routes(project *edgev1alpha1.Project) []routeTarget {
+	return []routeTarget{{name: "postgres", service: postgresPrimaryService(project), port: 5432, tcp: true}}
+}
+
+func (p postgresProvider) CertificateDNSNames(project *edgev1alpha1.Project) []string {
+	return serviceDNSNames(project, project.Name+"-postgres-postgresql", postgresPrimaryService(project),
+		project.Name+"-postgres-postgresql-read")
+}
+
+func (p postgresProvider) InjectTLS(values map[string]any, cert issuedCertificate) {
+	injectPostgresTLS(values, cert)
+}
+
+// zitadelProvider installs Zitadel backed by the project's PostgreSQL
+type zitadelProvider struct{ baseProvider }
+
+func (p zitadelProvider) VerifyExternal(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) (edgev1alpha1.ComponentStatus, error) {
+	return r.verifyExternalIdP(ctx, project, ref)
+}
+
+func (p zitadelProvider) PreInstall(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	return r.reconcileZitadel(ctx, project, p.Name(), ref)
+}
+
+func (p zitadelProvider) Endpoint(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string {
+	return zitadelServiceURL(project)
+}
+
+func (p zitadelProvider) Issuer(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string {
+	return zitadelIssuerURL(ref.Release.ValuesContent)
+}
+
+func (p zitadelProvider) Secrets(project *edgev1alpha1.Project) []string {
+	return []string{project.Name + "-zitadel-masterkey", project.Name + "-zitadel-firstinstance",
+		project.Name + "-pguser-zitadel"}
+}
+
+func (p zitadelProvider) Routes(project *edgev1alpha1.Project) []routeTarget {
+	return []routeTarget{{name: "iam", service: project.Name + "-zitadel", port: 8080}}
+}
+
+// keycloakProvider installs Keycloak backed by the project's PostgreSQL
+type keycloakProvider struct{ baseProvider }
+
+func (p keycloakProvider) VerifyExternal(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) (edgev1alpha1.ComponentStatus, error) {
+	return r.verifyExternalIdP(ctx, project, ref)
+}
+
+func (p keycloakProvider) PreInstall(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	return r.reconcileKeycloak(ctx, project, p.Name(), ref)
+}
+
+func (p keycloakProvider) Endpoint(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string {
+	return keycloakIssuerURL(project, ref.Release.ValuesContent)
+}
+
+func (p keycloakProvider) Secrets(project *edgev1alpha1.Project) []string {
+	return []string{project.Name + "-keycloak-admin", project.Name + "-pguser-keycloak"}
+}
+
+func (p keycloakProvider) Routes(project *edgev1alpha1.Project) []routeTarget {
+	return []routeTarget{{name: "keycloak", service: project.Name + "-keycloak", port: 80}}
+}
+
+// postgrestProvider installs PostgREST as the project's API layer
+type postgrestProvider struct{ baseProvider }
+
+func (p postgrestProvider) PreInstall(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	return r.reconcilePostgREST(ctx, project, p.Name(), ref)
+}
+
+func (p postgrestProvider) Endpoint(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string {
+	return fmt.Sprintf("http://%s-postgrest.%s.svc.cluster.local", project.Name, project.Namespace)
+}
+
+func (p postgrestProvider) Secrets(project *edgev1alpha1.Project) []string {
+	return []string{fmt.Sprintf("%s-pguser-%s", project.Name, postgrestAuthenticatorRole)}
+}
+
+func (p postgrestProvider) Routes(project *edgev1alpha1.Project) []routeTarget {
+	return []routeTarget{{name: "api", service: project.Name + "-postgrest", port: 80}}
+}
+
+// storageProvider installs MinIO or SeaweedFS and manages the project's buckets on it
+type storageProvider struct{ baseProvider }
+
+func (p storageProvider) VerifyExternal(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) (edgev1alpha1.ComponentStatus, error) {
+	return r.verifyExternalStorageSecret(ctx, project, ref)
+}
+
+func (p storageProvider) PreInstall(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	return r.prepareStorage(ctx, project, p.Name(), ref)
+}
+
+func (p storageProvider) Endpoint(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string {
+	if p.Name() == "seaweedfs" {
+		return fmt.Sprintf("http://%s-seaweedfs-s3.%s.svc.cluster.local:8333", project.Name, project.Namespace)
+	}
+	return fmt.Sprintf("http://%s-%s.%s.svc.cluster.local:9000", project.Name, p.Name(), project.Namespace)
+}
+
+func (p storageProvider) Secrets(project *edgev1alpha1.Project) []string {
+	return []string{storageRootSecretName(project, p.Name()), project.Name + "-s3"}
+}
+
+// Routes exposes the S3 API and the web console, MinIO's console or SeaweedFS's filer UI
+func (p storageProvider) Routes(project *edgev1alpha1.Project) []routeTarget {
+	if p.Name() == "seaweedfs" {
+		return []routeTarget{
+			{name: "s3", service: project.Name + "-seaweedfs-s3", port: 8333},
+			{name: "console", service: project.Name + "-seaweedfs-filer", port: 8888},
+		}
+	}
+	return []routeTarget{
+		{name: "s3", service: fmt.Sprintf("%s-%s", project.Name, p.Name()), port: 9000},
+		{name: "console", service: fmt.Sprintf("%s-%s", project.Name, p.Name()), port: 9001},
+	}
+}
+
+func (p storageProvider) PostReady(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	return r.publishStorage(ctx, project, p.Name(), ref)
+}
+
+// pgoProvider installs PGO for the project's REST API and change data capture pipelines
+type pgoProvider struct{ baseProvider }
+
+func (p pgoProvider) PreInstall(ctx context.Context, r *ProjectReconciler, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	return r.reconcilePGO(ctx, project, p.Name(), ref)
+}
+
+func (p pgoProvider) Endpoint(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string {
+	return fmt.Sprintf("http://%s-pgo.%s.svc.cluster.local:8001", project.Name, project.Namespace)
+}
+
+func (p pgoProvider) Secrets(project *edgev1alpha1.Project) []string {
+	return []string{fmt.Sprintf("%s-pguser-%s", project.Name, pgoRole)}
+}
+
+func (p pgoProvider) Routes(project *edgev1alpha1.Project) []routeTarget {
+	return []routeTarget{{name: "pubsub", service: project.Name + "-pgo", port: 8001}}
+}
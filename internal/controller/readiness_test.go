@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+func TestUpdateReadiness(t *testing.T) {
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+	project.Spec.Database = &edgev1alpha1.Database{Postgres: &edgev1alpha1.ComponentRef{}}
+	project.Spec.Auth = &edgev1alpha1.Auth{Zitadel: &edgev1alpha1.ComponentRef{}}
+	project.Spec.API = &edgev1alpha1.API{PostgREST: &edgev1alpha1.ComponentRef{}}
+	// PostgREST hasn't reported yet
+	project.Status.Conditions = []metav1.Condition{
+		{Type: "PostgresReady", Status: metav1.ConditionTrue, Reason: common.ReasonReady},
+		{Type: "ZitadelReady", Status: metav1.ConditionFalse, Reason: common.ReasonComponentError},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := edgev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	r := &ProjectReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(project).WithStatusSubresource(project).Build(),
+	}
+
+	ready, err := r.updateReadiness(context.Background(), project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ready || project.Status.ReadyComponents != 1 || project.Status.TotalComponents != 3 {
+		t.Errorf("got ready %v with %d of %d components", ready, project.Status.ReadyComponents,
+			project.Status.TotalComponents)
+	}
+
+	want := map[string]metav1.Condition{
+		common.ConditionTypeReady: {Status: metav1.ConditionFalse, Reason: common.ReasonComponentsDegraded,
+			Message: "1 of 3 components ready"},
+		common.ConditionTypeProgressing: {Status: metav1.ConditionTrue, Reason: common.ReasonComponentsProgressing,
+			Message: "Waiting for postgrest"},
+		common.ConditionTypeDegraded: {Status: metav1.ConditionTrue, Reason: common.ReasonComponentsDegraded,
+			Message: "Failing: zitadel"},
+	}
+	for condType, w := range want {
+		cond := meta.FindStatusCondition(project.Status.Conditions, condType)
+		if cond == nil || cond.Status != w.Status || cond.Reason != w.Reason || cond.Message != w.Message {
+			t.Errorf("got %s condition %+v, want %+v", condType, cond, w)
+		}
+	}
+
+	// Ready once every component reported ready
+	meta.SetStatusCondition(&project.Status.Conditions,
+		metav1.Condition{Type: "ZitadelReady", Status: metav1.ConditionTrue, Reason: common.ReasonReady})
+	meta.SetStatusCondition(&project.Status.Conditions,
+		metav1.Condition{Type: "PostgrestReady", Status: metav1.ConditionTrue, Reason: common.ReasonReady})
+	if ready, err := r.updateReadiness(context.Background(), project); err != nil || !ready {
+		t.Errorf("got ready %v, %v", ready, err)
+	}
+	if !meta.IsStatusConditionFalse(project.Status.Conditions, common.ConditionTypeDegraded) {
+		t.Error("still degraded")
+	}
+}
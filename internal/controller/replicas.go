@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/pgo/pkg/pgx/role"
+	"github.com/jackc/pgx/v5"
+)
+
+// readonlyRole reads the tables of the project's database, through the read replicas if the
+// built-in PostgreSQL has any
+const readonlyRole = "readonly"
+
+// replicaStatusInterval is the least time between queries of the replicas. Their lag changes with
+// every write, reporting it on every reconciliation would trigger the next one
+const replicaStatusInterval = time.Minute
+
+func readonlySecretName(project *edgev1alpha1.Project) string {
+	return project.Name + "-pguser-readonly"
+}
+
+// reconcileReadonlyRole ensures the readonly role of the built-in PostgreSQL and its
+// <project>-pguser-readonly connection secret pointing at the read replicas' service, if any. The role may
+// only read the tables of the public schema of the project's database, also those created later
+// by the superuser, the migrations' role and the declared login roles, and its transactions are
+// read-only.
+func (r *ProjectReconciler) reconcileReadonlyRole(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	if ref.IsExternal() {
+		return nil
+	}
+
+	// Without replicas the secret points at the primary, as the superuser's does
+	host := r.postgresReadHost(project, ref)
+	readonly := role.Role{Name: readonlyRole, CanLogin: true, Inherit: true, ConnLimit: 100}
+	if err := r.ensurePostgresRoleSecretAt(ctx, project, readonlySecretName(project), host, readonly,
+		projectDatabase, false); err != nil {
+		return fmt.Errorf("failed to ensure the readonly role: %w", err)
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	pool, err := r.connectPostgresAsSuperuser(dbCtx, project, projectDatabase)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database %s: %w", projectDatabase, err)
+	}
+	defer pool.Close()
+
+	// Roles yet to be created, e.g. a migrations role declared elsewhere, are skipped
+	rows, err := pool.Query(dbCtx, "SELECT rolname FROM pg_roles WHERE rolname = ANY($1) ORDER BY rolname",
+		readonlyOwners(project))
+	if err != nil {
+		return fmt.Errorf("failed to query roles: %w", err)
+	}
+	owners, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to query roles: %w", err)
+	}
+	for _, stmt := range readonlyStatements(owners) {
+		if _, err := pool.Exec(dbCtx, stmt); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// readonlyStatements renders the privileges of the readonly role. Default privileges cover the
+// tables the owners create later.
+func readonlyStatements(owners []string) []string {
+	grantee := pgx.Identifier{readonlyRole}.Sanitize()
+	stmts := []string{
+		fmt.Sprintf("ALTER ROLE %s SET default_transaction_read_only = on", grantee),
+		fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", pgx.Identifier{projectDatabase}.Sanitize(), grantee),
+	}
+	stmts = append(stmts, grantStatements(roleGrant{role: readonlyRole, grant: edgev1alpha1.PostgresGrant{
+		Tables: []string{"*"}, Privileges: []string{"SELECT"},
+	}}, false)...)
+	for _, owner := range owners {
+		stmts = append(stmts, fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA public GRANT SELECT ON TABLES TO %s",
+			pgx.Identifier{owner}.Sanitize(), grantee))
+	}
+	return stmts
+}
+
+// readonlyOwners returns the roles whose future tables the readonly role may read
+func readonlyOwners(project *edgev1alpha1.Project) []string {
+	owners := []string{"postgres"}
+	if migrations := project.Spec.Database.Migrations; migrations != nil && migrations.Role != "" {
+		owners = append(owners, migrations.Role)
+	}
+	for _, declared := range project.Spec.Database.Roles {
+		if declared.Login && !slices.Contains(owners, declared.Name) {
+			owners = append(owners, declared.Name)
+		}
+	}
+	return owners
+}
+
+// reconcileReplicaStatus reports the clients streaming from the built-in PostgreSQL's primary and
+// their lag in the status of the postgres component
+func (r *ProjectReconciler) reconcileReplicaStatus(ctx context.Context, project *edgev1alpha1.Project) error {
+	ref := postgresRef(project)
+	status, ok := project.Status.ComponentStatuses["database-postgres"]
+	if ref == nil || ref.IsExternal() || !ok || !status.Ready {
+		return nil
+	}
+	now := metav1.Now()
+	if status.ReplicasObservedAt != nil && now.Sub(status.ReplicasObservedAt.Time) < replicaStatusInterval {
+		return nil
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	pool, err := r.connectPostgresAsSuperuser(dbCtx, project, "postgres")
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer pool.Close()
+
+	rows, err := pool.Query(dbCtx, `SELECT coalesce(application_name, ''), coalesce(state, ''),
+		coalesce(sync_state, ''), extract(epoch FROM replay_lag)::float8,
+		coalesce(pg_wal_lsn_diff(pg_current_wal_lsn(), replay_lsn), 0)::bigint
+		FROM pg_stat_replication ORDER BY application_name, pid`)
+	if err != nil {
+		return fmt.Errorf("failed to query pg_stat_replication: %w", err)
+	}
+	replicas, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (edgev1alpha1.ReplicaStatus, error) {
+		replica := edgev1alpha1.ReplicaStatus{}
+		var replayLag *float64
+		if err := row.Scan(&replica.Name, &replica.State, &replica.SyncState, &replayLag, &replica.LagBytes); err != nil {
+			return replica, err
+		}
+		if replayLag != nil {
+			replica.ReplayLag = &metav1.Duration{Duration: time.Duration(*replayLag * float64(time.Second)).Round(time.Millisecond)}
+		}
+		return replica, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query pg_stat_replication: %w", err)
+	}
+	if len(replicas) == 0 {
+		replicas = nil
+	}
+
+	patch := client.MergeFrom(project.DeepCopy())
+	status.Replicas = replicas
+	status.ReplicasObservedAt = &now
+	project.Status.ComponentStatuses["database-postgres"] = status
+	return r.patchStatus(ctx, project, patch)
+}
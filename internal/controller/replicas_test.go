@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestReadonlyStatements(t *testing.T) {
+	stmts := readonlyStatements([]string{"postgres", "app"})
+	for _, want := range []string{
+		`ALTER ROLE "readonly" SET default_transaction_read_only = on`,
+		`GRANT CONNECT ON DATABASE "main" TO "readonly"`,
+		`GRANT USAGE ON SCHEMA "public" TO "readonly"`,
+		`GRANT SELECT ON ALL TABLES IN SCHEMA "public" TO "readonly"`,
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "postgres" IN SCHEMA public GRANT SELECT ON TABLES TO "readonly"`,
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "app" IN SCHEMA public GRANT SELECT ON TABLES TO "readonly"`,
+	} {
+		if !slices.Contains(stmts, want) {
+			t.Errorf("statements miss %q:\n%q", want, stmts)
+		}
+	}
+	for _, stmt := range stmts {
+		for _, privilege := range []string{"INSERT", "UPDATE", "DELETE", "TRUNCATE"} {
+			if strings.Contains(stmt, privilege) {
+				t.Errorf("readonly role granted %s: %s", privilege, stmt)
+			}
+		}
+	}
+}
+
+func TestReadonlyOwners(t *testing.T) {
+	project := &edgev1alpha1.Project{Spec: edgev1alpha1.ProjectSpec{Database: &edgev1alpha1.Database{
+		Migrations: &edgev1alpha1.Migrations{Role: "app"},
+		Roles: []edgev1alpha1.PostgresRole{
+			{Name: "app", Login: true},
+			{Name: "reporting"},
+			{Name: "etl", Login: true},
+		},
+	}}}
+	if got := readonlyOwners(project); !slices.Equal(got, []string{"postgres", "app", "etl"}) {
+		t.Errorf("unexpected owners %v", got)
+	}
+}
+
+func TestReplicaStatusInterval(t *testing.T) {
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "acme", "default"
+	project.Spec.Database = &edgev1alpha1.Database{}
+	observed := metav1.NewTime(time.Now().Add(-30 * time.Second))
+	project.Status.ComponentStatuses = map[string]edgev1alpha1.ComponentStatus{
+		"database-postgres": {Ready: true, ReplicasObservedAt: &observed},
+	}
+	r := &ProjectReconciler{Client: fake.NewClientBuilder().Build()}
+
+	if err := r.reconcileReplicaStatus(context.Background(), project); err != nil {
+		t.Errorf("replicas queried within the interval: %v", err)
+	}
+
+	// Once the interval elapsed, the primary is queried as the superuser
+	observed = metav1.NewTime(time.Now().Add(-2 * replicaStatusInterval))
+	status := project.Status.ComponentStatuses["database-postgres"]
+	status.ReplicasObservedAt = &observed
+	project.Status.ComponentStatuses["database-postgres"] = status
+	if err := r.reconcileReplicaStatus(context.Background(), project); err == nil ||
+		!strings.Contains(err.Error(), "acme-pguser-postgres") {
+		t.Errorf("replicas not queried once the interval elapsed, got %v", err)
+	}
+}
@@ -0,0 +1,491 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+	"github.com/edgeflare/pgo/pkg/pgx/role"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// grantPrivileges are the privileges a declared grant may list
+var grantPrivileges = []string{"ALL", "CREATE", "DELETE", "INSERT", "REFERENCES", "SELECT", "TRIGGER",
+	"TRUNCATE", "UPDATE", "USAGE"}
+
+// roleGrant is a declared grant of a role, keyed for comparison with the recorded ones
+type roleGrant struct {
+	role  string
+	grant edgev1alpha1.PostgresGrant
+}
+
+// reconcileDatabaseObjects reconciles the roles and databases declared in spec.database in the
+// project's built-in PostgreSQL: it creates or alters the roles, issues the connection secrets of
+// login roles, creates the databases and grants the memberships and privileges. Those removed
+// from the spec since the last reconciliation are revoked or dropped per the removal policy.
+func (r *ProjectReconciler) reconcileDatabaseObjects(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	if ref.IsExternal() || project.Spec.Database == nil {
+		return nil
+	}
+	spec := project.Spec.Database
+	recorded := project.Status.DatabaseObjects
+	if len(spec.Roles) == 0 && len(spec.Databases) == 0 && recorded == nil {
+		return nil
+	}
+
+	err := r.applyDatabaseObjects(ctx, project, ref)
+	if err != nil {
+		r.event(project, corev1.EventTypeWarning, common.ReasonRoleError, "%v", err)
+	}
+	return err
+}
+
+func (r *ProjectReconciler) applyDatabaseObjects(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) error {
+	logger := log.FromContext(ctx)
+	spec := project.Spec.Database
+	recorded := project.Status.DatabaseObjects
+	if recorded == nil {
+		recorded = &edgev1alpha1.DatabaseObjectsStatus{}
+	}
+	if err := validateDatabaseObjects(project, ref); err != nil {
+		return err
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	pool, err := r.connectPostgresAsSuperuser(dbCtx, project, "postgres")
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer pool.Close()
+
+	// 1. Create or alter the roles, login roles along with their connection secrets
+	for _, declared := range spec.Roles {
+		if err := r.ensureDeclaredRole(dbCtx, project, pool, declared); err != nil {
+			return err
+		}
+	}
+
+	// 2. Create the databases and set their owners
+	for _, database := range spec.Databases {
+		owner := database.Owner
+		if err := ensurePostgresDatabase(dbCtx, pool, database.Name, owner, owner != ""); err != nil {
+			return err
+		}
+		if owner != "" {
+			stmt := fmt.Sprintf("ALTER DATABASE %s OWNER TO %s", pgx.Identifier{database.Name}.Sanitize(),
+				pgx.Identifier{owner}.Sanitize())
+			if _, err := pool.Exec(dbCtx, stmt); err != nil {
+				return fmt.Errorf("failed to set the owner of database %s: %w", database.Name, err)
+			}
+		}
+	}
+
+	// 3. Grant exactly the declared memberships
+	for _, declared := range spec.Roles {
+		if err := reconcileMemberships(dbCtx, pool, declared.Name, declared.MemberOf); err != nil {
+			return err
+		}
+	}
+
+	// 4. Grant the declared privileges, revoke the recorded ones removed from the spec
+	policy := spec.GetRemovalPolicy()
+	grants := declaredGrants(spec.Roles)
+	revoked := []roleGrant{}
+	for _, g := range recordedGrants(recorded) {
+		if policy == edgev1alpha1.RemovalPolicyRetain || slices.ContainsFunc(grants, g.equal) {
+			continue
+		}
+		// DROP OWNED revokes the privileges of dropped roles
+		if policy == edgev1alpha1.RemovalPolicyDrop && !slices.ContainsFunc(spec.Roles,
+			func(declared edgev1alpha1.PostgresRole) bool { return declared.Name == g.role }) {
+			continue
+		}
+		revoked = append(revoked, g)
+	}
+	if err := r.applyGrants(dbCtx, project, grants, revoked); err != nil {
+		return err
+	}
+
+	// 5. Revoke or drop the removed databases, then the removed roles
+	for _, name := range recorded.Databases {
+		if slices.ContainsFunc(spec.Databases, func(d edgev1alpha1.PostgresDatabase) bool { return d.Name == name }) {
+			continue
+		}
+		if err := removeDatabase(dbCtx, pool, name, policy); err != nil {
+			return err
+		}
+		logger.Info("Removed database", "name", name, "policy", policy)
+	}
+	for _, name := range recorded.Roles {
+		if slices.ContainsFunc(spec.Roles, func(declared edgev1alpha1.PostgresRole) bool { return declared.Name == name }) {
+			continue
+		}
+		if err := r.removeRole(dbCtx, project, pool, name, policy); err != nil {
+			return err
+		}
+	}
+
+	// 6. Record the reconciled objects
+	status := &edgev1alpha1.DatabaseObjectsStatus{}
+	for _, declared := range spec.Roles {
+		status.Roles = append(status.Roles, declared.Name)
+	}
+	for _, database := range spec.Databases {
+		status.Databases = append(status.Databases, database.Name)
+	}
+	for _, g := range grants {
+		status.Grants = append(status.Grants, edgev1alpha1.RoleGrantStatus{Role: g.role, PostgresGrant: g.grant})
+	}
+	if len(status.Roles) == 0 && len(status.Databases) == 0 {
+		status = nil
+	}
+	if equality.Semantic.DeepEqual(project.Status.DatabaseObjects, status) {
+		return nil
+	}
+	patch := client.MergeFrom(project.DeepCopy())
+	project.Status.DatabaseObjects = status
+	return r.patchStatus(ctx, project, patch)
+}
+
+// validateDatabaseObjects rejects declared roles the controller or PostgreSQL reserve and unknown
+// privileges
+func validateDatabaseObjects(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) error {
+	reserved := []string{"postgres", postgresReplicationUser(project, ref), "zitadel", keycloakPostgresRole,
+		postgrestAuthenticatorRole, postgrestAnonRole, postgrestAuthnRole, pgoRole, readonlyRole}
+	for _, declared := range project.Spec.Database.Roles {
+		if slices.Contains(reserved, declared.Name) || strings.HasPrefix(declared.Name, "pg_") {
+			return fmt.Errorf("role %s is reserved", declared.Name)
+		}
+		for _, grant := range declared.Grants {
+			for _, privilege := range grant.Privileges {
+				if !slices.Contains(grantPrivileges, strings.ToUpper(privilege)) {
+					return fmt.Errorf("role %s: unknown privilege %q", declared.Name, privilege)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ensureDeclaredRole creates or alters a declared role. A login role gets a connection secret,
+// the secret of a role that no longer logs in is deleted.
+func (r *ProjectReconciler) ensureDeclaredRole(ctx context.Context, project *edgev1alpha1.Project,
+	pool *pgxpool.Pool, declared edgev1alpha1.PostgresRole) error {
+	pgRole := role.Role{
+		Name:        declared.Name,
+		CanLogin:    declared.Login,
+		Inherit:     declared.Inherit,
+		CreateDB:    declared.CreateDB,
+		CreateRole:  declared.CreateRole,
+		Replication: declared.Replication,
+		ConnLimit:   int(declared.ConnectionLimit),
+	}
+
+	secretName := declaredRoleSecretName(project, declared.Name)
+	if declared.Login {
+		database := declared.Database
+		if database == "" {
+			database = projectDatabase
+		}
+		if err := r.ensurePostgresRoleSecret(ctx, project, secretName, pgRole, database, false); err != nil {
+			return err
+		}
+	} else {
+		if _, err := role.Get(ctx, pool, declared.Name); err == role.ErrRoleNotFound {
+			if err := role.Create(ctx, pool, pgRole); err != nil {
+				return fmt.Errorf("failed to create PostgreSQL role %s: %w", declared.Name, err)
+			}
+			r.event(project, corev1.EventTypeNormal, common.ReasonRoleCreated, "Created PostgreSQL role %s", declared.Name)
+		} else if err != nil {
+			return fmt.Errorf("error checking for existing PostgreSQL role: %w", err)
+		} else if err := role.Update(ctx, pool, pgRole); err != nil {
+			return fmt.Errorf("failed to update PostgreSQL role %s: %w", declared.Name, err)
+		}
+		if err := r.deleteRoleSecret(ctx, project, secretName); err != nil {
+			return err
+		}
+	}
+
+	// role.Update only sets the attributes a role has, clear the others
+	if _, err := pool.Exec(ctx, fmt.Sprintf("ALTER ROLE %s%s", pgx.Identifier{declared.Name}.Sanitize(),
+		clearedRoleAttributes(pgRole))); err != nil {
+		return fmt.Errorf("failed to update PostgreSQL role %s: %w", declared.Name, err)
+	}
+	return nil
+}
+
+// clearedRoleAttributes renders the NO* options of the attributes the role doesn't have
+func clearedRoleAttributes(pgRole role.Role) string {
+	var builder strings.Builder
+	for _, attr := range []struct {
+		set  bool
+		name string
+	}{
+		{pgRole.CanLogin, "LOGIN"},
+		{pgRole.Inherit, "INHERIT"},
+		{pgRole.CreateDB, "CREATEDB"},
+		{pgRole.CreateRole, "CREATEROLE"},
+		{pgRole.Replication, "REPLICATION"},
+	} {
+		if !attr.set {
+			builder.WriteString(" NO" + attr.name)
+		}
+	}
+	return builder.String()
+}
+
+// reconcileMemberships grants the role membership in memberOf and revokes its other memberships
+func reconcileMemberships(ctx context.Context, pool *pgxpool.Pool, name string, memberOf []string) error {
+	rows, err := pool.Query(ctx, `SELECT r.rolname FROM pg_auth_members m
+		JOIN pg_roles r ON r.oid = m.roleid JOIN pg_roles u ON u.oid = m.member WHERE u.rolname = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to query the memberships of role %s: %w", name, err)
+	}
+	current, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to query the memberships of role %s: %w", name, err)
+	}
+
+	member := pgx.Identifier{name}.Sanitize()
+	for _, group := range memberOf {
+		if slices.Contains(current, group) {
+			continue
+		}
+		if _, err := pool.Exec(ctx, fmt.Sprintf("GRANT %s TO %s", pgx.Identifier{group}.Sanitize(), member)); err != nil {
+			return fmt.Errorf("failed to grant role %s to %s: %w", group, name, err)
+		}
+	}
+	for _, group := range current {
+		if slices.Contains(memberOf, group) {
+			continue
+		}
+		if _, err := pool.Exec(ctx, fmt.Sprintf("REVOKE %s FROM %s", pgx.Identifier{group}.Sanitize(), member)); err != nil {
+			return fmt.Errorf("failed to revoke role %s from %s: %w", group, name, err)
+		}
+	}
+	return nil
+}
+
+// applyGrants grants and revokes privileges, connected to the database of each grant
+func (r *ProjectReconciler) applyGrants(ctx context.Context, project *edgev1alpha1.Project,
+	grants, revoked []roleGrant) error {
+	statements := map[string][]string{}
+	databases := []string{}
+	add := func(database, stmt string) {
+		if _, ok := statements[database]; !ok {
+			databases = append(databases, database)
+		}
+		statements[database] = append(statements[database], stmt)
+	}
+	for _, g := range grants {
+		for _, stmt := range grantStatements(g, false) {
+			add(g.database(), stmt)
+		}
+	}
+	for _, g := range revoked {
+		for _, stmt := range grantStatements(g, true) {
+			add(g.database(), stmt)
+		}
+	}
+
+	for _, database := range databases {
+		pool, err := r.connectPostgresAsSuperuser(ctx, project, database)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database %s: %w", database, err)
+		}
+		for _, stmt := range statements[database] {
+			if _, err := pool.Exec(ctx, stmt); err != nil {
+				pool.Close()
+				return fmt.Errorf("failed to execute %q: %w", stmt, err)
+			}
+		}
+		pool.Close()
+	}
+	return nil
+}
+
+// grantStatements renders the GRANT statements of g, or the REVOKE statements undoing them.
+// USAGE on the schema granted along with table privileges isn't revoked, other grants may rely
+// on it.
+func grantStatements(g roleGrant, revoke bool) []string {
+	privileges := make([]string, 0, len(g.grant.Privileges))
+	for _, privilege := range g.grant.Privileges {
+		privileges = append(privileges, strings.ToUpper(privilege))
+	}
+	schema := pgx.Identifier{g.schema()}.Sanitize()
+	grantee := pgx.Identifier{g.role}.Sanitize()
+
+	var target string
+	statements := []string{}
+	switch {
+	case len(g.grant.Tables) == 0:
+		target = "SCHEMA " + schema
+	case slices.Contains(g.grant.Tables, "*"):
+		target = "ALL TABLES IN SCHEMA " + schema
+	default:
+		tables := make([]string, 0, len(g.grant.Tables))
+		for _, table := range g.grant.Tables {
+			tables = append(tables, pgx.Identifier{g.schema(), table}.Sanitize())
+		}
+		target = "TABLE " + strings.Join(tables, ", ")
+	}
+	if len(g.grant.Tables) > 0 && !revoke {
+		statements = append(statements, fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", schema, grantee))
+	}
+
+	if revoke {
+		return append(statements, fmt.Sprintf("REVOKE %s ON %s FROM %s", strings.Join(privileges, ", "), target, grantee))
+	}
+	return append(statements, fmt.Sprintf("GRANT %s ON %s TO %s", strings.Join(privileges, ", "), target, grantee))
+}
+
+// removeDatabase revokes connecting to a removed database or drops it
+func removeDatabase(ctx context.Context, pool *pgxpool.Pool, name string, policy edgev1alpha1.RemovalPolicy) error {
+	var stmt string
+	switch policy {
+	case edgev1alpha1.RemovalPolicyRevoke:
+		stmt = fmt.Sprintf("REVOKE CONNECT ON DATABASE %s FROM PUBLIC", pgx.Identifier{name}.Sanitize())
+	case edgev1alpha1.RemovalPolicyDrop:
+		stmt = fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", pgx.Identifier{name}.Sanitize())
+	default:
+		return nil
+	}
+	if _, err := pool.Exec(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to remove database %s: %w", name, err)
+	}
+	return nil
+}
+
+// removeRole disables a removed role and deletes its connection secret, or drops it along with
+// the objects it owns in every database
+func (r *ProjectReconciler) removeRole(ctx context.Context, project *edgev1alpha1.Project, pool *pgxpool.Pool,
+	name string, policy edgev1alpha1.RemovalPolicy) error {
+	logger := log.FromContext(ctx)
+	if policy == edgev1alpha1.RemovalPolicyRetain {
+		return nil
+	}
+	if _, err := role.Get(ctx, pool, name); err == role.ErrRoleNotFound {
+		return r.deleteRoleSecret(ctx, project, declaredRoleSecretName(project, name))
+	} else if err != nil {
+		return fmt.Errorf("error checking for existing PostgreSQL role: %w", err)
+	}
+
+	switch policy {
+	case edgev1alpha1.RemovalPolicyRevoke:
+		if _, err := pool.Exec(ctx, fmt.Sprintf("ALTER ROLE %s NOLOGIN", pgx.Identifier{name}.Sanitize())); err != nil {
+			return fmt.Errorf("failed to disable role %s: %w", name, err)
+		}
+		if err := reconcileMemberships(ctx, pool, name, nil); err != nil {
+			return err
+		}
+		logger.Info("Revoked PostgreSQL role", "name", name)
+		r.event(project, corev1.EventTypeNormal, common.ReasonRoleRevoked, "Revoked PostgreSQL role %s", name)
+	case edgev1alpha1.RemovalPolicyDrop:
+		rows, err := pool.Query(ctx, "SELECT datname FROM pg_database WHERE datallowconn AND NOT datistemplate")
+		if err != nil {
+			return fmt.Errorf("failed to list databases: %w", err)
+		}
+		databases, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return fmt.Errorf("failed to list databases: %w", err)
+		}
+		stmt := fmt.Sprintf("DROP OWNED BY %s", pgx.Identifier{name}.Sanitize())
+		for _, database := range databases {
+			dbPool, err := r.connectPostgresAsSuperuser(ctx, project, database)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database %s: %w", database, err)
+			}
+			_, err = dbPool.Exec(ctx, stmt)
+			dbPool.Close()
+			if err != nil {
+				return fmt.Errorf("failed to drop the objects of role %s in %s: %w", name, database, err)
+			}
+		}
+		if err := role.Delete(ctx, pool, name); err != nil {
+			return fmt.Errorf("failed to drop role %s: %w", name, err)
+		}
+		logger.Info("Dropped PostgreSQL role", "name", name)
+		r.event(project, corev1.EventTypeNormal, common.ReasonRoleDropped, "Dropped PostgreSQL role %s", name)
+	}
+	return r.deleteRoleSecret(ctx, project, declaredRoleSecretName(project, name))
+}
+
+// deleteRoleSecret deletes the connection secret of a declared role if the project generated it
+func (r *ProjectReconciler) deleteRoleSecret(ctx context.Context, project *edgev1alpha1.Project, name string) error {
+	secret, err := r.rotatableSecret(ctx, project, name)
+	if err != nil || secret == nil {
+		return err
+	}
+	if err := r.Delete(ctx, secret); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete secret %s: %w", name, err)
+	}
+	return nil
+}
+
+func declaredRoleSecretName(project *edgev1alpha1.Project, name string) string {
+	return fmt.Sprintf("%s-pguser-%s", project.Name, strings.ReplaceAll(name, "_", "-"))
+}
+
+// declaredLoginRoleSecrets returns the names of the connection secrets of the declared login roles
+func declaredLoginRoleSecrets(project *edgev1alpha1.Project) []string {
+	if project.Spec.Database == nil {
+		return nil
+	}
+	secrets := []string{}
+	for _, declared := range project.Spec.Database.Roles {
+		if declared.Login {
+			secrets = append(secrets, declaredRoleSecretName(project, declared.Name))
+		}
+	}
+	return secrets
+}
+
+func declaredGrants(roles []edgev1alpha1.PostgresRole) []roleGrant {
+	grants := []roleGrant{}
+	for _, declared := range roles {
+		for _, grant := range declared.Grants {
+			grants = append(grants, roleGrant{role: declared.Name, grant: grant})
+		}
+	}
+	return grants
+}
+
+func recordedGrants(status *edgev1alpha1.DatabaseObjectsStatus) []roleGrant {
+	grants := []roleGrant{}
+	for _, g := range status.Grants {
+		grants = append(grants, roleGrant{role: g.Role, grant: g.PostgresGrant})
+	}
+	return grants
+}
+
+func (g roleGrant) equal(other roleGrant) bool {
+	return g.role == other.role && equality.Semantic.DeepEqual(g.grant, other.grant)
+}
+
+func (g roleGrant) database() string {
+	if g.grant.Database == "" {
+		return projectDatabase
+	}
+	return g.grant.Database
+}
+
+func (g roleGrant) schema() string {
+	if g.grant.Schema == "" {
+		return "public"
+	}
+	return g.grant.Schema
+}
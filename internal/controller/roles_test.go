@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/pgo/pkg/pgx/role"
+)
+
+func TestGrantStatements(t *testing.T) {
+	g := roleGrant{role: "reporting", grant: edgev1alpha1.PostgresGrant{
+		Schema: "sales", Tables: []string{"orders", "customers"}, Privileges: []string{"select"},
+	}}
+	want := []string{
+		`GRANT USAGE ON SCHEMA "sales" TO "reporting"`,
+		`GRANT SELECT ON TABLE "sales"."orders", "sales"."customers" TO "reporting"`,
+	}
+	if got := grantStatements(g, false); !slices.Equal(got, want) {
+		t.Errorf("unexpected grants %q", got)
+	}
+	if got := grantStatements(g, true); !slices.Equal(got,
+		[]string{`REVOKE SELECT ON TABLE "sales"."orders", "sales"."customers" FROM "reporting"`}) {
+		t.Errorf("unexpected revokes %q", got)
+	}
+
+	g.grant = edgev1alpha1.PostgresGrant{Tables: []string{"*"}, Privileges: []string{"SELECT", "INSERT"}}
+	if got := grantStatements(g, false); got[1] != `GRANT SELECT, INSERT ON ALL TABLES IN SCHEMA "public" TO "reporting"` {
+		t.Errorf("unexpected grant on all tables %q", got)
+	}
+	if g.database() != projectDatabase {
+		t.Errorf("grant defaulted to database %s", g.database())
+	}
+
+	g.grant = edgev1alpha1.PostgresGrant{Schema: "app", Privileges: []string{"USAGE", "CREATE"}}
+	if got := grantStatements(g, false); !slices.Equal(got, []string{`GRANT USAGE, CREATE ON SCHEMA "app" TO "reporting"`}) {
+		t.Errorf("unexpected schema grant %q", got)
+	}
+}
+
+func TestClearedRoleAttributes(t *testing.T) {
+	if got := clearedRoleAttributes(role.Role{CanLogin: true, Inherit: true}); got != " NOCREATEDB NOCREATEROLE NOREPLICATION" {
+		t.Errorf("unexpected cleared attributes %q", got)
+	}
+}
+
+func TestValidateDatabaseObjects(t *testing.T) {
+	project := &edgev1alpha1.Project{}
+	project.Name = "demo"
+	project.Spec.Database = &edgev1alpha1.Database{Roles: []edgev1alpha1.PostgresRole{{Name: "app", Login: true}}}
+	ref := &edgev1alpha1.ComponentRef{}
+
+	if err := validateDatabaseObjects(project, ref); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"postgres", "repl_user", "authenticator", "readonly", "pg_monitor"} {
+		project.Spec.Database.Roles[0].Name = name
+		if err := validateDatabaseObjects(project, ref); err == nil {
+			t.Errorf("reserved role %s accepted", name)
+		}
+	}
+
+	project.Spec.Database.Roles[0] = edgev1alpha1.PostgresRole{Name: "app", Grants: []edgev1alpha1.PostgresGrant{
+		{Privileges: []string{"SELECT; DROP TABLE x"}},
+	}}
+	if err := validateDatabaseObjects(project, ref); err == nil {
+		t.Error("unknown privilege accepted")
+	}
+
+	if name := declaredRoleSecretName(project, "app_user"); name != "demo-pguser-app-user" {
+		t.Errorf("unexpected secret name %s", name)
+	}
+}
@@ -0,0 +1,312 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+	"github.com/edgeflare/pgo/pkg/pgx/role"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const defaultReplUser = "repl_user"
+
+// rotationDue reports whether the generated database credentials are to be rotated: the
+// edgeflare.io/rotate-credentials annotation changed or the rotation interval elapsed since the
+// last rotation, or since the project was created
+func rotationDue(project *edgev1alpha1.Project, now time.Time) bool {
+	status := project.Status.CredentialRotation
+	if request := project.Annotations[common.AnnotationRotateCredentials]; request != "" &&
+		(status == nil || status.LastRequest != request) {
+		return true
+	}
+
+	if project.Spec.Database == nil || project.Spec.Database.Rotation == nil ||
+		project.Spec.Database.Rotation.Interval == nil || project.Spec.Database.Rotation.Interval.Duration <= 0 {
+		return false
+	}
+	last := project.CreationTimestamp.Time
+	if status != nil {
+		last = status.LastRotated.Time
+	}
+	return !now.Before(last.Add(project.Spec.Database.Rotation.Interval.Duration))
+}
+
+// rotateCredentials replaces the passwords of the roles the controller generated in the project's
+// built-in PostgreSQL once rotation is due: the superuser and replication user if the controller
+// generated the <project>-postgresql secret, and the components' roles. The components whose
+// credentials changed are restarted by restartRotated once their derived secrets are updated.
+func (r *ProjectReconciler) rotateCredentials(ctx context.Context, project *edgev1alpha1.Project) error {
+	if !rotationDue(project, time.Now()) {
+		return nil
+	}
+	ref := postgresRef(project)
+	if ref == nil || ref.IsExternal() || !project.Status.ComponentStatuses["database-postgres"].Ready {
+		// Rotated once the project's PostgreSQL is ready
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	pool, err := r.connectPostgresAsSuperuser(dbCtx, project, "postgres")
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer pool.Close()
+
+	rotated := []string{}
+	for _, p := range Providers() {
+		if p.Name() == "postgres" {
+			continue
+		}
+		if ref := p.ComponentRef(&project.Spec); ref == nil || ref.IsExternal() {
+			continue
+		}
+		for _, secretName := range p.Secrets(project) {
+			if !strings.HasPrefix(secretName, project.Name+"-pguser-") {
+				continue
+			}
+			ok, err := r.rotateRoleSecret(ctx, dbCtx, project, pool, secretName)
+			if err != nil {
+				return err
+			}
+			if ok && !slices.Contains(rotated, p.Name()) {
+				rotated = append(rotated, p.Name())
+			}
+		}
+	}
+
+	// The workloads using the readonly and declared login roles aren't known, their secrets are
+	// only updated
+	for _, secretName := range append([]string{readonlySecretName(project)}, declaredLoginRoleSecrets(project)...) {
+		if _, err := r.rotateRoleSecret(ctx, dbCtx, project, pool, secretName); err != nil {
+			return err
+		}
+	}
+
+	// The superuser is rotated last, the pool opens its connections with the superuser's old password
+	authSecret, err := r.rotatableSecret(ctx, project, project.Name+"-postgresql")
+	if err != nil {
+		return err
+	}
+	if authSecret != nil {
+		passwords := map[string]string{"postgres": newAlphaNumericPassword(16)}
+		data := map[string][]byte{"postgres-password": []byte(passwords["postgres"])}
+
+		replUser := postgresReplicationUser(project, ref)
+		if _, err := role.Get(dbCtx, pool, replUser); err == nil {
+			passwords[replUser] = newAlphaNumericPassword(16)
+			data["replication-password"] = []byte(passwords[replUser])
+		} else if err != role.ErrRoleNotFound {
+			return fmt.Errorf("error checking for PostgreSQL role %s: %w", replUser, err)
+		}
+
+		// Both roles are altered or neither
+		if err := r.rotateAuthSecret(ctx, authSecret, data, func() error {
+			return pgx.BeginFunc(dbCtx, pool, func(tx pgx.Tx) error {
+				for _, name := range slices.Sorted(maps.Keys(passwords)) {
+					if _, err := tx.Exec(dbCtx, rolePasswordStatement(name, passwords[name])); err != nil {
+						return fmt.Errorf("failed to rotate the password of PostgreSQL role %s: %w", name, err)
+					}
+				}
+				return nil
+			})
+		}); err != nil {
+			return err
+		}
+		rotated = append(rotated, "postgres")
+	}
+
+	logger.Info("Rotated database credentials", "components", rotated)
+
+	patch := client.MergeFrom(project.DeepCopy())
+	project.Status.CredentialRotation = &edgev1alpha1.CredentialRotationStatus{
+		LastRotated:     metav1.Now(),
+		LastRequest:     project.Annotations[common.AnnotationRotateCredentials],
+		PendingRestarts: rotated,
+	}
+	if err := r.patchStatus(ctx, project, patch); err != nil {
+		return err
+	}
+	r.event(project, corev1.EventTypeNormal, common.ReasonCredentialsRotated,
+		"Rotated database credentials of %s", strings.Join(rotated, ", "))
+	return nil
+}
+
+// rotateAuthSecret writes data to the <project>-postgresql secret before alter sets the new
+// passwords in the database, as a password only set in the database would be lost if writing the
+// secret failed, locking the controller out. The secret is put back if alter fails. The superuser
+// secret is derived from it when the postgres component is reconciled.
+func (r *ProjectReconciler) rotateAuthSecret(ctx context.Context, authSecret *corev1.Secret,
+	data map[string][]byte, alter func() error) error {
+	previous := maps.Clone(authSecret.Data)
+	maps.Copy(authSecret.Data, data)
+	if err := r.Update(ctx, authSecret); err != nil {
+		return fmt.Errorf("failed to update secret %s: %w", authSecret.Name, err)
+	}
+
+	if err := alter(); err != nil {
+		authSecret.Data = previous
+		if revertErr := r.Update(ctx, authSecret); revertErr != nil {
+			return fmt.Errorf("%w, and failed to restore secret %s: %w", err, authSecret.Name, revertErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// rotateRoleSecret replaces the password of the role in a generated connection secret. It reports
+// whether the secret was rotated. The secret is written first, the role's password is set from it
+// when its component is reconciled if altering the role fails.
+func (r *ProjectReconciler) rotateRoleSecret(ctx, dbCtx context.Context, project *edgev1alpha1.Project,
+	pool *pgxpool.Pool, secretName string) (bool, error) {
+	secret, err := r.rotatableSecret(ctx, project, secretName)
+	if err != nil {
+		return false, err
+	}
+	if secret == nil || len(secret.Data["PGUSER"]) == 0 {
+		return false, nil
+	}
+
+	password := newAlphaNumericPassword(16)
+	secret.Data["PGPASSWORD"] = []byte(password)
+	secret.Data["conn-string"] = []byte(roleConnString(secret.Data))
+	if err := r.Update(ctx, secret); err != nil {
+		return false, fmt.Errorf("failed to update secret %s: %w", secretName, err)
+	}
+	if err := alterRolePassword(dbCtx, pool, string(secret.Data["PGUSER"]), password); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// restartRotated restarts the workloads of the components whose credentials were rotated, once
+// the components were reconciled with the new credentials. Only the read replicas of the
+// project's PostgreSQL are restarted, the primary reads its passwords from its data directory.
+func (r *ProjectReconciler) restartRotated(ctx context.Context, project *edgev1alpha1.Project) error {
+	status := project.Status.CredentialRotation
+	if status == nil || len(status.PendingRestarts) == 0 {
+		return nil
+	}
+
+	restartedAt := time.Now().Format(time.RFC3339)
+	for _, name := range status.PendingRestarts {
+		selector := client.MatchingLabels{labelInstance: fmt.Sprintf("%s-%s", project.Name, name)}
+		// Bitnami's chart labels the read replicas' StatefulSet with the read component
+		if name == "postgres" {
+			selector[common.LabelComponent] = "read"
+		}
+		if err := r.restartWorkloads(ctx, project.Namespace, selector, restartedAt); err != nil {
+			return err
+		}
+	}
+
+	patch := client.MergeFrom(project.DeepCopy())
+	project.Status.CredentialRotation.PendingRestarts = nil
+	return r.patchStatus(ctx, project, patch)
+}
+
+// restartWorkloads rolls the Deployments and StatefulSets matching selector by annotating their
+// pod template, as kubectl rollout restart does
+func (r *ProjectReconciler) restartWorkloads(ctx context.Context, namespace string,
+	selector client.MatchingLabels, restartedAt string) error {
+	logger := log.FromContext(ctx)
+
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(namespace), selector); err != nil {
+		return fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		patch := client.MergeFrom(d.DeepCopy())
+		d.Spec.Template.Annotations = setRestartedAt(d.Spec.Template.Annotations, restartedAt)
+		if err := r.Patch(ctx, d, patch); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to restart deployment %s: %w", d.Name, err)
+		}
+		logger.Info("Restarted deployment", "name", d.Name)
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulSets, client.InNamespace(namespace), selector); err != nil {
+		return fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for i := range statefulSets.Items {
+		s := &statefulSets.Items[i]
+		patch := client.MergeFrom(s.DeepCopy())
+		s.Spec.Template.Annotations = setRestartedAt(s.Spec.Template.Annotations, restartedAt)
+		if err := r.Patch(ctx, s, patch); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to restart statefulset %s: %w", s.Name, err)
+		}
+		logger.Info("Restarted statefulset", "name", s.Name)
+	}
+	return nil
+}
+
+func setRestartedAt(annotations map[string]string, restartedAt string) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[common.AnnotationRestartedAt] = restartedAt
+	return annotations
+}
+
+// rotatableSecret returns the secret if the project generated it, nil if it's missing or was
+// supplied by the user
+func (r *ProjectReconciler) rotatableSecret(ctx context.Context, project *edgev1alpha1.Project,
+	name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: project.Namespace}, secret)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+	if !metav1.IsControlledBy(secret, project) || secret.Data == nil {
+		return nil, nil
+	}
+	return secret, nil
+}
+
+// alterRolePassword sets the password of an existing role
+func alterRolePassword(ctx context.Context, pool *pgxpool.Pool, name, password string) error {
+	if _, err := pool.Exec(ctx, rolePasswordStatement(name, password)); err != nil {
+		return fmt.Errorf("failed to rotate the password of PostgreSQL role %s: %w", name, err)
+	}
+	return nil
+}
+
+func rolePasswordStatement(name, password string) string {
+	return fmt.Sprintf("ALTER ROLE %s WITH PASSWORD '%s'", pgx.Identifier{name}.Sanitize(),
+		strings.ReplaceAll(password, "'", "''"))
+}
+
+// postgresReplicationUser reads the replication user of the built-in PostgreSQL from its values
+func postgresReplicationUser(project *edgev1alpha1.Project, ref *edgev1alpha1.ComponentRef) string {
+	values := struct {
+		Auth struct {
+			ReplicationUsername string `json:"replicationUsername"`
+		} `json:"auth"`
+	}{}
+	releaseSpec := ref.GetReleaseSpec("postgres", project.Name)
+	if err := yaml.Unmarshal([]byte(releaseSpec.ValuesContent), &values); err != nil ||
+		values.Auth.ReplicationUsername == "" {
+		return defaultReplUser
+	}
+	return values.Auth.ReplicationUsername
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+func TestRotationDue(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	project := &edgev1alpha1.Project{}
+	project.CreationTimestamp = metav1.NewTime(created)
+	project.Spec.Database = &edgev1alpha1.Database{}
+
+	if rotationDue(project, created.Add(24*time.Hour)) {
+		t.Error("credentials rotated without an interval or request")
+	}
+
+	project.Spec.Database.Rotation = &edgev1alpha1.CredentialRotation{Interval: &metav1.Duration{Duration: time.Hour}}
+	if rotationDue(project, created.Add(30*time.Minute)) {
+		t.Error("credentials rotated before the interval elapsed")
+	}
+	if !rotationDue(project, created.Add(time.Hour)) {
+		t.Error("credentials not rotated once the interval elapsed since creation")
+	}
+
+	project.Status.CredentialRotation = &edgev1alpha1.CredentialRotationStatus{
+		LastRotated: metav1.NewTime(created.Add(time.Hour)),
+	}
+	if rotationDue(project, created.Add(90*time.Minute)) {
+		t.Error("credentials rotated before the interval elapsed since the last rotation")
+	}
+
+	project.Annotations = map[string]string{common.AnnotationRotateCredentials: "1"}
+	if !rotationDue(project, created.Add(90*time.Minute)) {
+		t.Error("credentials not rotated on request")
+	}
+	project.Status.CredentialRotation.LastRequest = "1"
+	if rotationDue(project, created.Add(90*time.Minute)) {
+		t.Error("the same request rotated credentials again")
+	}
+}
+
+func TestRoleConnString(t *testing.T) {
+	data := map[string][]byte{
+		"PGHOST": []byte("db"), "PGPORT": []byte("5432"), "PGUSER": []byte("app"),
+		"PGPASSWORD": []byte("secret"), "PGDATABASE": []byte("main"), "PGSSLMODE": []byte("require"),
+	}
+	if conn := roleConnString(data); conn != "host=db port=5432 user=app password=secret dbname=main sslmode=require" {
+		t.Errorf("unexpected connection string %q", conn)
+	}
+}
+
+func TestRolePasswordStatement(t *testing.T) {
+	if stmt := rolePasswordStatement(`repl"user`, "it's"); stmt != `ALTER ROLE "repl""user" WITH PASSWORD 'it''s'` {
+		t.Errorf("unexpected statement %q", stmt)
+	}
+}
+
+func TestRotateCredentialsDue(t *testing.T) {
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "acme", "default"
+	project.CreationTimestamp = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	project.Spec.Database = &edgev1alpha1.Database{
+		Rotation: &edgev1alpha1.CredentialRotation{Interval: &metav1.Duration{Duration: time.Hour}},
+	}
+	project.Status.ComponentStatuses = map[string]edgev1alpha1.ComponentStatus{"database-postgres": {Ready: true}}
+	r := &ProjectReconciler{Client: fake.NewClientBuilder().Build()}
+
+	// Due rotation of the built-in PostgreSQL connects as the superuser
+	if err := r.rotateCredentials(context.Background(), project); err == nil ||
+		!strings.Contains(err.Error(), "acme-pguser-postgres") {
+		t.Errorf("due rotation skipped, got %v", err)
+	}
+}
+
+func TestRotateAuthSecret(t *testing.T) {
+	ctx := context.Background()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "acme-postgresql", Namespace: "default"},
+		Data: map[string][]byte{
+			"postgres-password":    []byte("old"),
+			"replication-password": []byte("old-repl"),
+		},
+	}
+	r := &ProjectReconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build()}
+	stored := func() map[string][]byte {
+		current := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: "acme-postgresql", Namespace: "default"}, current); err != nil {
+			t.Fatal(err)
+		}
+		return current.Data
+	}
+
+	// The new passwords are stored before the roles are altered, and put back if altering fails
+	alterErr := errors.New("failed to rotate the password of PostgreSQL role postgres")
+	err := r.rotateAuthSecret(ctx, secret, map[string][]byte{"postgres-password": []byte("new")}, func() error {
+		if data := stored(); string(data["postgres-password"]) != "new" {
+			t.Errorf("roles altered before the secret was written: %q", data["postgres-password"])
+		}
+		return alterErr
+	})
+	if !errors.Is(err, alterErr) {
+		t.Errorf("got error %v, want %v", err, alterErr)
+	}
+	if data := stored(); string(data["postgres-password"]) != "old" || string(data["replication-password"]) != "old-repl" {
+		t.Errorf("secret not restored: %q", data)
+	}
+
+	if err := r.rotateAuthSecret(ctx, secret, map[string][]byte{"postgres-password": []byte("new")},
+		func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if data := stored(); string(data["postgres-password"]) != "new" || string(data["replication-password"]) != "old-repl" {
+		t.Errorf("secret not rotated: %q", data)
+	}
+}
@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+const (
+	kindHTTPRoute = "HTTPRoute"
+	kindTCPRoute  = "TCPRoute"
+	gatewayGroup  = "gateway.networking.k8s.io"
+)
+
+// Gateway API kinds are read as unstructured objects so that the controller runs on clusters
+// without the Gateway API CRDs
+var routeGVKs = map[string]schema.GroupVersionKind{
+	kindHTTPRoute: {Group: gatewayGroup, Version: "v1", Kind: kindHTTPRoute},
+	kindTCPRoute:  {Group: gatewayGroup, Version: "v1alpha2", Kind: kindTCPRoute},
+}
+
+// routeTarget is a service of a component exposed through the project's Gateway
+type routeTarget struct {
+	// name labels the endpoint in its hostname, e.g. iam, and keys the listener of TCP endpoints
+	name    string
+	service string
+	port    int64
+	tcp     bool
+}
+
+// reconcileRoutes creates the HTTPRoutes and TCPRoutes exposing the project's built-in components
+// on its Gateway once they're ready, and deletes those of removed components or ingress. A route
+// is kept while its component isn't ready. A route name claimed by an earlier component, e.g. the
+// s3 endpoint of a second storage component, isn't routed.
+func (r *ProjectReconciler) reconcileRoutes(ctx context.Context, project *edgev1alpha1.Project) error {
+	logger := log.FromContext(ctx)
+
+	desired := []*unstructured.Unstructured{}
+	routes := []edgev1alpha1.RouteStatus{}
+	if ingress := project.Spec.Ingress; ingress != nil {
+		for _, p := range Providers() {
+			ref := p.ComponentRef(&project.Spec)
+			if ref == nil || ref.IsExternal() {
+				continue
+			}
+			component := fmt.Sprintf("%s-%s", p.Type(), p.Name())
+			for _, target := range p.Routes(project) {
+				route, status := buildRoute(project, ingress, component, target)
+				if route == nil || (!project.Status.ComponentStatuses[component].Ready &&
+					!slices.Contains(project.Status.Routes, status)) {
+					continue
+				}
+				if slices.ContainsFunc(desired, func(u *unstructured.Unstructured) bool {
+					return u.GetKind() == route.GetKind() && u.GetName() == route.GetName()
+				}) {
+					logger.Info("Route name already taken, not routed", "component", component, "route", target.name)
+					continue
+				}
+				desired = append(desired, route)
+				routes = append(routes, status)
+			}
+		}
+	}
+
+	var routeErr error
+	for _, route := range desired {
+		if routeErr = r.applyUnstructured(ctx, project, route); routeErr != nil {
+			break
+		}
+	}
+	if routeErr == nil {
+		for _, recorded := range project.Status.Routes {
+			if slices.Contains(routes, recorded) {
+				continue
+			}
+			logger.Info("Deleting route", "kind", recorded.Kind, "name", recorded.Name)
+			route := &unstructured.Unstructured{}
+			route.SetGroupVersionKind(routeGVKs[recorded.Kind])
+			route.SetName(recorded.Name)
+			route.SetNamespace(project.Namespace)
+			if err := r.Delete(ctx, route); err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+				routeErr = err
+				break
+			}
+		}
+	}
+	if routeErr != nil && !meta.IsNoMatchError(routeErr) {
+		return fmt.Errorf("failed to reconcile routes: %w", routeErr)
+	}
+
+	patch := client.MergeFrom(project.DeepCopy())
+	changed := false
+	if project.Spec.Ingress == nil && routeErr == nil {
+		changed = meta.RemoveStatusCondition(&project.Status.Conditions, common.ConditionTypeRoutesReady)
+	} else {
+		cond := metav1.Condition{
+			Type:               common.ConditionTypeRoutesReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: project.Generation,
+			Reason:             common.ReasonRoutesReconciled,
+			Message:            fmt.Sprintf("%d routes attached to Gateway %s", len(routes), gatewayName(project)),
+		}
+		if routeErr != nil {
+			// Reconciled projects don't take the fast path while it's false, the routes are created
+			// on a resync once the CRDs are installed
+			cond.Status, cond.Reason = metav1.ConditionFalse, common.ReasonGatewayAPIMissing
+			cond.Message = fmt.Sprintf("Gateway API CRDs are not installed: %v", routeErr)
+			routes = project.Status.Routes
+		}
+		r.recordTransition(project, cond)
+		changed = meta.SetStatusCondition(&project.Status.Conditions, cond)
+	}
+	if !slices.Equal(project.Status.Routes, routes) {
+		project.Status.Routes = routes
+		changed = true
+	}
+	if len(project.Status.Routes) == 0 {
+		project.Status.Routes = nil
+	}
+	if !changed {
+		return nil
+	}
+	return r.patchStatus(ctx, project, patch)
+}
+
+// buildRoute renders the route exposing target, or nil for a TCP endpoint without a listener
+func buildRoute(project *edgev1alpha1.Project, ingress *edgev1alpha1.Ingress, component string,
+	target routeTarget) (*unstructured.Unstructured, edgev1alpha1.RouteStatus) {
+	name := fmt.Sprintf("%s-%s", project.Name, target.name)
+	parentRef := map[string]any{
+		"group":     gatewayGroup,
+		"kind":      "Gateway",
+		"name":      ingress.GatewayRef.Name,
+		"namespace": gatewayNamespace(project),
+	}
+	backendRef := map[string]any{
+		"group":  "",
+		"kind":   "Service",
+		"name":   target.service,
+		"port":   target.port,
+		"weight": int64(1),
+	}
+
+	var kind string
+	var spec map[string]any
+	status := edgev1alpha1.RouteStatus{Name: name, Component: component}
+	if target.tcp {
+		listener := ingress.GatewayRef.TCPListeners[target.name]
+		if listener == "" {
+			return nil, status
+		}
+		parentRef["sectionName"] = listener
+		kind = kindTCPRoute
+		spec = map[string]any{
+			"parentRefs": []any{parentRef},
+			"rules":      []any{map[string]any{"backendRefs": []any{backendRef}}},
+		}
+	} else {
+		if ingress.GatewayRef.SectionName != "" {
+			parentRef["sectionName"] = ingress.GatewayRef.SectionName
+		}
+		kind = kindHTTPRoute
+		status.Hostname = routeHostname(project, ingress, target.name)
+		spec = map[string]any{
+			"parentRefs": []any{parentRef},
+			"hostnames":  []any{status.Hostname},
+			"rules": []any{map[string]any{
+				"backendRefs": []any{backendRef},
+				"matches": []any{map[string]any{
+					"path": map[string]any{"type": "PathPrefix", "value": "/"},
+				}},
+			}},
+		}
+	}
+	status.Kind = kind
+
+	route := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	route.SetGroupVersionKind(routeGVKs[kind])
+	route.SetName(name)
+	route.SetNamespace(project.Namespace)
+	route.SetLabels(map[string]string{
+		common.LabelManagedBy: "edge",
+		common.LabelProject:   project.Name,
+		common.LabelComponent: component,
+	})
+	return route, status
+}
+
+// applyUnstructured creates an object the project owns, e.g. a route, or updates the spec of an
+// existing one that differs. Kinds of optional CRDs are handled as unstructured objects.
+func (r *ProjectReconciler) applyUnstructured(ctx context.Context, project *edgev1alpha1.Project,
+	obj *unstructured.Unstructured) error {
+	if err := controllerutil.SetControllerReference(project, obj, r.Scheme); err != nil {
+		return err
+	}
+
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(obj.GroupVersionKind())
+	err := r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, current)
+	if errors.IsNotFound(err) {
+		log.FromContext(ctx).Info("Creating", "kind", obj.GetKind(), "name", obj.GetName())
+		return r.Create(ctx, obj)
+	} else if err != nil {
+		return err
+	}
+
+	// Fields defaulted by the API server are ignored
+	if equality.Semantic.DeepDerivative(obj.Object["spec"], current.Object["spec"]) {
+		return nil
+	}
+	current.Object["spec"] = obj.Object["spec"]
+	current.SetLabels(obj.GetLabels())
+	current.SetOwnerReferences(obj.GetOwnerReferences())
+	return r.Update(ctx, current)
+}
+
+// routeHostname renders the ingress's hostname pattern for the endpoint name
+func routeHostname(project *edgev1alpha1.Project, ingress *edgev1alpha1.Ingress, name string) string {
+	return strings.NewReplacer(
+		"{name}", name,
+		"{project}", project.Name,
+		"{namespace}", project.Namespace,
+		"{domain}", ingress.Domain,
+	).Replace(ingress.GetHostnamePattern())
+}
+
+func gatewayNamespace(project *edgev1alpha1.Project) string {
+	if ns := project.Spec.Ingress.GatewayRef.Namespace; ns != "" {
+		return ns
+	}
+	return project.Namespace
+}
+
+func gatewayName(project *edgev1alpha1.Project) string {
+	return gatewayNamespace(project) + "/" + project.Spec.Ingress.GatewayRef.Name
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestBuildRoute(t *testing.T) {
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+	project.Spec.Ingress = &edgev1alpha1.Ingress{
+		Domain: "example.com",
+		GatewayRef: edgev1alpha1.GatewayRef{
+			Name: "eg", Namespace: "envoy-gateway-system",
+			TCPListeners: map[string]string{"postgres": "tcp-postgres-5432"},
+		},
+	}
+	ingress := project.Spec.Ingress
+
+	route, status := buildRoute(project, ingress, "auth-zitadel",
+		routeTarget{name: "iam", service: "demo-zitadel", port: 8080})
+	if route.GetKind() != kindHTTPRoute || route.GetName() != "demo-iam" || status.Hostname != "iam.demo.example.com" {
+		t.Errorf("unexpected HTTPRoute %s/%s for %s", route.GetKind(), route.GetName(), status.Hostname)
+	}
+	hostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+	if len(hostnames) != 1 || hostnames[0] != "iam.demo.example.com" {
+		t.Errorf("unexpected hostnames %v", hostnames)
+	}
+
+	route, status = buildRoute(project, ingress, "database-postgres",
+		routeTarget{name: "postgres", service: "demo-postgres-postgresql-primary", port: 5432, tcp: true})
+	if route.GetKind() != kindTCPRoute || status.Hostname != "" {
+		t.Fatalf("unexpected TCPRoute %s with hostname %q", route.GetKind(), status.Hostname)
+	}
+	parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	if len(parentRefs) != 1 || parentRefs[0].(map[string]any)["sectionName"] != "tcp-postgres-5432" {
+		t.Errorf("unexpected parentRefs %v", parentRefs)
+	}
+
+	ingress.GatewayRef.TCPListeners = nil
+	if route, _ := buildRoute(project, ingress, "database-postgres",
+		routeTarget{name: "postgres", port: 5432, tcp: true}); route != nil {
+		t.Error("TCP endpoint without a listener was routed")
+	}
+
+	ingress.HostnamePattern = "{name}-{project}.{namespace}.{domain}"
+	if hostname := routeHostname(project, ingress, "api"); hostname != "api-demo.apps.example.com" {
+		t.Errorf("unexpected hostname %q", hostname)
+	}
+}
+
+func TestAuthRouteNames(t *testing.T) {
+	project := &edgev1alpha1.Project{}
+	project.Name = "demo"
+
+	// Both auth components may be declared, each gets its own hostname
+	names := []string{}
+	for _, p := range Providers() {
+		if p.Type() != "auth" {
+			continue
+		}
+		for _, target := range p.Routes(project) {
+			if slices.Contains(names, target.name) {
+				t.Errorf("route name %s of %s already taken", target.name, p.Name())
+			}
+			names = append(names, target.name)
+		}
+	}
+	if !slices.Equal(names, []string{"iam", "keycloak"}) {
+		t.Errorf("got auth route names %v", names)
+	}
+}
@@ -0,0 +1,495 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	s3RootUserKey        = "root-user"
+	s3RootPasswordKey    = "root-password"
+	seaweedfsS3ConfigKey = "seaweedfs_s3_config"
+	s3DefaultRegion      = "us-east-1"
+)
+
+// prepareStorage ensures the generated root credentials of a built-in storage backend and points
+// the chart at them. MinIO additionally gets OpenID login against the project's auth component.
+func (r *ProjectReconciler) prepareStorage(ctx context.Context, project *edgev1alpha1.Project,
+	name string, ref *edgev1alpha1.ComponentRef) error {
+	logger := log.FromContext(ctx)
+	compType := "storage"
+
+	storageValues := make(map[string]any)
+	if err := yaml.Unmarshal([]byte(ref.Release.ValuesContent), &storageValues); err != nil {
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Values error: %v", err), "")
+		return fmt.Errorf("error parsing %s values: %w", name, err)
+	}
+	if storageValues == nil {
+		storageValues = make(map[string]any)
+	}
+
+	// Step 1: Ensure the root credentials secret
+	rootSecretName := storageRootSecretName(project, name)
+	if err := r.ensureStorageRootSecret(ctx, project, rootSecretName); err != nil {
+		logger.Error(err, "Failed to ensure storage root secret")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Secret error: %v", err), "")
+		return err
+	}
+
+	// Step 2: Point the chart at the generated credentials
+	switch name {
+	case "minio":
+		issuer := ""
+		if project.Spec.Auth != nil {
+			var ready bool
+			if issuer, ready = projectIssuer(project); !ready {
+				err := fmt.Errorf("%w: waiting for auth component", errDependencyNotReady)
+				_ = r.updateComponentStatus(ctx, project, compType, name, false,
+					fmt.Sprintf("Auth error: %v", err), "")
+				return err
+			}
+		}
+		configured := false
+		if issuer != "" {
+			var err error
+			if configured, err = r.minioOIDCConfigured(ctx, project); err != nil {
+				return err
+			}
+		}
+		// Without the client credentials MinIO would offer a login it can't complete
+		if configured {
+			r.updateMinioValuesWithOIDC(storageValues, project, issuer)
+		}
+		if err := r.reportStorageSSO(ctx, project, issuer, configured); err != nil {
+			return err
+		}
+		r.updateMinioValuesWithSecret(storageValues, rootSecretName)
+	case "seaweedfs":
+		r.updateSeaweedFSValuesWithSecret(storageValues, rootSecretName)
+	}
+
+	updatedValues, err := yaml.Marshal(storageValues)
+	if err != nil {
+		logger.Error(err, "Failed to marshal updated storage values")
+		return err
+	}
+	ref.Release.ValuesContent = string(updatedValues)
+	return nil
+}
+
+// publishStorage runs once the storage component is ready. Built-in backends publish their
+// credentials as the <project>-s3 secret; the declared buckets are then created on either kind.
+func (r *ProjectReconciler) publishStorage(ctx context.Context, project *edgev1alpha1.Project,
+	name string, ref *edgev1alpha1.ComponentRef) error {
+	compType := "storage"
+
+	// External S3 compatible storage only needs its buckets
+	if ref.IsExternal() {
+		return r.ensureBuckets(ctx, project, compType, name, ref.GetSecretName())
+	}
+
+	status := project.Status.ComponentStatuses[fmt.Sprintf("%s-%s", compType, name)]
+	s3SecretName := fmt.Sprintf("%s-s3", project.Name)
+	if err := r.ensureS3Secret(ctx, project, s3SecretName, storageRootSecretName(project, name),
+		status.Endpoint); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to ensure S3 secret")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Secret error: %v", err), status.Endpoint)
+		return err
+	}
+
+	return r.ensureBuckets(ctx, project, compType, name, s3SecretName)
+}
+
+func storageRootSecretName(project *edgev1alpha1.Project, name string) string {
+	return fmt.Sprintf("%s-%s-root", project.Name, name)
+}
+
+// verifyExternalStorageSecret verifies that an external storage secret has the S3 connection keys
+// and reports the S3 endpoint it references.
+func (r *ProjectReconciler) verifyExternalStorageSecret(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) (edgev1alpha1.ComponentStatus, error) {
+	status := edgev1alpha1.ComponentStatus{}
+	secretName := ref.GetSecretName()
+	if secretName == "" {
+		return status, fmt.Errorf("external storage requires a secretName")
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, secret); err != nil {
+		if errors.IsNotFound(err) {
+			return status, fmt.Errorf("required external storage secret %s not found", secretName)
+		}
+		return status, fmt.Errorf("failed to get external storage secret: %w", err)
+	}
+
+	requiredFields := []string{"AWS_ENDPOINT_URL_S3", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}
+	missingFields := []string{}
+	for _, field := range requiredFields {
+		if _, exists := secret.Data[field]; !exists {
+			missingFields = append(missingFields, field)
+		}
+	}
+	if len(missingFields) > 0 {
+		return status, fmt.Errorf("external storage secret %s is missing required fields: %v",
+			secretName, strings.Join(missingFields, ", "))
+	}
+
+	status.Endpoint = string(secret.Data[connS3Endpoint])
+	return status, nil
+}
+
+// ensureStorageRootSecret creates the root credentials secret of the storage backend if it doesn't exist.
+// The same credentials are rendered as a SeaweedFS S3 identity config so either backend can consume it.
+func (r *ProjectReconciler) ensureStorageRootSecret(ctx context.Context, project *edgev1alpha1.Project,
+	secretName string) error {
+	existingSecret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, existingSecret)
+
+	if err == nil {
+		user, password := existingSecret.Data[s3RootUserKey], existingSecret.Data[s3RootPasswordKey]
+		if len(user) > 0 && len(password) > 0 && len(existingSecret.Data[seaweedfsS3ConfigKey]) > 0 {
+			return nil
+		}
+		if len(user) == 0 || len(password) == 0 {
+			user = []byte(strings.ToLower(newAlphaNumericPassword(16)))
+			password = []byte(newAlphaNumericPassword(32))
+		}
+
+		data, err := storageRootSecretData(string(user), string(password))
+		if err != nil {
+			return err
+		}
+		existingSecret.Data = data
+		return r.Update(ctx, existingSecret)
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+
+	data, err := storageRootSecretData(strings.ToLower(newAlphaNumericPassword(16)), newAlphaNumericPassword(32))
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: project.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: project.APIVersion,
+					Kind:       project.Kind,
+					Name:       project.Name,
+					UID:        project.UID,
+					Controller: ptr.To(true),
+				},
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+
+	return r.createSecret(ctx, project, secret)
+}
+
+func storageRootSecretData(user, password string) (map[string][]byte, error) {
+	s3Config, err := json.Marshal(map[string]any{
+		"identities": []map[string]any{
+			{
+				"name":        "admin",
+				"credentials": []map[string]string{{"accessKey": user, "secretKey": password}},
+				"actions":     []string{"Admin", "Read", "List", "Tagging", "Write"},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render SeaweedFS S3 config: %w", err)
+	}
+
+	return map[string][]byte{
+		s3RootUserKey:        []byte(user),
+		s3RootPasswordKey:    []byte(password),
+		seaweedfsS3ConfigKey: s3Config,
+	}, nil
+}
+
+// ensureS3Secret creates or updates the consumer facing <project>-s3 secret from the root credentials.
+func (r *ProjectReconciler) ensureS3Secret(ctx context.Context, project *edgev1alpha1.Project,
+	secretName, rootSecretName, endpoint string) error {
+	rootSecret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: rootSecretName, Namespace: project.Namespace}, rootSecret); err != nil {
+		return fmt.Errorf("failed to get storage root secret %s: %w", rootSecretName, err)
+	}
+
+	secretData := map[string][]byte{
+		"AWS_ACCESS_KEY_ID":     rootSecret.Data[s3RootUserKey],
+		"AWS_ENDPOINT_URL_S3":   []byte(endpoint),
+		"AWS_REGION":            []byte(s3DefaultRegion),
+		"AWS_SECRET_ACCESS_KEY": rootSecret.Data[s3RootPasswordKey],
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, secret)
+	if err == nil {
+		secret.Data = secretData
+		return r.Update(ctx, secret)
+	} else if errors.IsNotFound(err) {
+		newSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: project.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: project.APIVersion,
+						Kind:       project.Kind,
+						Name:       project.Name,
+						UID:        project.UID,
+						Controller: ptr.To(true),
+					},
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: secretData,
+		}
+		return r.createSecret(ctx, project, newSecret)
+	}
+
+	return err
+}
+
+// ensureBuckets creates the project's declared buckets and applies their versioning and lifecycle
+// configuration using the S3 credentials in secretName.
+func (r *ProjectReconciler) ensureBuckets(ctx context.Context, project *edgev1alpha1.Project,
+	compType, name, secretName string) error {
+	logger := log.FromContext(ctx)
+
+	buckets := project.Spec.Storage.Buckets
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, secret); err != nil {
+		return fmt.Errorf("failed to get S3 secret %s: %w", secretName, err)
+	}
+
+	client, err := newS3Client(string(secret.Data["AWS_ENDPOINT_URL_S3"]),
+		string(secret.Data["AWS_ACCESS_KEY_ID"]), string(secret.Data["AWS_SECRET_ACCESS_KEY"]),
+		string(secret.Data["AWS_REGION"]))
+	if err != nil {
+		return err
+	}
+
+	for _, bucket := range buckets {
+		if err := ensureBucket(ctx, client, bucket); err != nil {
+			logger.Error(err, "Failed to ensure bucket", "bucket", bucket.Name)
+			status := project.Status.ComponentStatuses[fmt.Sprintf("%s-%s", compType, name)]
+			_ = r.updateComponentStatus(ctx, project, compType, name, false,
+				fmt.Sprintf("Bucket error: %v", err), status.Endpoint)
+			return err
+		}
+	}
+
+	logger.Info("Buckets ensured", "count", len(buckets))
+	return nil
+}
+
+func newS3Client(endpoint, accessKey, secretKey, region string) (*minio.Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: u.Scheme == "https",
+		Region: region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	return client, nil
+}
+
+func ensureBucket(ctx context.Context, client *minio.Client, bucket edgev1alpha1.Bucket) error {
+	exists, err := client.BucketExists(ctx, bucket.Name)
+	if err != nil {
+		return fmt.Errorf("failed to check bucket %s: %w", bucket.Name, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, bucket.Name, minio.MakeBucketOptions{}); err != nil {
+			return fmt.Errorf("failed to create bucket %s: %w", bucket.Name, err)
+		}
+	}
+
+	versioning, err := client.GetBucketVersioning(ctx, bucket.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get versioning of bucket %s: %w", bucket.Name, err)
+	}
+	if bucket.Versioning && !versioning.Enabled() {
+		if err := client.EnableVersioning(ctx, bucket.Name); err != nil {
+			return fmt.Errorf("failed to enable versioning of bucket %s: %w", bucket.Name, err)
+		}
+	} else if !bucket.Versioning && versioning.Enabled() {
+		if err := client.SuspendVersioning(ctx, bucket.Name); err != nil {
+			return fmt.Errorf("failed to suspend versioning of bucket %s: %w", bucket.Name, err)
+		}
+	}
+
+	// An empty configuration removes any existing lifecycle rules
+	config := lifecycle.NewConfiguration()
+	for _, rule := range bucket.Lifecycle {
+		config.Rules = append(config.Rules, lifecycle.Rule{
+			ID:         rule.ID,
+			Status:     "Enabled",
+			RuleFilter: lifecycle.Filter{Prefix: rule.Prefix},
+			Expiration: lifecycle.Expiration{Days: lifecycle.ExpirationDays(rule.ExpirationDays)},
+			NoncurrentVersionExpiration: lifecycle.NoncurrentVersionExpiration{
+				NoncurrentDays: lifecycle.ExpirationDays(rule.NoncurrentExpirationDays),
+			},
+		})
+	}
+	if err := client.SetBucketLifecycle(ctx, bucket.Name, config); err != nil {
+		return fmt.Errorf("failed to set lifecycle of bucket %s: %w", bucket.Name, err)
+	}
+
+	return nil
+}
+
+// updateMinioValuesWithSecret updates the MinIO values to use the generated root credentials.
+func (r *ProjectReconciler) updateMinioValuesWithSecret(values map[string]any, secretName string) {
+	auth, ok := values["auth"].(map[string]any)
+	if !ok {
+		auth = make(map[string]any)
+		values["auth"] = auth
+	}
+
+	auth["existingSecret"] = secretName
+	delete(auth, "rootUser")
+	delete(auth, "rootPassword")
+}
+
+// minioOIDCSecretName returns the name of the secret holding the client-id and client-secret of
+// the project's minio OIDC app, created in the auth component by the user
+func minioOIDCSecretName(project *edgev1alpha1.Project) string {
+	return fmt.Sprintf("%s-minio-oidc", project.Name)
+}
+
+// minioOIDCConfigured reports whether the client credentials of the minio OIDC app are supplied
+func (r *ProjectReconciler) minioOIDCConfigured(ctx context.Context, project *edgev1alpha1.Project) (bool, error) {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: minioOIDCSecretName(project), Namespace: project.Namespace}, secret)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to get MinIO OIDC secret: %w", err)
+	}
+	return len(secret.Data["client-id"]) > 0 && len(secret.Data["client-secret"]) > 0, nil
+}
+
+// reportStorageSSO records in the StorageSSO condition whether MinIO's OpenID login is enabled. It's
+// disabled until the client credentials of the minio app are supplied. The condition is removed
+// if the project has no issuer to log in with.
+func (r *ProjectReconciler) reportStorageSSO(ctx context.Context, project *edgev1alpha1.Project,
+	issuer string, configured bool) error {
+	if issuer == "" {
+		if meta.FindStatusCondition(project.Status.Conditions, common.ConditionTypeStorageSSO) == nil {
+			return nil
+		}
+		patch := client.MergeFrom(project.DeepCopy())
+		meta.RemoveStatusCondition(&project.Status.Conditions, common.ConditionTypeStorageSSO)
+		return r.patchStatus(ctx, project, patch)
+	}
+
+	status, reason, message := metav1.ConditionTrue, common.ReasonOIDCClientConfigured, "MinIO OpenID login enabled"
+	if !configured {
+		status, reason = metav1.ConditionFalse, common.ReasonOIDCClientMissing
+		message = fmt.Sprintf("MinIO OpenID login disabled until secret %s holds the client-id and "+
+			"client-secret of the minio app", minioOIDCSecretName(project))
+	}
+	if cond := meta.FindStatusCondition(project.Status.Conditions, common.ConditionTypeStorageSSO); cond != nil &&
+		cond.Status == status && cond.Message == message {
+		return nil
+	}
+	return r.setCondition(ctx, project, common.ConditionTypeStorageSSO, status, reason, message)
+}
+
+// updateMinioValuesWithOIDC enables MinIO's OpenID login against the project's auth component with
+// the client credentials of the minio app in the <project>-minio-oidc secret.
+func (r *ProjectReconciler) updateMinioValuesWithOIDC(values map[string]any, project *edgev1alpha1.Project,
+	issuer string) {
+	if issuer == "" {
+		return
+	}
+
+	oidcSecretName := minioOIDCSecretName(project)
+	secretEnv := func(name, key string) map[string]any {
+		return map[string]any{
+			"name": name,
+			"valueFrom": map[string]any{
+				"secretKeyRef": map[string]any{"name": oidcSecretName, "key": key},
+			},
+		}
+	}
+	valueEnv := func(name, value string) map[string]any {
+		return map[string]any{"name": name, "value": value}
+	}
+
+	env, _ := values["extraEnvVars"].([]any)
+	for _, envVar := range []map[string]any{
+		valueEnv("MINIO_IDENTITY_OPENID_CONFIG_URL", oidcDiscoveryURL(issuer)),
+		secretEnv("MINIO_IDENTITY_OPENID_CLIENT_ID", "client-id"),
+		secretEnv("MINIO_IDENTITY_OPENID_CLIENT_SECRET", "client-secret"),
+		valueEnv("MINIO_IDENTITY_OPENID_DISPLAY_NAME", "Login with SSO"),
+		valueEnv("MINIO_IDENTITY_OPENID_CLAIM_NAME", "policy_minio"),
+		valueEnv("MINIO_IDENTITY_OPENID_REDIRECT_URI_DYNAMIC", "on"),
+		valueEnv("MINIO_IDENTITY_OPENID_CLAIM_USERINFO", "on"),
+	} {
+		env = setEnvVar(env, envVar)
+	}
+	values["extraEnvVars"] = env
+}
+
+// updateSeaweedFSValuesWithSecret enables S3 authentication with the generated identity config.
+func (r *ProjectReconciler) updateSeaweedFSValuesWithSecret(values map[string]any, secretName string) {
+	s3, ok := values["s3"].(map[string]any)
+	if !ok {
+		s3 = make(map[string]any)
+		values["s3"] = s3
+	}
+
+	s3["enabled"] = true
+	s3["enableAuth"] = true
+	s3["existingConfigSecret"] = secretName
+}
+
+// setEnvVar adds envVar to a chart's env list, replacing any entry of the same name.
+func setEnvVar(env []any, envVar map[string]any) []any {
+	for i, existing := range env {
+		if e, ok := existing.(map[string]any); ok && e["name"] == envVar["name"] {
+			env[i] = envVar
+			return env
+		}
+	}
+	return append(env, envVar)
+}
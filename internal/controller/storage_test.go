@@ -0,0 +1,218 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+func TestEnsureStorageRootSecret(t *testing.T) {
+	ctx := context.Background()
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+	// Credentials supplied by the user are kept, only the SeaweedFS identity config is added
+	supplied := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-seaweedfs-root", Namespace: "apps"},
+		Data:       map[string][]byte{s3RootUserKey: []byte("admin"), s3RootPasswordKey: []byte("secret")},
+	}
+	r := &ProjectReconciler{Client: fake.NewClientBuilder().WithObjects(supplied).Build()}
+
+	for _, name := range []string{"demo-minio-root", "demo-seaweedfs-root"} {
+		if err := r.ensureStorageRootSecret(ctx, project, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	generated := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: "demo-minio-root", Namespace: "apps"}, generated); err != nil {
+		t.Fatal(err)
+	}
+	if len(generated.Data[s3RootUserKey]) != 16 || len(generated.Data[s3RootPasswordKey]) != 32 {
+		t.Errorf("unexpected generated credentials %q", generated.Data[s3RootUserKey])
+	}
+
+	if err := r.Get(ctx, types.NamespacedName{Name: "demo-seaweedfs-root", Namespace: "apps"}, supplied); err != nil {
+		t.Fatal(err)
+	}
+	if string(supplied.Data[s3RootUserKey]) != "admin" || string(supplied.Data[s3RootPasswordKey]) != "secret" {
+		t.Errorf("supplied credentials replaced")
+	}
+	config := struct {
+		Identities []struct {
+			Credentials []struct {
+				AccessKey string `json:"accessKey"`
+				SecretKey string `json:"secretKey"`
+			} `json:"credentials"`
+			Actions []string `json:"actions"`
+		} `json:"identities"`
+	}{}
+	if err := json.Unmarshal(supplied.Data[seaweedfsS3ConfigKey], &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Identities) != 1 || len(config.Identities[0].Credentials) != 1 ||
+		config.Identities[0].Credentials[0].AccessKey != "admin" ||
+		config.Identities[0].Credentials[0].SecretKey != "secret" || len(config.Identities[0].Actions) != 5 {
+		t.Errorf("unexpected SeaweedFS identity config %s", supplied.Data[seaweedfsS3ConfigKey])
+	}
+}
+
+func TestUpdateMinioValuesWithSecret(t *testing.T) {
+	r := &ProjectReconciler{}
+	values := map[string]any{"auth": map[string]any{"rootUser": "admin", "rootPassword": "changeme"}}
+
+	r.updateMinioValuesWithSecret(values, "demo-minio-root")
+	auth := values["auth"].(map[string]any)
+	if auth["existingSecret"] != "demo-minio-root" {
+		t.Errorf("root secret not referenced: %v", auth)
+	}
+	if _, ok := auth["rootPassword"]; ok {
+		t.Error("inline root password kept")
+	}
+}
+
+func TestUpdateMinioValuesWithOIDC(t *testing.T) {
+	r := &ProjectReconciler{}
+	project := &edgev1alpha1.Project{}
+	project.Name = "demo"
+
+	values := map[string]any{}
+	r.updateMinioValuesWithOIDC(values, project, "")
+	if _, ok := values["extraEnvVars"]; ok {
+		t.Error("OpenID configured without an issuer")
+	}
+
+	values = map[string]any{"extraEnvVars": []any{
+		map[string]any{"name": "MINIO_IDENTITY_OPENID_CLAIM_NAME", "value": "groups"},
+		map[string]any{"name": "MINIO_BROWSER", "value": "on"},
+	}}
+	r.updateMinioValuesWithOIDC(values, project, "https://iam.example.com/")
+	env := map[string]map[string]any{}
+	for _, e := range values["extraEnvVars"].([]any) {
+		env[e.(map[string]any)["name"].(string)] = e.(map[string]any)
+	}
+	if len(env) != 8 || env["MINIO_BROWSER"]["value"] != "on" {
+		t.Errorf("declared environment not kept: %v", env)
+	}
+	if got := env["MINIO_IDENTITY_OPENID_CONFIG_URL"]["value"]; got !=
+		"https://iam.example.com/.well-known/openid-configuration" {
+		t.Errorf("got config URL %v", got)
+	}
+	if got := env["MINIO_IDENTITY_OPENID_CLAIM_NAME"]["value"]; got != "policy_minio" {
+		t.Errorf("got claim name %v", got)
+	}
+	ref := env["MINIO_IDENTITY_OPENID_CLIENT_ID"]["valueFrom"].(map[string]any)["secretKeyRef"].(map[string]any)
+	if ref["name"] != "demo-minio-oidc" || ref["key"] != "client-id" || ref["optional"] != nil {
+		t.Errorf("got client ID reference %v", ref)
+	}
+}
+
+func TestUpdateSeaweedFSValuesWithSecret(t *testing.T) {
+	r := &ProjectReconciler{}
+	values := map[string]any{"s3": map[string]any{"port": 8333}}
+
+	r.updateSeaweedFSValuesWithSecret(values, "demo-seaweedfs-root")
+	s3 := values["s3"].(map[string]any)
+	if s3["enabled"] != true || s3["enableAuth"] != true || s3["existingConfigSecret"] != "demo-seaweedfs-root" ||
+		s3["port"] != 8333 {
+		t.Errorf("unexpected s3 values %v", s3)
+	}
+}
+
+func TestPrepareStorageSSO(t *testing.T) {
+	ctx := context.Background()
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+	project.Spec.Auth = &edgev1alpha1.Auth{Zitadel: &edgev1alpha1.ComponentRef{}}
+	project.Status.ComponentStatuses = map[string]edgev1alpha1.ComponentStatus{
+		"auth-zitadel": {Ready: true, Endpoint: "https://iam.example.com"},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := edgev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(project).WithStatusSubresource(project).Build()
+	r := &ProjectReconciler{Client: c, Scheme: scheme}
+
+	prepare := func() string {
+		ref := &edgev1alpha1.ComponentRef{Release: &helmv1alpha1.ReleaseSpec{}}
+		if err := r.prepareStorage(ctx, project, "minio", ref); err != nil {
+			t.Fatal(err)
+		}
+		return ref.Release.ValuesContent
+	}
+
+	// MinIO can't complete a login without the client credentials of the minio app
+	if values := prepare(); strings.Contains(values, "MINIO_IDENTITY_OPENID") {
+		t.Errorf("OpenID login enabled without client credentials:\n%s", values)
+	}
+	cond := meta.FindStatusCondition(project.Status.Conditions, common.ConditionTypeStorageSSO)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != common.ReasonOIDCClientMissing {
+		t.Errorf("got condition %+v", cond)
+	}
+
+	if err := c.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-minio-oidc", Namespace: "apps"},
+		Data:       map[string][]byte{"client-id": []byte("minio"), "client-secret": []byte("secret")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if values := prepare(); !strings.Contains(values, "MINIO_IDENTITY_OPENID_CLIENT_ID") {
+		t.Errorf("OpenID login not enabled:\n%s", values)
+	}
+	if !meta.IsStatusConditionTrue(project.Status.Conditions, common.ConditionTypeStorageSSO) {
+		t.Error("StorageSSO not reported configured")
+	}
+
+	// Nothing to log in with once the auth component is removed
+	project.Spec.Auth = nil
+	prepare()
+	if meta.FindStatusCondition(project.Status.Conditions, common.ConditionTypeStorageSSO) != nil {
+		t.Error("StorageSSO condition kept without an auth component")
+	}
+}
+
+func TestVerifyExternalStorageSecret(t *testing.T) {
+	ctx := context.Background()
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "s3", Namespace: "apps"},
+		Data: map[string][]byte{
+			connS3Endpoint:  []byte("https://s3.example.com"),
+			connS3AccessKey: []byte("key"),
+		},
+	}
+	r := &ProjectReconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build()}
+	ref := &edgev1alpha1.ComponentRef{External: &edgev1alpha1.ExternalRef{SecretName: "s3"}}
+
+	if _, err := r.verifyExternalStorageSecret(ctx, project, ref); err == nil ||
+		!strings.Contains(err.Error(), connS3SecretKey) {
+		t.Errorf("expected the missing secret key to be reported, got %v", err)
+	}
+
+	secret.Data[connS3SecretKey] = []byte("secret")
+	if err := r.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	status, err := r.verifyExternalStorageSecret(ctx, project, ref)
+	if err != nil || status.Endpoint != "https://s3.example.com" {
+		t.Errorf("got endpoint %q, %v", status.Endpoint, err)
+	}
+}
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+// templateResolution is the template spec a project resolves against
+type templateResolution struct {
+	name       string
+	generation int64
+	spec       *edgev1alpha1.ProjectSpec
+	// rollout is set when the resolution differs from the one recorded in the project's status
+	rollout bool
+}
+
+// resolveTemplate picks the spec of the project's ProjectTemplate to resolve against. Projects
+// with the Manual rollout policy keep the recorded spec until their own generation changes. It
+// returns nil if the project references no template, and an error wrapping NotFound if the
+// template doesn't exist.
+func (r *ProjectReconciler) resolveTemplate(ctx context.Context,
+	project *edgev1alpha1.Project) (*templateResolution, error) {
+	ref := project.Spec.TemplateRef
+	if ref == nil {
+		return nil, nil
+	}
+
+	recorded := project.Status.Template
+	if recorded != nil && recorded.Name == ref.Name && recorded.Spec != nil &&
+		ref.GetRolloutPolicy() == edgev1alpha1.TemplateRolloutManual &&
+		project.Status.Generation == project.Generation {
+		return &templateResolution{name: recorded.Name, generation: recorded.Generation, spec: recorded.Spec}, nil
+	}
+
+	template := &edgev1alpha1.ProjectTemplate{}
+	if err := r.Get(ctx, types.NamespacedName{Name: ref.Name}, template); err != nil {
+		return nil, fmt.Errorf("failed to get ProjectTemplate %s: %w", ref.Name, err)
+	}
+
+	return &templateResolution{
+		name:       template.Name,
+		generation: template.Generation,
+		spec:       &template.Spec,
+		rollout: recorded == nil || recorded.Name != template.Name ||
+			recorded.Generation != template.Generation,
+	}, nil
+}
+
+// applyTemplate replaces the project's in-memory spec with its overrides merged onto the resolved
+// template spec, and records a template rollout in the project's status
+func (r *ProjectReconciler) applyTemplate(ctx context.Context, project *edgev1alpha1.Project,
+	resolution *templateResolution) error {
+	merged, err := mergeTemplate(resolution.spec, &project.Spec)
+	if err != nil {
+		return err
+	}
+
+	if resolution.rollout {
+		patch := client.MergeFrom(project.DeepCopy())
+		project.Status.Template = &edgev1alpha1.TemplateStatus{
+			Name:       resolution.name,
+			Generation: resolution.generation,
+			Spec:       resolution.spec.DeepCopy(),
+		}
+		if err := r.patchStatus(ctx, project, patch); err != nil {
+			return err
+		}
+		r.event(project, corev1.EventTypeNormal, common.ReasonTemplateRolledOut,
+			"Resolved against ProjectTemplate %s generation %d", resolution.name, resolution.generation)
+	}
+
+	project.Spec = *merged
+	return nil
+}
+
+// mergeTemplate merges the overrides of a project's spec onto its template's spec. Fields set in
+// overrides replace the template's, nested objects are merged and lists replaced. The valuesContent
+// of a release set in both is deep-merged, and a component overridden as external drops the
+// template's release and vice versa.
+func mergeTemplate(template, overrides *edgev1alpha1.ProjectSpec) (*edgev1alpha1.ProjectSpec, error) {
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+	overridesJSON, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, err
+	}
+	mergedJSON, err := strategicpatch.StrategicMergePatch(templateJSON, overridesJSON, edgev1alpha1.ProjectSpec{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge template: %w", err)
+	}
+
+	merged := &edgev1alpha1.ProjectSpec{}
+	if err := json.Unmarshal(mergedJSON, merged); err != nil {
+		return nil, err
+	}
+	merged.TemplateRef = overrides.TemplateRef
+
+	// ComponentRef initializes a declared group's default component, read from copies
+	template, overrides = template.DeepCopy(), overrides.DeepCopy()
+	for _, p := range Providers() {
+		templateRef, overrideRef, mergedRef := p.ComponentRef(template), p.ComponentRef(overrides),
+			p.ComponentRef(merged)
+		if templateRef == nil || overrideRef == nil || mergedRef == nil {
+			continue
+		}
+
+		switch {
+		case overrideRef.External != nil:
+			mergedRef.Release = nil
+		case overrideRef.Release != nil:
+			mergedRef.External = nil
+		}
+
+		if templateRef.Release == nil || overrideRef.Release == nil || mergedRef.Release == nil {
+			continue
+		}
+		// chartURL isn't omitted when empty, an override of the values alone keeps the chart
+		if mergedRef.Release.ChartURL == "" {
+			mergedRef.Release.ChartURL = templateRef.Release.ChartURL
+		}
+		if templateRef.Release.ValuesContent == "" || overrideRef.Release.ValuesContent == "" {
+			continue
+		}
+		values, err := mergeValues(templateRef.Release.ValuesContent, overrideRef.Release.ValuesContent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge %s values: %w", p.Name(), err)
+		}
+		mergedRef.Release.ValuesContent = values
+	}
+
+	return merged, nil
+}
+
+// mergeValues deep-merges the values YAML of overrides onto base
+func mergeValues(base, overrides string) (string, error) {
+	baseValues, overrideValues := map[string]any{}, map[string]any{}
+	if err := yaml.Unmarshal([]byte(base), &baseValues); err != nil {
+		return "", err
+	}
+	if err := yaml.Unmarshal([]byte(overrides), &overrideValues); err != nil {
+		return "", err
+	}
+
+	merged, err := yaml.Marshal(chartutil.MergeTables(overrideValues, baseValues))
+	if err != nil {
+		return "", err
+	}
+	return string(merged), nil
+}
+
+// projectsForTemplate maps a ProjectTemplate to the Projects referencing it
+func (r *ProjectReconciler) projectsForTemplate(ctx context.Context, obj client.Object) []reconcile.Request {
+	projects := &edgev1alpha1.ProjectList{}
+	if err := r.List(ctx, projects); err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, project := range projects.Items {
+		if project.Spec.TemplateRef != nil && project.Spec.TemplateRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&project)})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+)
+
+func TestMergeTemplate(t *testing.T) {
+	template := &edgev1alpha1.ProjectSpec{
+		DeletionPolicy: edgev1alpha1.DeletionPolicyRetain,
+		Database: &edgev1alpha1.Database{Postgres: &edgev1alpha1.ComponentRef{
+			Release: &helmv1alpha1.ReleaseSpec{
+				ChartURL:      "oci://registry-1.docker.io/bitnamicharts/postgresql",
+				ValuesContent: "primary:\n  persistence:\n    size: 8Gi\n  resources:\n    limits:\n      cpu: 1\n",
+			},
+		}},
+		Auth: &edgev1alpha1.Auth{Zitadel: &edgev1alpha1.ComponentRef{}},
+		Storage: &edgev1alpha1.Storage{
+			SeaweedFS: &edgev1alpha1.ComponentRef{},
+			Buckets:   []edgev1alpha1.Bucket{{Name: "assets"}, {Name: "logs"}},
+		},
+	}
+	overrides := &edgev1alpha1.ProjectSpec{
+		TemplateRef: &edgev1alpha1.TemplateRef{Name: "standard"},
+		Database: &edgev1alpha1.Database{Postgres: &edgev1alpha1.ComponentRef{
+			Release: &helmv1alpha1.ReleaseSpec{ValuesContent: "primary:\n  persistence:\n    size: 20Gi\n"},
+		}},
+		Auth: &edgev1alpha1.Auth{Zitadel: &edgev1alpha1.ComponentRef{
+			External: &edgev1alpha1.ExternalRef{SecretName: "idp"},
+		}},
+		Storage: &edgev1alpha1.Storage{Buckets: []edgev1alpha1.Bucket{{Name: "media"}}},
+	}
+	original := template.DeepCopy()
+
+	merged, err := mergeTemplate(template, overrides)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	if merged.TemplateRef == nil || merged.TemplateRef.Name != "standard" {
+		t.Errorf("templateRef not kept: %+v", merged.TemplateRef)
+	}
+	if merged.DeletionPolicy != edgev1alpha1.DeletionPolicyRetain {
+		t.Errorf("template deletion policy lost: %q", merged.DeletionPolicy)
+	}
+
+	release := merged.Database.Postgres.Release
+	if release.ChartURL != template.Database.Postgres.Release.ChartURL {
+		t.Errorf("template chart lost: %q", release.ChartURL)
+	}
+	values := map[string]any{}
+	if err := yaml.Unmarshal([]byte(release.ValuesContent), &values); err != nil {
+		t.Fatal(err)
+	}
+	primary := values["primary"].(map[string]any)
+	if size := primary["persistence"].(map[string]any)["size"]; size != "20Gi" {
+		t.Errorf("override not applied, size %v", size)
+	}
+	if _, ok := primary["resources"]; !ok {
+		t.Errorf("template values lost: %s", release.ValuesContent)
+	}
+
+	if merged.Auth.Zitadel.External == nil || merged.Auth.Zitadel.Release != nil {
+		t.Errorf("external override not applied: %+v", merged.Auth.Zitadel)
+	}
+	if merged.Storage.SeaweedFS == nil || len(merged.Storage.Buckets) != 1 ||
+		merged.Storage.Buckets[0].Name != "media" {
+		t.Errorf("storage not merged: %+v", merged.Storage)
+	}
+
+	if template.Database.Postgres.Release.ValuesContent != original.Database.Postgres.Release.ValuesContent {
+		t.Error("template modified")
+	}
+}
@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+const (
+	certManagerGroup = "cert-manager.io"
+	// certCAKey is the key of an issued certificate's secret holding the issuer's CA, if known
+	certCAKey = "ca.crt"
+	// annotationCertificateName is set by cert-manager on the secrets of its certificates
+	annotationCertificateName = "cert-manager.io/certificate-name"
+)
+
+var certificateGVK = schema.GroupVersionKind{Group: certManagerGroup, Version: "v1", Kind: "Certificate"}
+
+// issuedCertificate is the secret of a component's issued certificate
+type issuedCertificate struct {
+	secretName string
+	// revision changes with every certificate issued to the secret
+	revision string
+}
+
+// certificateName returns the name of a component's Certificate and its secret
+func certificateName(project *edgev1alpha1.Project, name string) string {
+	return fmt.Sprintf("%s-%s-tls", project.Name, name)
+}
+
+// reconcileCertificate requests the certificate of a built-in component from the project's issuer
+// and points the release values at its secret once issued. A renewed certificate changes the
+// values, rolling the component's pods.
+func (r *ProjectReconciler) reconcileCertificate(ctx context.Context, project *edgev1alpha1.Project,
+	p ComponentProvider, ref *edgev1alpha1.ComponentRef) error {
+	if project.Spec.TLS == nil || ref.Release == nil {
+		return nil
+	}
+	dnsNames := p.CertificateDNSNames(project)
+	if len(dnsNames) == 0 {
+		return nil
+	}
+
+	name := certificateName(project, p.Name())
+	component := fmt.Sprintf("%s-%s", p.Type(), p.Name())
+	if err := r.applyUnstructured(ctx, project, buildCertificate(project, name, dnsNames)); meta.IsNoMatchError(err) {
+		return fmt.Errorf("cert-manager CRDs are not installed: %w", err)
+	} else if err != nil {
+		return fmt.Errorf("failed to reconcile certificate %s: %w", name, err)
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: project.Namespace}, secret)
+	if errors.IsNotFound(err) || (err == nil && len(secret.Data[corev1.TLSCertKey]) == 0) {
+		return fmt.Errorf("%w: waiting for certificate %s", errDependencyNotReady, name)
+	} else if err != nil {
+		return fmt.Errorf("failed to get certificate secret %s: %w", name, err)
+	}
+
+	// cert-manager leaves the secret behind when the Certificate is deleted with the project
+	if !slices.ContainsFunc(secret.OwnerReferences, func(o metav1.OwnerReference) bool { return o.UID == project.UID }) {
+		patch := client.MergeFrom(secret.DeepCopy())
+		if err := controllerutil.SetOwnerReference(project, secret, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Patch(ctx, secret, patch); err != nil {
+			return fmt.Errorf("failed to own certificate secret %s: %w", name, err)
+		}
+	}
+
+	cert := issuedCertificate{secretName: name, revision: certificateRevision(secret)}
+	values := make(map[string]any)
+	if err := yaml.Unmarshal([]byte(ref.Release.ValuesContent), &values); err != nil {
+		return fmt.Errorf("error parsing %s values: %w", p.Name(), err)
+	}
+	if values == nil {
+		values = make(map[string]any)
+	}
+	p.InjectTLS(values, cert)
+	updatedValues, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	ref.Release.ValuesContent = string(updatedValues)
+
+	return r.recordCertificate(ctx, project, edgev1alpha1.CertificateStatus{
+		Component: component, Name: name, Revision: cert.revision})
+}
+
+// buildCertificate renders the Certificate of a component's DNS names
+func buildCertificate(project *edgev1alpha1.Project, name string, dnsNames []string) *unstructured.Unstructured {
+	tls := project.Spec.TLS
+	issuerKind := tls.IssuerRef.Kind
+	if issuerKind == "" {
+		issuerKind = "Issuer"
+	}
+	labels := map[string]any{
+		common.LabelManagedBy: "edge",
+		common.LabelProject:   project.Name,
+	}
+
+	spec := map[string]any{
+		"secretName": name,
+		// Labels the secret so that renewals are watched
+		"secretTemplate": map[string]any{"labels": labels},
+		"dnsNames":       toAnySlice(dnsNames),
+		"issuerRef": map[string]any{
+			"group": certManagerGroup,
+			"kind":  issuerKind,
+			"name":  tls.IssuerRef.Name,
+		},
+		"privateKey": map[string]any{"algorithm": "ECDSA", "size": int64(256), "rotationPolicy": "Always"},
+		"usages":     []any{"server auth", "digital signature", "key encipherment"},
+	}
+	if tls.Duration != nil {
+		spec["duration"] = tls.Duration.Duration.String()
+	}
+	if tls.RenewBefore != nil {
+		spec["renewBefore"] = tls.RenewBefore.Duration.String()
+	}
+
+	cert := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	cert.SetGroupVersionKind(certificateGVK)
+	cert.SetName(name)
+	cert.SetNamespace(project.Namespace)
+	cert.SetLabels(map[string]string{
+		common.LabelManagedBy: "edge",
+		common.LabelProject:   project.Name,
+	})
+	return cert
+}
+
+// recordCertificate records the revision of a component's certificate rolled out
+func (r *ProjectReconciler) recordCertificate(ctx context.Context, project *edgev1alpha1.Project,
+	cert edgev1alpha1.CertificateStatus) error {
+	i := slices.IndexFunc(project.Status.Certificates, func(c edgev1alpha1.CertificateStatus) bool {
+		return c.Name == cert.Name
+	})
+	if i >= 0 && project.Status.Certificates[i] == cert {
+		return nil
+	}
+
+	patch := client.MergeFrom(project.DeepCopy())
+	if i >= 0 {
+		r.event(project, corev1.EventTypeNormal, common.ReasonCertificateRenewed,
+			"Rolling out renewed certificate %s", cert.Name)
+		project.Status.Certificates[i] = cert
+	} else {
+		project.Status.Certificates = append(project.Status.Certificates, cert)
+	}
+	return r.patchStatus(ctx, project, patch)
+}
+
+// certificatesRenewed reports whether a certificate was issued since its revision was rolled out
+func (r *ProjectReconciler) certificatesRenewed(ctx context.Context, project *edgev1alpha1.Project) (bool, error) {
+	for _, cert := range project.Status.Certificates {
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: cert.Name, Namespace: project.Namespace}, secret)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return false, err
+		}
+		if len(secret.Data[corev1.TLSCertKey]) > 0 && certificateRevision(secret) != cert.Revision {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// pruneCertificates deletes the Certificates, and their secrets, of components removed from the
+// project or of a project whose TLS was turned off
+func (r *ProjectReconciler) pruneCertificates(ctx context.Context, project *edgev1alpha1.Project) error {
+	if len(project.Status.Certificates) == 0 {
+		return nil
+	}
+
+	desired := map[string]bool{}
+	if project.Spec.TLS != nil {
+		for _, p := range Providers() {
+			if ref := p.ComponentRef(&project.Spec); ref != nil && !ref.IsExternal() &&
+				len(p.CertificateDNSNames(project)) > 0 {
+				desired[certificateName(project, p.Name())] = true
+			}
+		}
+	}
+
+	kept := []edgev1alpha1.CertificateStatus{}
+	for _, cert := range project.Status.Certificates {
+		if desired[cert.Name] {
+			kept = append(kept, cert)
+			continue
+		}
+		log.FromContext(ctx).Info("Deleting certificate", "name", cert.Name)
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(certificateGVK)
+		obj.SetName(cert.Name)
+		obj.SetNamespace(project.Namespace)
+		if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return err
+		}
+		// cert-manager leaves the secret behind
+		secret := &corev1.Secret{}
+		secret.Name, secret.Namespace = cert.Name, project.Namespace
+		if err := r.Delete(ctx, secret); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	if len(kept) == len(project.Status.Certificates) {
+		return nil
+	}
+
+	patch := client.MergeFrom(project.DeepCopy())
+	project.Status.Certificates = kept
+	if len(kept) == 0 {
+		project.Status.Certificates = nil
+	}
+	return r.patchStatus(ctx, project, patch)
+}
+
+// certificateRevision identifies the certificate in an issued secret
+func certificateRevision(secret *corev1.Secret) string {
+	sum := sha256.Sum256(secret.Data[corev1.TLSCertKey])
+	return hex.EncodeToString(sum[:8])
+}
+
+// postgresSSLMode returns the sslmode of connections to the project's built-in PostgreSQL and the
+// CA verifying the server, if any. Certificates issued for the project are verified with
+// verify-full, the chart's self-signed certificate only encrypts with require.
+func (r *ProjectReconciler) postgresSSLMode(ctx context.Context, project *edgev1alpha1.Project) (string, []byte, error) {
+	if project.Spec.TLS == nil {
+		return "require", nil, nil
+	}
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: certificateName(project, "postgres"), Namespace: project.Namespace}, secret)
+	if errors.IsNotFound(err) {
+		return "require", nil, nil
+	} else if err != nil {
+		return "", nil, fmt.Errorf("failed to get PostgreSQL certificate: %w", err)
+	}
+	// Without a CA in the secret, e.g. from a public ACME issuer, the system roots verify
+	return "verify-full", secret.Data[certCAKey], nil
+}
+
+// unverifiedSSLMode maps the verify-ca and verify-full modes of a connection secret to require, for
+// components whose chart doesn't mount the secret's CA
+func unverifiedSSLMode(sslMode string) string {
+	if sslMode == "verify-ca" || sslMode == "verify-full" {
+		return "require"
+	}
+	return sslMode
+}
+
+// injectPostgresTLS points the Bitnami PostgreSQL chart at an issued certificate
+func injectPostgresTLS(values map[string]any, cert issuedCertificate) {
+	tls := childValues(values, "tls")
+	tls["enabled"] = true
+	tls["autoGenerated"] = false
+	tls["certificatesSecret"] = cert.secretName
+	tls["certFilename"] = corev1.TLSCertKey
+	tls["certKeyFilename"] = corev1.TLSPrivateKeyKey
+	// The chart requires client certificates if a CA file is set
+	delete(tls, "certCAFilename")
+
+	// PostgreSQL reads the certificate on start, a new revision restarts the pods
+	for _, key := range []string{"primary", "readReplicas"} {
+		childValues(childValues(values, key), "podAnnotations")[common.AnnotationCertRevision] = cert.revision
+	}
+}
+
+// serviceDNSNames returns the in-cluster DNS names of the project's services
+func serviceDNSNames(project *edgev1alpha1.Project, services ...string) []string {
+	names := []string{}
+	for _, service := range services {
+		names = append(names, service,
+			fmt.Sprintf("%s.%s", service, project.Namespace),
+			fmt.Sprintf("%s.%s.svc", service, project.Namespace),
+			fmt.Sprintf("%s.%s.svc.cluster.local", service, project.Namespace))
+	}
+	return names
+}
+
+// childValues returns the nested values under key, creating them if missing
+func childValues(values map[string]any, key string) map[string]any {
+	child, ok := values[key].(map[string]any)
+	if !ok {
+		child = make(map[string]any)
+		values[key] = child
+	}
+	return child
+}
+
+func toAnySlice(s []string) []any {
+	out := make([]any, len(s))
+	for i, v := range s {
+		out[i] = v
+	}
+	return out
+}
+
+// projectForCertificateSecret maps the secret of a certificate issued for a project to the project,
+// so that renewals are rolled out
+func (r *ProjectReconciler) projectForCertificateSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	project := obj.GetLabels()[common.LabelProject]
+	if project == "" || obj.GetLabels()[common.LabelManagedBy] != "edge" ||
+		obj.GetAnnotations()[annotationCertificateName] == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: project, Namespace: obj.GetNamespace()}}}
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+func TestBuildCertificate(t *testing.T) {
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+	project.Spec.TLS = &edgev1alpha1.TLS{
+		IssuerRef:   edgev1alpha1.IssuerRef{Name: "self-signed", Kind: "ClusterIssuer"},
+		RenewBefore: &metav1.Duration{Duration: 720 * time.Hour},
+	}
+
+	dnsNames := postgresProvider{}.CertificateDNSNames(project)
+	cert := buildCertificate(project, certificateName(project, "postgres"), dnsNames)
+	if cert.GetName() != "demo-postgres-tls" || cert.GetKind() != "Certificate" {
+		t.Errorf("unexpected certificate %s/%s", cert.GetKind(), cert.GetName())
+	}
+	if secretName, _, _ := unstructured.NestedString(cert.Object, "spec", "secretName"); secretName != "demo-postgres-tls" {
+		t.Errorf("unexpected secret %q", secretName)
+	}
+	if kind, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "kind"); kind != "ClusterIssuer" {
+		t.Errorf("unexpected issuer kind %q", kind)
+	}
+	if renewBefore, _, _ := unstructured.NestedString(cert.Object, "spec", "renewBefore"); renewBefore != "720h0m0s" {
+		t.Errorf("unexpected renewBefore %q", renewBefore)
+	}
+	names, _, _ := unstructured.NestedStringSlice(cert.Object, "spec", "dnsNames")
+	found := false
+	for _, name := range names {
+		found = found || name == "demo-postgres-postgresql-primary.apps.svc.cluster.local"
+	}
+	if !found {
+		t.Errorf("primary service missing from DNS names %v", names)
+	}
+}
+
+func TestInjectPostgresTLS(t *testing.T) {
+	values := map[string]any{"tls": map[string]any{"autoGenerated": true, "certCAFilename": "ca.crt"}}
+	injectPostgresTLS(values, issuedCertificate{secretName: "demo-postgres-tls", revision: "abc"})
+
+	tls := values["tls"].(map[string]any)
+	if tls["enabled"] != true || tls["autoGenerated"] != false || tls["certificatesSecret"] != "demo-postgres-tls" ||
+		tls["certFilename"] != "tls.crt" || tls["certKeyFilename"] != "tls.key" {
+		t.Errorf("unexpected tls values %v", tls)
+	}
+	if _, ok := tls["certCAFilename"]; ok {
+		t.Error("certCAFilename would require client certificates")
+	}
+	for _, key := range []string{"primary", "readReplicas"} {
+		annotations, _, _ := unstructured.NestedStringMap(values, key, "podAnnotations")
+		if annotations[common.AnnotationCertRevision] != "abc" {
+			t.Errorf("%s pods not annotated with the certificate revision: %v", key, annotations)
+		}
+	}
+}
+
+func TestSuperuserConnConfig(t *testing.T) {
+	project := &edgev1alpha1.Project{}
+	project.Name, project.Namespace = "demo", "apps"
+	secret := &corev1.Secret{Data: map[string][]byte{
+		"PGHOST":     []byte("demo-postgres-postgresql-primary.apps.svc.cluster.local"),
+		"PGPORT":     []byte("5432"),
+		"PGUSER":     []byte("postgres"),
+		"PGPASSWORD": []byte("secret"),
+		"PGDATABASE": []byte("postgres"),
+	}}
+
+	config, err := superuserConnConfig(project, secret, "main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Database != "main" || config.TLSConfig == nil || !config.TLSConfig.InsecureSkipVerify {
+		t.Errorf("expected an unverified connection to main, got %+v", config.Config)
+	}
+
+	secret.Data["PGSSLMODE"] = []byte("verify-full")
+	config, err = superuserConnConfig(project, secret, "postgres")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.TLSConfig == nil || config.TLSConfig.InsecureSkipVerify ||
+		config.TLSConfig.ServerName != "demo-postgres-postgresql-primary.apps.svc.cluster.local" {
+		t.Error("verify-full should verify the primary's name")
+	}
+
+	if unverifiedSSLMode("verify-full") != "require" || unverifiedSSLMode("prefer") != "prefer" {
+		t.Error("unexpected unverified sslmode")
+	}
+}
+
+func TestCertificateSecretRequests(t *testing.T) {
+	r := &ProjectReconciler{}
+	mapFn := withOwnerProject(r.projectForCertificateSecret)
+	demo := types.NamespacedName{Name: "demo", Namespace: "apps"}
+
+	// A generated credential secret is mapped to its owner
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "demo-pguser-postgres", Namespace: "apps",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: edgev1alpha1.GroupVersion.String(), Kind: "Project",
+			Name: "demo", UID: "uid", Controller: ptr.To(true)}}}}
+	if requests := mapFn(context.Background(), secret); len(requests) != 1 || requests[0].NamespacedName != demo {
+		t.Errorf("got requests %v for an owned secret", requests)
+	}
+
+	// An issued certificate's secret, also owned by the project, is requested once
+	secret.Labels = map[string]string{common.LabelProject: "demo", common.LabelManagedBy: "edge"}
+	secret.Annotations = map[string]string{annotationCertificateName: "demo-postgres-tls"}
+	if requests := mapFn(context.Background(), secret); len(requests) != 1 || requests[0].NamespacedName != demo {
+		t.Errorf("got requests %v for a certificate secret", requests)
+	}
+
+	// Other owners and unrelated secrets aren't mapped
+	secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "apps",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "demo",
+			UID: "uid", Controller: ptr.To(true)}}}}
+	if requests := mapFn(context.Background(), secret); len(requests) != 0 {
+		t.Errorf("got requests %v for an unrelated secret", requests)
+	}
+}
@@ -4,15 +4,15 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"os"
+	"strings"
 	"time"
 
 	edgev1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/metrics"
 	"github.com/edgeflare/pgo/pkg/pgx/role"
 	"github.com/edgeflare/pgo/pkg/util/rand"
 	rnd "github.com/edgeflare/pgo/pkg/util/rand"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -22,37 +22,6 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
-// reconcileAuth reconciles the auth component for a project.
-// It handles both external and built-in auth providers, with special logic for Zitadel.
-// For Zitadel, it ensures required secrets exist before proceeding with the Helm release.
-func (r *ProjectReconciler) reconcileAuth(ctx context.Context, project *edgev1alpha1.Project,
-	name string, ref *edgev1alpha1.ComponentRef) error {
-	logger := log.FromContext(ctx)
-	compType := "auth"
-	logger.Info("Reconciling auth", "component", name)
-
-	// Handle external auth components
-	if ref.IsExternal() {
-		if err := r.verifyExternalIdPSecret(ctx, project, name, ref); err != nil {
-			logger.Error(err, "External auth secret verification failed")
-			_ = r.updateComponentStatus(ctx, project, compType, name, false,
-				fmt.Sprintf("Secret error: %v", err), "")
-			return err
-		}
-		return r.handleExternalComponent(ctx, project, compType, name, ref)
-	}
-
-	// Handle Zitadel specifically
-	if name == "zitadel" {
-		if err := r.reconcileZitadel(ctx, project, name, ref); err != nil {
-			return err
-		}
-	}
-
-	// Process the component release
-	return r.handleComponentRelease(ctx, project, compType, name, ref)
-}
-
 // reconcileZitadel handles the specific requirements for the Zitadel auth component.
 // It ensures all required secrets exist and dependencies are ready before proceeding.
 func (r *ProjectReconciler) reconcileZitadel(ctx context.Context, project *edgev1alpha1.Project,
@@ -60,28 +29,19 @@ func (r *ProjectReconciler) reconcileZitadel(ctx context.Context, project *edgev
 	logger := log.FromContext(ctx)
 	compType := "auth"
 
-	// Load and parse schema for validation
-	zitadelSchema, err := os.ReadFile("hack/zitadel.values.schema.json")
-	if err != nil {
-		logger.Error(err, "Failed to load Zitadel schema")
+	if err := r.ensureZitadelInstanceSecret(ctx, project); err != nil {
 		return err
 	}
 
-	if err = r.ensureZitadelInstanceSecret(ctx, project); err != nil {
+	// Parse the supplied values
+	zitadelValues := make(map[string]any)
+	if err := yaml.Unmarshal([]byte(ref.Release.ValuesContent), &zitadelValues); err != nil {
+		logger.Error(err, "Failed to parse Zitadel values")
+		_ = r.updateComponentStatus(ctx, project, compType, name, false,
+			fmt.Sprintf("Values error: %v", err), "")
 		return err
 	}
-
-	// Parse the supplied values with schema validation
-	var zitadelValues map[string]any
-	if ref.Release.ValuesContent != "" {
-		zitadelValues, err = r.parseValues(ref.Release.ValuesContent, zitadelSchema)
-		if err != nil {
-			logger.Error(err, "Failed to parse Zitadel values")
-			_ = r.updateComponentStatus(ctx, project, compType, name, false,
-				fmt.Sprintf("Values error: %v", err), "")
-			return err
-		}
-	} else {
+	if zitadelValues == nil {
 		zitadelValues = make(map[string]any)
 	}
 
@@ -95,7 +55,7 @@ func (r *ProjectReconciler) reconcileZitadel(ctx context.Context, project *edgev
 	}
 
 	// Step 2: Wait for PostgreSQL to be ready
-	if err := r.waitForPostgreSQLReady(ctx, project); err != nil {
+	if err := r.waitForPostgreSQLReady(ctx, project, capabilityCreateRole); err != nil {
 		logger.Error(err, "PostgreSQL is not ready")
 		_ = r.updateComponentStatus(ctx, project, compType, name, false,
 			fmt.Sprintf("Database error: %v", err), "")
@@ -169,29 +129,33 @@ func (r *ProjectReconciler) reconcileZitadelMasterkey(ctx context.Context, proje
 	return masterkeySecretName, nil
 }
 
-// waitForPostgreSQLReady waits for the PostgreSQL database to be available.
+// waitForPostgreSQLReady waits for the PostgreSQL database to be available and to have the
+// capabilities the calling component requires.
 // It uses exponential backoff to retry connections until success or timeout.
-func (r *ProjectReconciler) waitForPostgreSQLReady(ctx context.Context, project *edgev1alpha1.Project) error {
+func (r *ProjectReconciler) waitForPostgreSQLReady(ctx context.Context, project *edgev1alpha1.Project,
+	capabilities ...databaseCapability) error {
 	logger := log.FromContext(ctx)
-	pgSuperuserSecretName := fmt.Sprintf("%s-pguser-postgres", project.Name)
 
-	// Get PostgreSQL admin connection secret
-	pgSecret := &corev1.Secret{}
-	if err := r.Get(ctx, types.NamespacedName{
-		Name:      pgSuperuserSecretName,
-		Namespace: project.Namespace,
-	}, pgSecret); err != nil {
-		return fmt.Errorf("failed to get PostgreSQL secret %s: %w", pgSuperuserSecretName, err)
+	// Don't attempt to connect before the database component reports ready
+	if status := project.Status.ComponentStatuses["database-postgres"]; !status.Ready {
+		return fmt.Errorf("%w: waiting for PostgreSQL", errDependencyNotReady)
+	}
+	if err := requireDatabaseCapabilities(project, capabilities...); err != nil {
+		return err
+	}
+	if restorePending(project) {
+		return fmt.Errorf("%w: waiting for the restore of backup %s", errDependencyNotReady,
+			project.Spec.Database.RestoreFrom.Backup)
 	}
 
-	// Create connection string
-	serviceName := fmt.Sprintf("%s-postgres-postgresql-primary.%s.svc.cluster.local",
-		project.Name, project.Namespace)
-	password := string(pgSecret.Data["PGPASSWORD"])
-	connString := fmt.Sprintf("host=%s port=5432 user=postgres password=%s dbname=postgres sslmode=require",
-		serviceName, password)
+	// Connect as the built-in PostgreSQL's superuser or with the external one's credentials
+	ref := postgresRef(project)
+	config, err := r.postgresDatabaseConnConfig(ctx, project, ref, "postgres")
+	if err != nil {
+		return err
+	}
 
-	conn, err := r.connectWithRetry(ctx, connString)
+	conn, err := r.connectWithRetry(ctx, config)
 	if err != nil {
 		return err
 	}
@@ -204,7 +168,7 @@ func (r *ProjectReconciler) waitForPostgreSQLReady(ctx context.Context, project
 }
 
 // connectWithRetry attempts to connect to PostgreSQL with exponential backoff.
-func (r *ProjectReconciler) connectWithRetry(ctx context.Context, connString string) (*pgx.Conn, error) {
+func (r *ProjectReconciler) connectWithRetry(ctx context.Context, config *pgx.ConnConfig) (*pgx.Conn, error) {
 	var conn *pgx.Conn
 	var err error
 
@@ -217,7 +181,7 @@ func (r *ProjectReconciler) connectWithRetry(ctx context.Context, connString str
 	logger := log.FromContext(ctx)
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		// Try to connect
-		conn, err = pgx.Connect(ctx, connString)
+		conn, err = pgx.ConnectConfig(ctx, config)
 		if err == nil {
 			logger.Info("Successfully connected to PostgreSQL", "attempt", attempt)
 			return conn, nil
@@ -241,6 +205,7 @@ func (r *ProjectReconciler) connectWithRetry(ctx context.Context, connString str
 		// Wait before next attempt using exponential backoff
 		select {
 		case <-time.After(backoff):
+			metrics.PostgresConnectionRetries.Inc()
 			// Increase backoff for next attempt, but don't exceed maxBackoff
 			backoff = time.Duration(math.Min(float64(backoff*2), float64(maxBackoff)))
 		case <-ctx.Done():
@@ -297,7 +262,7 @@ func (r *ProjectReconciler) ensureZitadelMasterkeySecret(ctx context.Context,
 			},
 		}
 
-		return r.Create(ctx, secret)
+		return r.createSecret(ctx, project, secret)
 	}
 
 	return err
@@ -335,11 +300,110 @@ func (r *ProjectReconciler) updateZitadelValuesWithMasterkey(values map[string]a
 	delete(zitadel, "masterkey")
 }
 
-// verifyExternalIdPSecret verifies that an external IdP secret exists and has the required keys.
-func (r *ProjectReconciler) verifyExternalIdPSecret(ctx context.Context,
-	project *edgev1alpha1.Project, name string, ref *edgev1alpha1.ComponentRef) error {
-	// External IdP secret verification would be implemented here
-	return nil
+// Zitadel's defaults for the external address it names itself by. The chart enables ExternalSecure
+const (
+	zitadelDefaultExternalDomain = "localhost"
+	zitadelDefaultExternalPort   = 8080
+)
+
+// zitadelServiceURL returns the in-cluster URL Zitadel is reached at
+func zitadelServiceURL(project *edgev1alpha1.Project) string {
+	return fmt.Sprintf("http://%s-zitadel.%s.svc.cluster.local:8080", project.Name, project.Namespace)
+}
+
+// zitadelIssuerURL returns the OIDC issuer of Zitadel, derived from ExternalDomain, ExternalPort and
+// ExternalSecure in configmapConfig. Zitadel names itself by its external address whichever URL it's
+// reached at, so unset values fall back to Zitadel's defaults rather than the in-cluster service.
+func zitadelIssuerURL(valuesContent string) string {
+	values := make(map[string]any)
+	_ = yaml.Unmarshal([]byte(valuesContent), &values)
+
+	config := map[string]any{}
+	if zitadel, ok := values["zitadel"].(map[string]any); ok {
+		if cc, ok := zitadel["configmapConfig"].(map[string]any); ok {
+			config = cc
+		}
+	}
+
+	domain, _ := config["ExternalDomain"].(string)
+	if domain == "" {
+		domain = zitadelDefaultExternalDomain
+	}
+
+	scheme := "https"
+	if secure, ok := config["ExternalSecure"].(bool); ok && !secure {
+		scheme = "http"
+	}
+
+	// yaml numbers are decoded as float64
+	port := zitadelDefaultExternalPort
+	if p, ok := config["ExternalPort"].(float64); ok && p != 0 {
+		port = int(p)
+	}
+	if (scheme == "https" && port == 443) || (scheme == "http" && port == 80) {
+		return fmt.Sprintf("%s://%s", scheme, domain)
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, domain, port)
+}
+
+// verifyExternalIdP verifies an external identity provider against its secret, which holds the
+// issuer, client-id, client-secret and optionally audience keys. The issuer must serve an OIDC
+// discovery document and a non-empty JWKS, and the client credentials must not be rejected by a
+// client credentials token request. The issuer and JWKS URI are returned for the component's status.
+func (r *ProjectReconciler) verifyExternalIdP(ctx context.Context, project *edgev1alpha1.Project,
+	ref *edgev1alpha1.ComponentRef) (edgev1alpha1.ComponentStatus, error) {
+	logger := log.FromContext(ctx)
+	status := edgev1alpha1.ComponentStatus{}
+
+	secretName := ref.GetSecretName()
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, secret)
+	if errors.IsNotFound(err) {
+		return status, fmt.Errorf("required external identity provider secret %s not found", secretName)
+	} else if err != nil {
+		return status, fmt.Errorf("failed to get external identity provider secret: %w", err)
+	}
+
+	missingFields := []string{}
+	for _, field := range []string{idpIssuerKey, idpClientIDKey, idpClientSecretKey} {
+		if len(secret.Data[field]) == 0 {
+			missingFields = append(missingFields, field)
+		}
+	}
+	if len(missingFields) > 0 {
+		return status, fmt.Errorf("external identity provider secret %s is missing required fields: %s",
+			secretName, strings.Join(missingFields, ", "))
+	}
+
+	issuer := string(secret.Data[idpIssuerKey])
+	discovery, err := discoverOIDC(ctx, issuer, issuer)
+	if err != nil {
+		return status, err
+	}
+	if _, err := fetchKeySet(ctx, discovery.JWKSURI, ""); err != nil {
+		return status, err
+	}
+
+	status.Endpoint = issuer
+	status.Issuer = issuer
+	status.JWKSURI = discovery.JWKSURI
+	status.Message = "Verified external identity provider"
+
+	if discovery.TokenEndpoint == "" {
+		status.Message += ", client credentials not confirmed: no token endpoint"
+		return status, nil
+	}
+	confirmed, err := verifyClientCredentials(ctx, discovery.TokenEndpoint, string(secret.Data[idpClientIDKey]),
+		string(secret.Data[idpClientSecretKey]), string(secret.Data[idpAudienceKey]))
+	if err != nil {
+		return status, fmt.Errorf("client %s: %w", secret.Data[idpClientIDKey], err)
+	}
+	if !confirmed {
+		logger.Info("Client credentials grant not allowed, credentials not confirmed", "issuer", issuer)
+		status.Message += ", client credentials not confirmed: grant not allowed"
+	}
+
+	return status, nil
 }
 
 func (r *ProjectReconciler) ensureZitadelInstanceSecret(ctx context.Context, project *edgev1alpha1.Project) error {
@@ -370,7 +434,7 @@ func (r *ProjectReconciler) ensureZitadelInstanceSecret(ctx context.Context, pro
 				"ZITADEL_FIRSTINSTANCE_ORG_HUMAN_PASSWORD": rnd.NewPassword(16),
 			},
 		}
-		return r.Create(ctx, firstInstanceSecret)
+		return r.createSecret(ctx, project, firstInstanceSecret)
 	} else if err != nil {
 		// Handle other errors
 		return err
@@ -382,153 +446,14 @@ func (r *ProjectReconciler) ensureZitadelInstanceSecret(ctx context.Context, pro
 
 // ensureZitadelPostgresSecretAndRole ensures a Zitadel PostgreSQL user exists and creates/updates the corresponding secret
 func (r *ProjectReconciler) ensureZitadelPostgresSecretAndRole(ctx context.Context, project *edgev1alpha1.Project, secretName string) error {
-	logger := log.FromContext(ctx)
-	// Create a new context with timeout for database operations
-	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	// 1. Get the PostgreSQL superuser secret
-	pgSuperuserSecret := &corev1.Secret{}
-	pgSuperuserSecretName := fmt.Sprintf("%s-pguser-postgres", project.Name)
-	if err := r.Get(ctx, types.NamespacedName{
-		Name:      pgSuperuserSecretName,
-		Namespace: project.Namespace}, pgSuperuserSecret); err != nil {
-		return fmt.Errorf("failed to get PostgreSQL superuser secret: %w", err)
-	}
-
-	// Validate required secret data exists
-	requiredFields := []string{"PGHOST", "PGPORT", "PGPASSWORD"}
-	for _, field := range requiredFields {
-		if _, exists := pgSuperuserSecret.Data[field]; !exists {
-			return fmt.Errorf("PostgreSQL superuser secret missing required field: %s", field)
-		}
-	}
-
-	// 2. Extract connection info
-	pgHost := string(pgSuperuserSecret.Data["PGHOST"])
-	pgPort := string(pgSuperuserSecret.Data["PGPORT"])
-	pgSuperPassword := string(pgSuperuserSecret.Data["PGPASSWORD"])
-
-	// Define Zitadel user credentials
-	zitadelUser := "zitadel"
-	zitadelPassword := newAlphaNumericPassword(16)
-	zitadelDatabase := "main"
-
-	// 3. Build connection strings
-	serviceName := fmt.Sprintf("%s-postgres-postgresql-primary.%s.svc.cluster.local",
-		project.Name, project.Namespace)
-
-	// Connection string for superuser
-	superUserConnString := fmt.Sprintf("host=%s port=%s user=postgres password=%s dbname=postgres sslmode=require",
-		serviceName, pgPort, pgSuperPassword)
-
-	// Connection string for Zitadel user
-	zitadelConnString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=require",
-		pgHost, pgPort, zitadelUser, zitadelPassword, zitadelDatabase)
-
-	// 4. Prepare secret data
-	secretData := map[string][]byte{
-		"PGDATABASE":  []byte(zitadelDatabase),
-		"PGHOST":      []byte(pgHost),
-		"PGPASSWORD":  []byte(zitadelPassword),
-		"PGPORT":      []byte(pgPort),
-		"PGSSLMODE":   []byte("require"),
-		"PGUSER":      []byte(zitadelUser),
-		"conn-string": []byte(zitadelConnString),
-	}
-
-	// 5. Check if the Zitadel PostgreSQL secret already exists and handle accordingly
-	zitadelPgSecret := &corev1.Secret{}
-	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: project.Namespace}, zitadelPgSecret)
-
-	// Handle secret creation/update
-	if err != nil {
-		if !errors.IsNotFound(err) {
-			return fmt.Errorf("error getting Zitadel PostgreSQL secret: %w", err)
-		}
-
-		// Secret doesn't exist, create it
-		newSecret := &corev1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      secretName,
-				Namespace: project.Namespace,
-				OwnerReferences: []metav1.OwnerReference{
-					{
-						APIVersion: project.APIVersion,
-						Kind:       project.Kind,
-						Name:       project.Name,
-						UID:        project.UID,
-						Controller: ptr.To(true),
-					},
-				},
-			},
-			Type: corev1.SecretTypeOpaque,
-			Data: secretData,
-		}
-		if err := r.Create(ctx, newSecret); err != nil {
-			return fmt.Errorf("failed to create Zitadel PostgreSQL secret: %w", err)
-		}
-		logger.Info("Created Zitadel PostgreSQL secret", "name", secretName, "namespace", project.Namespace)
-	} else {
-		// Secret exists, update it if needed
-		zitadelPgSecret.Data = secretData
-		if err := r.Update(ctx, zitadelPgSecret); err != nil {
-			return fmt.Errorf("failed to update Zitadel PostgreSQL secret: %w", err)
-		}
-		logger.Info("Updated Zitadel PostgreSQL secret", "name", secretName, "namespace", project.Namespace)
-	}
-
-	// 6. Connect to PostgreSQL with retry
-	pool, err := pgConnectWithRetry(dbCtx, superUserConnString, 5, 2*time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
-	}
-	defer pool.Close()
-
-	// 7. Create or update the PostgreSQL role
 	zitadelPGRole := role.Role{
-		Name:      zitadelUser,
+		Name:      "zitadel",
 		CanLogin:  true,
-		Password:  zitadelPassword,
 		CreateDB:  true, // Allow to create its own database
 		Inherit:   true,
 		ConnLimit: 100, // Set a reasonable connection limit
 	}
-
-	existingRole, err := role.Get(dbCtx, pool, zitadelPGRole.Name)
-	if err != nil {
-		if err == role.ErrRoleNotFound {
-			// Role doesn't exist, create it
-			if err := role.Create(dbCtx, pool, zitadelPGRole); err != nil {
-				return fmt.Errorf("failed to create Zitadel PostgreSQL role: %w", err)
-			}
-			logger.Info("Created Zitadel PostgreSQL role", "name", zitadelUser)
-		} else {
-			return fmt.Errorf("error checking for existing PostgreSQL role: %w", err)
-		}
-	} else {
-		// Role exists, update it
-		logger.Info("Existing role found, updating", "name", existingRole.Name, "oid", existingRole.OID)
-		if err := role.Update(dbCtx, pool, zitadelPGRole); err != nil {
-			return fmt.Errorf("failed to update Zitadel PostgreSQL role: %w", err)
-		}
-		logger.Info("Updated Zitadel PostgreSQL role", "name", zitadelUser)
-	}
-
-	// 8. Ensure database exists
-	if _, err := pool.Exec(dbCtx, "CREATE DATABASE main;"); err != nil {
-		// Check for specific PostgreSQL error code for "database already exists" (42P04)
-		pgErr, isPgError := err.(*pgconn.PgError)
-		if isPgError && pgErr.Code == "42P04" {
-			logger.Info("Database already exists", "database", "main")
-		} else {
-			return fmt.Errorf("failed to create database: %w", err)
-		}
-	} else {
-		logger.Info("Created database", "database", "main")
-	}
-
-	return nil
+	return r.ensurePostgresRoleSecret(ctx, project, secretName, zitadelPGRole, projectDatabase, false)
 }
 
 // Add this helper function to your controller package
@@ -0,0 +1,30 @@
+package controller
+
+import "testing"
+
+func TestZitadelIssuerURL(t *testing.T) {
+	tests := []struct {
+		values string
+		want   string
+	}{
+		// Zitadel names itself by its default external address, not the in-cluster service
+		{values: "", want: "https://localhost:8080"},
+		{
+			values: "zitadel:\n  configmapConfig:\n    ExternalDomain: iam.example.com\n    ExternalPort: 443\n    ExternalSecure: true\n",
+			want:   "https://iam.example.com",
+		},
+		{
+			values: "zitadel:\n  configmapConfig:\n    ExternalDomain: iam.example.local\n    ExternalSecure: false\n",
+			want:   "http://iam.example.local:8080",
+		},
+		{
+			values: "zitadel:\n  configmapConfig:\n    ExternalPort: 80\n    ExternalSecure: false\n",
+			want:   "http://localhost",
+		},
+	}
+	for _, tt := range tests {
+		if got := zitadelIssuerURL(tt.values); got != tt.want {
+			t.Errorf("got issuer %s, want %s", got, tt.want)
+		}
+	}
+}
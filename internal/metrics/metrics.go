@@ -0,0 +1,107 @@
+// Package metrics registers the operator's Prometheus metrics with the controller-runtime registry,
+// served on the manager's metrics endpoint.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "edge"
+
+var (
+	// ComponentReconcileDuration observes the reconciliation of a project component
+	ComponentReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "component_reconcile_duration_seconds",
+		Help:      "Duration of the reconciliation of a project component.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+	}, []string{"component"})
+
+	// ComponentReconcileErrors counts failed reconciliations of a project component. Waiting for a
+	// dependency isn't counted
+	ComponentReconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "component_reconcile_errors_total",
+		Help:      "Number of failed reconciliations of a project component.",
+	}, []string{"component"})
+
+	// ComponentReady is 1 if a project's component is ready, 0 otherwise
+	ComponentReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "component_ready",
+		Help:      "Whether a component of a project is ready.",
+	}, []string{"namespace", "project", "component"})
+
+	// ProjectReady is 1 if all components of a project are ready, 0 otherwise
+	ProjectReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "project_ready",
+		Help:      "Whether all components of a project are ready.",
+	}, []string{"namespace", "project"})
+
+	// HelmOperationDuration observes Helm installs, upgrades and uninstalls
+	HelmOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "helm_operation_duration_seconds",
+		Help:      "Duration of Helm operations, including pulling the chart.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+	}, []string{"operation"})
+
+	// HelmOperationFailures counts failed Helm operations
+	HelmOperationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "helm_operation_failures_total",
+		Help:      "Number of failed Helm operations.",
+	}, []string{"operation"})
+
+	// PostgresConnectionRetries counts retried connection attempts to a project's PostgreSQL
+	PostgresConnectionRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "postgres_connection_retries_total",
+		Help:      "Number of retried connection attempts to PostgreSQL.",
+	})
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(
+		ComponentReconcileDuration,
+		ComponentReconcileErrors,
+		ComponentReady,
+		ProjectReady,
+		HelmOperationDuration,
+		HelmOperationFailures,
+		PostgresConnectionRetries,
+	)
+}
+
+// ObserveHelmOperation records the duration of a Helm operation started at start, and its failure
+// if err is set
+func ObserveHelmOperation(operation string, start time.Time, err error) {
+	HelmOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		HelmOperationFailures.WithLabelValues(operation).Inc()
+	}
+}
+
+// SetReady sets gauge to 1 if ready, 0 otherwise
+func SetReady(gauge prometheus.Gauge, ready bool) {
+	if ready {
+		gauge.Set(1)
+		return
+	}
+	gauge.Set(0)
+}
+
+// DeleteProject removes the readiness series of a deleted project
+func DeleteProject(namespace, project string) {
+	ProjectReady.DeleteLabelValues(namespace, project)
+	ComponentReady.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "project": project})
+}
+
+// DeleteComponent removes the readiness series of a component removed from a project
+func DeleteComponent(namespace, project, component string) {
+	ComponentReady.DeleteLabelValues(namespace, project, component)
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveHelmOperation(t *testing.T) {
+	ObserveHelmOperation("upgrade", time.Now(), nil)
+	ObserveHelmOperation("upgrade", time.Now(), errors.New("timed out"))
+
+	if got := testutil.ToFloat64(HelmOperationFailures.WithLabelValues("upgrade")); got != 1 {
+		t.Errorf("got %v upgrade failures, want 1", got)
+	}
+	if got := testutil.CollectAndCount(HelmOperationDuration); got != 1 {
+		t.Errorf("got %d duration series, want 1", got)
+	}
+}
+
+func TestDeleteProject(t *testing.T) {
+	SetReady(ProjectReady.WithLabelValues("default", "demo"), true)
+	SetReady(ComponentReady.WithLabelValues("default", "demo", "postgres"), false)
+	SetReady(ComponentReady.WithLabelValues("default", "demo", "zitadel"), true)
+	SetReady(ComponentReady.WithLabelValues("default", "other", "postgres"), true)
+
+	if got := testutil.ToFloat64(ComponentReady.WithLabelValues("default", "demo", "zitadel")); got != 1 {
+		t.Errorf("got readiness %v, want 1", got)
+	}
+
+	DeleteProject("default", "demo")
+	if got := testutil.CollectAndCount(ProjectReady); got != 0 {
+		t.Errorf("got %d project series, want 0", got)
+	}
+	if got := testutil.CollectAndCount(ComponentReady); got != 1 {
+		t.Errorf("got %d component series, want 1", got)
+	}
+}
+
+func TestDeleteComponent(t *testing.T) {
+	ComponentReady.Reset()
+	SetReady(ComponentReady.WithLabelValues("default", "demo", "postgres"), true)
+	SetReady(ComponentReady.WithLabelValues("default", "demo", "zitadel"), true)
+
+	DeleteComponent("default", "demo", "zitadel")
+	if got := testutil.CollectAndCount(ComponentReady); got != 1 {
+		t.Errorf("got %d component series, want 1", got)
+	}
+}
@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 	"sigs.k8s.io/yaml"
 )
 
@@ -25,8 +30,18 @@ type ReleaseSpec struct {
 type Client struct {
 	env      *cli.EnvSettings
 	registry *registry.Client
+	// Observer, if set, is called once an install, upgrade or uninstall finished, e.g. to record
+	// metrics of the operation
+	Observer func(operation string, start time.Time, err error)
 }
 
+// Operations reported to a Client's Observer
+const (
+	OperationInstall   = "install"
+	OperationUpgrade   = "upgrade"
+	OperationUninstall = "uninstall"
+)
+
 // NewClient returns a new helm registry client
 func NewClient() (*Client, error) {
 	env := cli.New()
@@ -41,6 +56,12 @@ func NewClient() (*Client, error) {
 	return &Client{env: env, registry: reg}, nil
 }
 
+func (c *Client) observe(operation string, start time.Time, err error) {
+	if c.Observer != nil {
+		c.Observer(operation, start, err)
+	}
+}
+
 func (c *Client) newActionConfig(namespace string) (*action.Configuration, error) {
 	cfg := new(action.Configuration)
 	if err := cfg.Init(c.env.RESTClientGetter(), namespace, os.Getenv("HELM_DRIVER"),
@@ -57,7 +78,7 @@ func (c *Client) newActionConfig(namespace string) (*action.Configuration, error
 }
 
 // Install installs a chart release, or upgrades if it already exists
-func (c *Client) Install(ctx context.Context, rel ReleaseSpec) (*release.Release, error) {
+func (c *Client) Install(ctx context.Context, rel ReleaseSpec) (result *release.Release, err error) {
 	cfg, err := c.newActionConfig(rel.Namespace)
 	if err != nil {
 		return nil, err
@@ -71,6 +92,12 @@ func (c *Client) Install(ctx context.Context, rel ReleaseSpec) (*release.Release
 	history, err := c.getReleaseHistory(rel.Name, rel.Namespace)
 	exists := err == nil && len(history) > 0
 
+	operation := OperationInstall
+	if exists {
+		operation = OperationUpgrade
+	}
+	defer func(start time.Time) { c.observe(operation, start, err) }(time.Now())
+
 	var chartPath string
 	if exists {
 		upgrade := action.NewUpgrade(cfg)
@@ -91,6 +118,15 @@ func (c *Client) Install(ctx context.Context, rel ReleaseSpec) (*release.Release
 		return nil, fmt.Errorf("chart loading failed: %w", err)
 	}
 
+	// Validate before any install is attempted, falling back to the embedded schema
+	schema := chart.Schema
+	if len(schema) == 0 {
+		schema = EmbeddedValuesSchema(chart.Name())
+	}
+	if err := ValidateValues(rel.ValuesContent, schema); err != nil {
+		return nil, err
+	}
+
 	values, err := parseYAMLValues(rel.ValuesContent)
 	if err != nil {
 		return nil, fmt.Errorf("values parsing failed: %w", err)
@@ -108,16 +144,74 @@ func (c *Client) Install(ctx context.Context, rel ReleaseSpec) (*release.Release
 	return install.Run(chart, values)
 }
 
-func (c *Client) Uninstall(ctx context.Context, name, namespace string) error {
+// Uninstall uninstalls a release. Resources of keepKinds, e.g. PersistentVolumeClaim, are left in
+// the cluster and adopted by a later release of the same name.
+func (c *Client) Uninstall(ctx context.Context, name, namespace string, keepKinds ...string) error {
 	cfg, err := c.newActionConfig(namespace)
 	if err != nil {
 		return err
 	}
 
+	if len(keepKinds) > 0 {
+		if err := keepResources(cfg, name, keepKinds); err != nil {
+			return fmt.Errorf("failed to mark resources to keep: %w", err)
+		}
+	}
+
+	start := time.Now()
 	_, err = action.NewUninstall(cfg).Run(name)
+	c.observe(OperationUninstall, start, err)
 	return err
 }
 
+// keepResources annotates the resources of keepKinds in the manifest of the deployed release with
+// helm.sh/resource-policy: keep, which uninstall honors
+func keepResources(cfg *action.Configuration, name string, keepKinds []string) error {
+	rel, err := cfg.Releases.Last(name)
+	if err != nil {
+		return err
+	}
+
+	manifests := releaseutil.SplitManifests(rel.Manifest)
+	keys := make([]string, 0, len(manifests))
+	for key := range manifests {
+		keys = append(keys, key)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	docs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		doc := manifests[key]
+		obj := map[string]any{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return err
+		}
+		if kind, _ := obj["kind"].(string); slices.Contains(keepKinds, kind) {
+			metadata, _ := obj["metadata"].(map[string]any)
+			if metadata == nil {
+				metadata = map[string]any{}
+			}
+			annotations, _ := metadata["annotations"].(map[string]any)
+			if annotations == nil {
+				annotations = map[string]any{}
+			}
+			annotations[kube.ResourcePolicyAnno] = kube.KeepPolicy
+			metadata["annotations"] = annotations
+			obj["metadata"] = metadata
+
+			out, err := yaml.Marshal(obj)
+			if err != nil {
+				return err
+			}
+			doc = string(out)
+		}
+		docs = append(docs, doc)
+	}
+
+	rel.Manifest = "---\n" + strings.Join(docs, "\n---\n")
+	return cfg.Releases.Update(rel)
+}
+
 func (c *Client) ListReleases(ctx context.Context, namespace string) ([]string, error) {
 	cfg, err := c.newActionConfig(namespace)
 	if err != nil {
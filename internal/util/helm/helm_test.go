@@ -0,0 +1,55 @@
+package helm
+
+import (
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func TestKeepResources(t *testing.T) {
+	cfg := &action.Configuration{Releases: storage.Init(driver.NewMemory())}
+	manifest := `---
+# Source: minio/templates/pvc.yaml
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: demo-minio
+spec:
+  accessModes: [ReadWriteOnce]
+---
+# Source: minio/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: demo-minio
+`
+	if err := cfg.Releases.Create(&release.Release{
+		Name: "demo-minio", Version: 1, Manifest: manifest,
+		Info: &release.Info{Status: release.StatusDeployed},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := keepResources(cfg, "demo-minio", []string{"PersistentVolumeClaim"}); err != nil {
+		t.Fatalf("keepResources failed: %v", err)
+	}
+
+	rel, err := cfg.Releases.Last("demo-minio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := strings.Split(rel.Manifest, "\n---\n")
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 manifests, got %d:\n%s", len(docs), rel.Manifest)
+	}
+	if !strings.Contains(docs[0], "helm.sh/resource-policy: keep") {
+		t.Errorf("PersistentVolumeClaim not kept:\n%s", docs[0])
+	}
+	if strings.Contains(docs[1], "resource-policy") || !strings.Contains(docs[1], "kind: Deployment") {
+		t.Errorf("Deployment changed:\n%s", docs[1])
+	}
+}
@@ -0,0 +1,114 @@
+package helm
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// embeddedSchemas are fallback values schemas for charts that don't ship a values.schema.json
+//
+//go:embed schemas/*.values.schema.json
+var embeddedSchemas embed.FS
+
+// InvalidValuesError is returned when release values don't satisfy the chart's values schema
+type InvalidValuesError struct {
+	Errors []string
+}
+
+func (e *InvalidValuesError) Error() string {
+	return fmt.Sprintf("invalid values: %s", strings.Join(e.Errors, "; "))
+}
+
+// schemaCache holds the values schema of every chart URL located so far
+var schemaCache sync.Map
+
+// ChartName returns the name of the chart referenced by an OCI chart URL,
+// e.g. postgresql for registry-1.docker.io/bitnamicharts/postgresql:16.4.9
+func ChartName(chartURL string) string {
+	name := path.Base(strings.TrimPrefix(chartURL, "oci://"))
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// EmbeddedValuesSchema returns the values schema shipped in the binary for chartName, or nil
+func EmbeddedValuesSchema(chartName string) []byte {
+	schema, err := embeddedSchemas.ReadFile(fmt.Sprintf("schemas/%s.values.schema.json", chartName))
+	if err != nil {
+		return nil
+	}
+	return schema
+}
+
+// ValuesSchema returns the values.schema.json of the chart at chartURL. Charts without a schema
+// fall back to the embedded one. Schemas are cached per chart URL, as OCI chart URLs are pinned to a version.
+func (c *Client) ValuesSchema(chartURL string) ([]byte, error) {
+	if cached, ok := schemaCache.Load(chartURL); ok {
+		return cached.([]byte), nil
+	}
+
+	chartRef := chartURL
+	if !strings.HasPrefix(chartRef, "oci://") {
+		chartRef = "oci://" + chartRef
+	}
+
+	pathOptions := action.ChartPathOptions{}
+	chartPath, err := pathOptions.LocateChart(chartRef, c.env)
+	if err != nil {
+		return nil, fmt.Errorf("chart location failed: %w", err)
+	}
+
+	chart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("chart loading failed: %w", err)
+	}
+
+	schema := chart.Schema
+	if len(schema) == 0 {
+		schema = EmbeddedValuesSchema(chart.Name())
+	}
+
+	schemaCache.Store(chartURL, schema)
+	return schema, nil
+}
+
+// ValidateValues validates the YAML valuesContent against a JSON schema. A nil schema accepts any
+// parseable values. Unparseable values and schema violations are reported as *InvalidValuesError.
+func ValidateValues(valuesContent string, schema []byte) error {
+	values, err := parseYAMLValues(valuesContent)
+	if err != nil {
+		return &InvalidValuesError{Errors: []string{err.Error()}}
+	}
+	if len(schema) == 0 {
+		return nil
+	}
+
+	jsonData, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("error converting to JSON: %w", err)
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewBytesLoader(jsonData))
+	if err != nil {
+		return fmt.Errorf("error during validation: %w", err)
+	}
+
+	if !result.Valid() {
+		errMsgs := make([]string, 0, len(result.Errors()))
+		for _, desc := range result.Errors() {
+			errMsgs = append(errMsgs, desc.String())
+		}
+		return &InvalidValuesError{Errors: errMsgs}
+	}
+
+	return nil
+}
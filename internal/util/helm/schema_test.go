@@ -0,0 +1,43 @@
+package helm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChartName(t *testing.T) {
+	for chartURL, want := range map[string]string{
+		"registry-1.docker.io/bitnamicharts/postgresql:16.4.9": "postgresql",
+		"oci://registry-1.docker.io/edgeflare/zitadel:8.12.0":  "zitadel",
+		"ghcr.io/edgeflare/pgo":                                "pgo",
+	} {
+		if got := ChartName(chartURL); got != want {
+			t.Errorf("ChartName(%q) = %q, want %q", chartURL, got, want)
+		}
+	}
+}
+
+func TestValidateValues(t *testing.T) {
+	schema := EmbeddedValuesSchema("postgresql")
+	if schema == nil {
+		t.Fatal("missing embedded postgresql schema")
+	}
+
+	if err := ValidateValues("architecture: replication\n", schema); err != nil {
+		t.Errorf("valid values rejected: %v", err)
+	}
+
+	err := ValidateValues("architecture: [replication]\n", schema)
+	invalidValues := &InvalidValuesError{}
+	if !errors.As(err, &invalidValues) || len(invalidValues.Errors) == 0 {
+		t.Errorf("expected InvalidValuesError, got %v", err)
+	}
+
+	if err := ValidateValues("anything: goes\n", nil); err != nil {
+		t.Errorf("values without schema rejected: %v", err)
+	}
+
+	if err := ValidateValues("anything: [goes\n", nil); !errors.As(err, &invalidValues) {
+		t.Errorf("expected InvalidValuesError for unparseable values, got %v", err)
+	}
+}
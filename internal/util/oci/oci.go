@@ -0,0 +1,96 @@
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/containerd/containerd/remotes/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/oras"
+)
+
+// Credentials authenticate pulls from a registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// PullFiles pulls the layers of an artifact pushed with e.g. oras push, keyed by the file names in
+// their org.opencontainers.image.title annotations. Layers without a title are skipped.
+func PullFiles(ctx context.Context, ref string, creds *Credentials) (map[string][]byte, error) {
+	ref = strings.TrimPrefix(ref, "oci://")
+	opts := docker.ResolverOptions{}
+	if creds != nil {
+		opts.Credentials = func(string) (string, string, error) {
+			return creds.Username, creds.Password, nil
+		}
+	}
+	registry := content.Registry{Resolver: docker.NewResolver(opts)}
+	store := content.NewMemory()
+
+	var layers []ocispec.Descriptor
+	if _, err := oras.Copy(ctx, registry, ref, store, "",
+		oras.WithLayerDescriptors(func(l []ocispec.Descriptor) { layers = l })); err != nil {
+		return nil, fmt.Errorf("failed to pull %s: %w", ref, err)
+	}
+
+	files := map[string][]byte{}
+	for _, layer := range layers {
+		name := layer.Annotations[ocispec.AnnotationTitle]
+		if name == "" {
+			continue
+		}
+		_, data, ok := store.Get(layer)
+		if !ok {
+			return nil, fmt.Errorf("layer %s of %s was not pulled", name, ref)
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
+// CredentialsFromDockerConfig returns the credentials for the registry of ref in the content of a
+// kubernetes.io/dockerconfigjson Secret, or nil if it has none
+func CredentialsFromDockerConfig(dockerConfig []byte, ref string) (*Credentials, error) {
+	config := struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}{}
+	if err := json.Unmarshal(dockerConfig, &config); err != nil {
+		return nil, fmt.Errorf("invalid docker config: %w", err)
+	}
+
+	host := Host(ref)
+	for server, auth := range config.Auths {
+		if Host(server) != host && !(host == "docker.io" && Host(server) == "index.docker.io") {
+			continue
+		}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth of %s: %w", server, err)
+			}
+			username, password, _ := strings.Cut(string(decoded), ":")
+			return &Credentials{Username: username, Password: password}, nil
+		}
+		return &Credentials{Username: auth.Username, Password: auth.Password}, nil
+	}
+	return nil, nil
+}
+
+// Host returns the registry host of a reference or docker config server, e.g. ghcr.io for
+// oci://ghcr.io/acme/migrations:v1
+func Host(ref string) string {
+	for _, scheme := range []string{"oci://", "https://", "http://"} {
+		ref = strings.TrimPrefix(ref, scheme)
+	}
+	host, _, _ := strings.Cut(ref, "/")
+	return host
+}
@@ -0,0 +1,44 @@
+package oci
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+	for ref, want := range map[string]string{
+		"oci://ghcr.io/acme/migrations:v1":  "ghcr.io",
+		"registry.local:5000/migrations:v1": "registry.local:5000",
+		"https://index.docker.io/v1/":       "index.docker.io",
+	} {
+		if got := Host(ref); got != want {
+			t.Errorf("Host(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+func TestCredentialsFromDockerConfig(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("bot:s3cr:et"))
+	config := []byte(`{"auths":{"ghcr.io":{"auth":"` + auth + `"},` +
+		`"https://registry.local:5000":{"username":"admin","password":"pw"}}}`)
+
+	creds, err := CredentialsFromDockerConfig(config, "ghcr.io/acme/migrations:v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds == nil || creds.Username != "bot" || creds.Password != "s3cr:et" {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+
+	creds, err = CredentialsFromDockerConfig(config, "oci://registry.local:5000/migrations:v1")
+	if err != nil || creds == nil || creds.Username != "admin" || creds.Password != "pw" {
+		t.Errorf("unexpected credentials %+v: %v", creds, err)
+	}
+
+	if creds, _ := CredentialsFromDockerConfig(config, "quay.io/acme/migrations:v1"); creds != nil {
+		t.Errorf("credentials of another registry returned: %+v", creds)
+	}
+	if _, err := CredentialsFromDockerConfig([]byte("not json"), "ghcr.io/x"); err == nil {
+		t.Error("invalid docker config accepted")
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2025 edgeflare.io.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+	"github.com/edgeflare/edge/internal/util/helm"
+)
+
+// log is for logging in this package.
+var releaselog = logf.Log.WithName("release-resource")
+
+// SetupReleaseWebhookWithManager registers the webhook for Release in the manager.
+func SetupReleaseWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&helmv1alpha1.Release{}).
+		WithValidator(&ReleaseCustomValidator{}).
+		WithDefaulter(&ReleaseCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-helm-edgeflare-io-v1alpha1-release,mutating=true,failurePolicy=fail,sideEffects=None,groups=helm.edgeflare.io,resources=releases,verbs=create;update,versions=v1alpha1,name=mrelease-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ReleaseCustomDefaulter records the user suspending a Release and fills the chart and values of
+// a Release of a known component, named by its app.kubernetes.io/name label, e.g. postgres.
+type ReleaseCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &ReleaseCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind Release.
+func (d *ReleaseCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	release, ok := obj.(*helmv1alpha1.Release)
+	if !ok {
+		return fmt.Errorf("expected a Release object but got %T", obj)
+	}
+	releaselog.Info("Defaulting for Release", "name", release.GetName())
+
+	// Record who suspends the release, the controller reports it in the Suspended condition
+	if req, err := admission.RequestFromContext(ctx); err == nil {
+		common.RecordSuspendedBy(release, release.Spec.Suspend, req.UserInfo.Username)
+	}
+
+	componentName := release.Labels[common.LabelName]
+	chartURL := common.DefaultChartURL(componentName)
+	if chartURL == "" {
+		return nil
+	}
+
+	if release.Spec.ChartURL == "" {
+		release.Spec.ChartURL = chartURL
+	}
+	// Default values are rendered with the project name and only fit the default chart
+	projectName := release.Labels[common.LabelProject]
+	if release.Spec.ValuesContent == "" && release.Spec.ChartURL == chartURL && projectName != "" {
+		release.Spec.ValuesContent = common.DefaultValuesContent(componentName, projectName)
+	}
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-helm-edgeflare-io-v1alpha1-release,mutating=false,failurePolicy=fail,sideEffects=None,groups=helm.edgeflare.io,resources=releases,verbs=create;update,versions=v1alpha1,name=vrelease-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ReleaseCustomValidator rejects Releases whose values can't be parsed or violate the values schema
+// the binary embeds for their chart.
+type ReleaseCustomValidator struct{}
+
+var _ webhook.CustomValidator = &ReleaseCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Release.
+func (v *ReleaseCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	release, ok := obj.(*helmv1alpha1.Release)
+	if !ok {
+		return nil, fmt.Errorf("expected a Release object but got %T", obj)
+	}
+	releaselog.Info("Validation for Release upon creation", "name", release.GetName())
+
+	return nil, validateRelease(release)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Release.
+func (v *ReleaseCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	release, ok := newObj.(*helmv1alpha1.Release)
+	if !ok {
+		return nil, fmt.Errorf("expected a Release object for the newObj but got %T", newObj)
+	}
+	releaselog.Info("Validation for Release upon update", "name", release.GetName())
+
+	return nil, validateRelease(release)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Release.
+func (v *ReleaseCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateRelease(release *helmv1alpha1.Release) error {
+	var allErrs field.ErrorList
+	fldPath := field.NewPath("spec", "valuesContent")
+
+	schema := helm.EmbeddedValuesSchema(helm.ChartName(release.Spec.ChartURL))
+	err := helm.ValidateValues(release.Spec.ValuesContent, schema)
+	invalidValues := &helm.InvalidValuesError{}
+	if errors.As(err, &invalidValues) {
+		for _, msg := range invalidValues.Errors {
+			allErrs = append(allErrs, field.Invalid(fldPath, "<valuesContent>", msg))
+		}
+	} else if err != nil {
+		allErrs = append(allErrs, field.InternalError(fldPath, err))
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(helmv1alpha1.GroupVersion.WithKind("Release").GroupKind(),
+		release.Name, allErrs)
+}
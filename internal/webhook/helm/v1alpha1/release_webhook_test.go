@@ -0,0 +1,81 @@
+/*
+Copyright 2025 edgeflare.io.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+var _ = Describe("Release Webhook", func() {
+	var (
+		ctx       context.Context
+		obj       *helmv1alpha1.Release
+		validator ReleaseCustomValidator
+		defaulter ReleaseCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		obj = &helmv1alpha1.Release{ObjectMeta: metav1.ObjectMeta{Name: "demo-postgres", Namespace: "default"}}
+		validator = ReleaseCustomValidator{}
+		defaulter = ReleaseCustomDefaulter{}
+	})
+
+	Context("When creating Release under Defaulting Webhook", func() {
+		It("Should fill the chart and values of a known component", func() {
+			obj.Labels = map[string]string{common.LabelName: "postgres", common.LabelProject: "demo"}
+
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.ChartURL).To(Equal(common.DefaultChartURL("postgres")))
+			Expect(obj.Spec.ValuesContent).To(ContainSubstring("demo-postgresql"))
+		})
+
+		It("Should leave other releases alone", func() {
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.ChartURL).To(BeEmpty())
+		})
+	})
+
+	Context("When creating or updating Release under Validating Webhook", func() {
+		It("Should deny schema-invalid values", func() {
+			obj.Spec.ChartURL = common.DefaultChartURL("postgres")
+			obj.Spec.ValuesContent = "architecture: [replication]\n"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.valuesContent")))
+		})
+
+		It("Should deny unparseable values of any chart", func() {
+			obj.Spec.ChartURL = "registry-1.docker.io/example/chart:1.0.0"
+			obj.Spec.ValuesContent = "replicaCount: [1\n"
+			_, err := validator.ValidateUpdate(ctx, obj.DeepCopy(), obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should admit valid values", func() {
+			obj.Spec.ChartURL = common.DefaultChartURL("postgres")
+			obj.Spec.ValuesContent = common.DefaultValuesContent("postgres", "demo")
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,255 @@
+/*
+Copyright 2025 edgeflare.io.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	edgeflareiov1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+	"github.com/edgeflare/edge/internal/component"
+	"github.com/edgeflare/edge/internal/util/helm"
+)
+
+// log is for logging in this package.
+var projectlog = logf.Log.WithName("project-resource")
+
+// immutableComponentTypes are the component groups holding the project's data. Their backend,
+// e.g. built-in or external PostgreSQL, can't be swapped on a live project.
+var immutableComponentTypes = []string{"database", "auth", "storage"}
+
+// SetupProjectWebhookWithManager registers the webhook for Project in the manager.
+func SetupProjectWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&edgeflareiov1alpha1.Project{}).
+		WithValidator(&ProjectCustomValidator{}).
+		WithDefaulter(&ProjectCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-edgeflare-io-v1alpha1-project,mutating=true,failurePolicy=fail,sideEffects=None,groups=edgeflare.io,resources=projects,verbs=create;update,versions=v1alpha1,name=mproject-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ProjectCustomDefaulter records the user suspending a Project and fills the release of every
+// declared built-in component with the chart and values of the component's defaults, unless the
+// project references a ProjectTemplate.
+type ProjectCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &ProjectCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind Project.
+func (d *ProjectCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	project, ok := obj.(*edgeflareiov1alpha1.Project)
+	if !ok {
+		return fmt.Errorf("expected a Project object but got %T", obj)
+	}
+	projectlog.Info("Defaulting for Project", "name", project.GetName())
+
+	// Record who suspends the project, the controller reports it in the Suspended condition
+	if req, err := admission.RequestFromContext(ctx); err == nil {
+		common.RecordSuspendedBy(project, project.Spec.Suspend, req.UserInfo.Username)
+	}
+
+	// Default values are rendered with the project name, which generateName leaves empty. The
+	// components of a templated project default to the template's
+	if project.Name == "" || project.Spec.TemplateRef != nil {
+		return nil
+	}
+
+	for _, p := range component.Components() {
+		ref := p.ComponentRef(&project.Spec)
+		if ref == nil || ref.IsExternal() {
+			continue
+		}
+
+		defaults := p.DefaultRelease(project.Name)
+		if ref.Release == nil {
+			ref.Release = &defaults
+			continue
+		}
+		if ref.Release.ChartURL == "" {
+			ref.Release.ChartURL = defaults.ChartURL
+		}
+		// Default values only fit the default chart
+		if ref.Release.ValuesContent == "" && ref.Release.ChartURL == defaults.ChartURL {
+			ref.Release.ValuesContent = defaults.ValuesContent
+		}
+	}
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-edgeflare-io-v1alpha1-project,mutating=false,failurePolicy=fail,sideEffects=None,groups=edgeflare.io,resources=projects,verbs=create;update,versions=v1alpha1,name=vproject-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ProjectCustomValidator validates the component references of a Project and rejects swapping
+// the backend of its database, auth or storage.
+type ProjectCustomValidator struct{}
+
+var _ webhook.CustomValidator = &ProjectCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Project.
+func (v *ProjectCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	project, ok := obj.(*edgeflareiov1alpha1.Project)
+	if !ok {
+		return nil, fmt.Errorf("expected a Project object but got %T", obj)
+	}
+	projectlog.Info("Validation for Project upon creation", "name", project.GetName())
+
+	return nil, projectInvalid(project, validateComponents(project))
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Project.
+func (v *ProjectCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	project, ok := newObj.(*edgeflareiov1alpha1.Project)
+	if !ok {
+		return nil, fmt.Errorf("expected a Project object for the newObj but got %T", newObj)
+	}
+	oldProject, ok := oldObj.(*edgeflareiov1alpha1.Project)
+	if !ok {
+		return nil, fmt.Errorf("expected a Project object for the oldObj but got %T", oldObj)
+	}
+	projectlog.Info("Validation for Project upon update", "name", project.GetName())
+
+	allErrs := validateComponents(project)
+	allErrs = append(allErrs, validateBackends(oldProject, project)...)
+	return nil, projectInvalid(project, allErrs)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Project.
+func (v *ProjectCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func projectInvalid(project *edgeflareiov1alpha1.Project, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(edgeflareiov1alpha1.GroupVersion.WithKind("Project").GroupKind(),
+		project.Name, allErrs)
+}
+
+// validateComponents validates the reference of every declared component
+func validateComponents(project *edgeflareiov1alpha1.Project) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// ComponentRef may initialize a declared group's default component, keep the object untouched
+	spec := project.Spec.DeepCopy()
+	for _, p := range component.Components() {
+		ref := p.ComponentRef(spec)
+		if ref == nil {
+			continue
+		}
+		fldPath := field.NewPath("spec", p.Type(), p.Name())
+
+		if ref.External != nil && ref.Release != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath,
+				"external and release are mutually exclusive"))
+			continue
+		}
+		if ref.External != nil && ref.External.SecretName == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("external", "secretName"),
+				"an external component must reference the secret holding its credentials"))
+		}
+		// The values of a templated project only override the template's, the merged values are
+		// validated before installing
+		if ref.Release != nil && spec.TemplateRef == nil {
+			allErrs = append(allErrs, validateValues(p, ref.Release.ChartURL, ref.Release.ValuesContent,
+				fldPath.Child("release", "valuesContent"))...)
+		}
+	}
+
+	return allErrs
+}
+
+// validateValues parses valuesContent and validates it against the values schema the binary
+// embeds for the chart, or the provider's. Charts are not pulled at admission time, the
+// controller validates against the chart's own schema before installing.
+func validateValues(p component.Component, chartURL, valuesContent string,
+	fldPath *field.Path) field.ErrorList {
+	schema := helm.EmbeddedValuesSchema(helm.ChartName(chartURL))
+	if schema == nil {
+		providerSchema, err := p.ValuesSchema()
+		if err != nil {
+			return field.ErrorList{field.InternalError(fldPath, err)}
+		}
+		schema = providerSchema
+	}
+
+	return valuesErrors(helm.ValidateValues(valuesContent, schema), fldPath)
+}
+
+// valuesErrors converts the error of helm.ValidateValues into field errors
+func valuesErrors(err error, fldPath *field.Path) field.ErrorList {
+	if err == nil {
+		return nil
+	}
+	invalidValues := &helm.InvalidValuesError{}
+	if !errors.As(err, &invalidValues) {
+		return field.ErrorList{field.InternalError(fldPath, err)}
+	}
+
+	allErrs := field.ErrorList{}
+	for _, msg := range invalidValues.Errors {
+		allErrs = append(allErrs, field.Invalid(fldPath, "<valuesContent>", msg))
+	}
+	return allErrs
+}
+
+// validateBackends rejects swapping the backend of a component group holding the project's data
+func validateBackends(oldProject, project *edgeflareiov1alpha1.Project) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for _, compType := range immutableComponentTypes {
+		oldBackend := componentBackend(&oldProject.Spec, compType)
+		newBackend := componentBackend(&project.Spec, compType)
+		if oldBackend != "" && newBackend != "" && oldBackend != newBackend {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", compType),
+				fmt.Sprintf("%s backend can't be changed from %s to %s on a live project",
+					compType, oldBackend, newBackend)))
+		}
+	}
+
+	return allErrs
+}
+
+// componentBackend describes the first declared component of compType, e.g. "external postgres",
+// or returns "" if the group declares none
+func componentBackend(spec *edgeflareiov1alpha1.ProjectSpec, compType string) string {
+	spec = spec.DeepCopy()
+	for _, p := range component.Components() {
+		if p.Type() != compType {
+			continue
+		}
+		ref := p.ComponentRef(spec)
+		if ref == nil {
+			continue
+		}
+		if ref.IsExternal() {
+			return "external " + p.Name()
+		}
+		return "built-in " + p.Name()
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2025 edgeflare.io.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	helmv1alpha1 "github.com/edgeflare/edge/api/helm/v1alpha1"
+	edgeflareiov1alpha1 "github.com/edgeflare/edge/api/v1alpha1"
+	"github.com/edgeflare/edge/internal/common"
+)
+
+var _ = Describe("Project Webhook", func() {
+	var (
+		ctx       context.Context
+		obj       *edgeflareiov1alpha1.Project
+		oldObj    *edgeflareiov1alpha1.Project
+		validator ProjectCustomValidator
+		defaulter ProjectCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		obj = &edgeflareiov1alpha1.Project{ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"}}
+		oldObj = obj.DeepCopy()
+		validator = ProjectCustomValidator{}
+		defaulter = ProjectCustomDefaulter{}
+	})
+
+	Context("When creating Project under Defaulting Webhook", func() {
+		It("Should fill the release of built-in components from the defaults", func() {
+			obj.Spec.Database = &edgeflareiov1alpha1.Database{}
+			obj.Spec.Auth = &edgeflareiov1alpha1.Auth{
+				Zitadel: &edgeflareiov1alpha1.ComponentRef{
+					Release: &helmv1alpha1.ReleaseSpec{ValuesContent: "replicaCount: 2\n"},
+				},
+			}
+
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Database.Postgres.Release).NotTo(BeNil())
+			Expect(obj.Spec.Database.Postgres.Release.ChartURL).To(Equal(common.DefaultChartURL("postgres")))
+			Expect(obj.Spec.Database.Postgres.Release.ValuesContent).To(ContainSubstring("demo-postgresql"))
+			Expect(obj.Spec.Auth.Zitadel.Release.ChartURL).To(Equal(common.DefaultChartURL("zitadel")))
+			Expect(obj.Spec.Auth.Zitadel.Release.ValuesContent).To(Equal("replicaCount: 2\n"))
+		})
+
+		It("Should leave external components alone", func() {
+			obj.Spec.Database = &edgeflareiov1alpha1.Database{
+				Postgres: &edgeflareiov1alpha1.ComponentRef{
+					External: &edgeflareiov1alpha1.ExternalRef{SecretName: "pg"},
+				},
+			}
+
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Database.Postgres.Release).To(BeNil())
+		})
+
+		It("Should record the user suspending the project", func() {
+			obj.Spec.Suspend = true
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: "alice"},
+			}}
+
+			Expect(defaulter.Default(admission.NewContextWithRequest(ctx, req), obj)).To(Succeed())
+			Expect(obj.Annotations).To(HaveKeyWithValue(common.AnnotationSuspendedBy, "alice"))
+		})
+
+		It("Should leave the components of a templated project to the template", func() {
+			obj.Spec.TemplateRef = &edgeflareiov1alpha1.TemplateRef{Name: "standard"}
+			obj.Spec.Database = &edgeflareiov1alpha1.Database{}
+
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Database.Postgres).To(BeNil())
+		})
+	})
+
+	Context("When creating or updating Project under Validating Webhook", func() {
+		It("Should deny a component with both external and release", func() {
+			obj.Spec.Database = &edgeflareiov1alpha1.Database{
+				Postgres: &edgeflareiov1alpha1.ComponentRef{
+					External: &edgeflareiov1alpha1.ExternalRef{SecretName: "pg"},
+					Release:  &helmv1alpha1.ReleaseSpec{ChartURL: common.DefaultChartURL("postgres")},
+				},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("mutually exclusive")))
+		})
+
+		It("Should deny an external component without secretName", func() {
+			obj.Spec.Database = &edgeflareiov1alpha1.Database{
+				Postgres: &edgeflareiov1alpha1.ComponentRef{External: &edgeflareiov1alpha1.ExternalRef{}},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.database.postgres.external.secretName")))
+		})
+
+		It("Should deny unparseable or schema-invalid values", func() {
+			obj.Spec.Database = &edgeflareiov1alpha1.Database{
+				Postgres: &edgeflareiov1alpha1.ComponentRef{
+					Release: &helmv1alpha1.ReleaseSpec{
+						ChartURL:      common.DefaultChartURL("postgres"),
+						ValuesContent: "architecture: [replication\n",
+					},
+				},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.database.postgres.release.valuesContent")))
+
+			obj.Spec.Database.Postgres.Release.ValuesContent = "architecture: [replication]\n"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.database.postgres.release.valuesContent")))
+		})
+
+		It("Should admit a defaulted project", func() {
+			obj.Spec.Database = &edgeflareiov1alpha1.Database{}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny swapping the database backend on update", func() {
+			oldObj.Spec.Database = &edgeflareiov1alpha1.Database{}
+			obj.Spec.Database = &edgeflareiov1alpha1.Database{
+				Postgres: &edgeflareiov1alpha1.ComponentRef{
+					External: &edgeflareiov1alpha1.ExternalRef{SecretName: "pg"},
+				},
+			}
+			_, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).To(MatchError(ContainSubstring("database backend can't be changed")))
+		})
+
+		It("Should deny swapping the auth provider on update", func() {
+			oldObj.Spec.Auth = &edgeflareiov1alpha1.Auth{Zitadel: &edgeflareiov1alpha1.ComponentRef{}}
+			obj.Spec.Auth = &edgeflareiov1alpha1.Auth{Keycloak: &edgeflareiov1alpha1.ComponentRef{}}
+			_, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should admit adding a component on update", func() {
+			oldObj.Spec.Database = &edgeflareiov1alpha1.Database{}
+			obj.Spec.Database = &edgeflareiov1alpha1.Database{}
+			obj.Spec.Auth = &edgeflareiov1alpha1.Auth{Zitadel: &edgeflareiov1alpha1.ComponentRef{}}
+			Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+})